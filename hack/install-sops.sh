@@ -0,0 +1,29 @@
+#!/usr/bin/env bash
+
+# Copyright 2024 The Kubernetes Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SOPS_VERSION=${SOPS_VERSION:-v3.9.1}
+INSTALL_DIR=${INSTALL_DIR:-$(go env GOPATH)/bin}
+
+if ! command -v sops; then
+    mkdir -p "${INSTALL_DIR}"
+    curl -sfL -o "${INSTALL_DIR}/sops" \
+        "https://github.com/getsops/sops/releases/download/${SOPS_VERSION}/sops-${SOPS_VERSION}.$(go env GOOS).$(go env GOARCH)"
+    chmod +x "${INSTALL_DIR}/sops"
+fi
@@ -17,6 +17,8 @@ limitations under the License.
 package klient
 
 import (
+	"net/http"
+
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	klog "k8s.io/klog/v2"
@@ -35,6 +37,10 @@ type Client interface {
 	// This method takes zero or at most 1 namespace (more will panic) that
 	// can be used in List operations.
 	Resources(...string) *resources.Resources
+	// WithTransportWrapper returns a new Client whose requests to the API server
+	// go through the http.RoundTripper returned by wrapper. It can be used to
+	// count API calls or to inject latency and failures.
+	WithTransportWrapper(wrapper func(http.RoundTripper) http.RoundTripper) (Client, error)
 }
 
 type client struct {
@@ -85,6 +91,15 @@ func (c *client) Resources(namespace ...string) *resources.Resources {
 	}
 }
 
+// WithTransportWrapper returns a new Client, with its own copy of the
+// *rest.Config, whose transport is wrapped by the provided wrapper.
+// Wrappers previously registered on the config are preserved.
+func (c *client) WithTransportWrapper(wrapper func(http.RoundTripper) http.RoundTripper) (Client, error) {
+	cfg := rest.CopyConfig(c.cfg)
+	cfg.Wrap(wrapper)
+	return New(cfg)
+}
+
 func init() {
 	log.SetLogger(klog.NewKlogr())
 }
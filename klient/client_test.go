@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/e2e-framework/klient"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newFakeAPIServer returns a server answering the discovery requests and
+// the requests for the ConfigMap named "example" in the default namespace.
+func newFakeAPIServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	responses := map[string]interface{}{
+		"/api": metav1.APIVersions{
+			TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+			Versions: []string{"v1"},
+		},
+		"/apis": metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}},
+		"/api/v1": metav1.APIResourceList{
+			TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"get"}}},
+		},
+		"/api/v1/namespaces/default/configmaps/example": corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Error(err)
+		}
+	}))
+}
+
+func TestClientWithTransportWrapper(t *testing.T) {
+	server := newFakeAPIServer(t)
+	defer server.Close()
+
+	client, err := klient.New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gets int32
+	counting, err := client.WithTransportWrapper(func(rt http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet && req.URL.Path == "/api/v1/namespaces/default/configmaps/example" {
+				atomic.AddInt32(&gets, 1)
+			}
+			return rt.RoundTrip(req)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cm corev1.ConfigMap
+	if err := client.Resources().Get(context.TODO(), "example", "default", &cm); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&gets); got != 0 {
+		t.Fatalf("expected the original client not to be wrapped, got %d counted requests", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := counting.Resources().Get(context.TODO(), "example", "default", &cm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&gets); got != 2 {
+		t.Errorf("expected 2 counted GET requests, got %d", got)
+	}
+	if cm.Name != "example" {
+		t.Errorf("unexpected object returned through the wrapped transport: %v", cm)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	serializerjson "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes/scheme"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// WithConvertToPreferredVersion converts each document decoded from a stream to the version the API server
+// behind r prefers for its group, before the document is decoded and handed to the handler. For instance, a
+// policy/v1beta1 PodDisruptionBudget is decoded as a policy/v1 PodDisruptionBudget on clusters serving
+// policy/v1. Documents are left as is when the preferred version is not newer than the declared one, or
+// does not serve their kind.
+//
+// Documents are converted with the conversion functions of the default scheme when it registers them. Otherwise,
+// the fields of the document are carried over by name, which is only done when the preferred version of the kind
+// is known to the default scheme and holds every field of the document: the documents holding fields renamed or
+// restructured across versions, such as the metric targets of autoscaling/v2beta1 HorizontalPodAutoscalers, are
+// rejected with an error rather than converted with their fields dropped.
+func WithConvertToPreferredVersion(r *resources.Resources) DecodeOption {
+	return func(do *Options) {
+		do.ConvertToPreferredVersion = r
+	}
+}
+
+// versionConverter rewrites documents to the preferred version of their group, caching the versions
+// discovered for each kind for the duration of a stream.
+type versionConverter struct {
+	client    discovery.DiscoveryInterface
+	preferred map[schema.GroupVersionKind]string
+}
+
+func newVersionConverter(r *resources.Resources) (*versionConverter, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(r.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &versionConverter{client: client, preferred: make(map[schema.GroupVersionKind]string)}, nil
+}
+
+// convert returns the document with its apiVersion set to the preferred version of its group, encoded as JSON,
+// or the document unchanged when no conversion applies.
+func (c *versionConverter) convert(document []byte) ([]byte, error) {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(document, &obj.Object); err != nil {
+		return nil, err
+	}
+	gvk := obj.GroupVersionKind()
+	if gvk.Version == "" || gvk.Kind == "" {
+		return document, nil
+	}
+	preferred, err := c.preferredVersion(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("conversion of %s %q failed: %w", gvk.Kind, obj.GetName(), err)
+	}
+	if version.CompareKubeAwareVersionStrings(preferred, gvk.Version) <= 0 {
+		return document, nil
+	}
+
+	klog.V(2).InfoS("Converting document to preferred version", "kind", gvk.Kind, "name", obj.GetName(), "from", gvk.Version, "to", preferred)
+	target := schema.GroupVersion{Group: gvk.Group, Version: preferred}
+	if converted, ok := convertWithScheme(obj, target); ok {
+		return converted, nil
+	}
+	if !scheme.Scheme.Recognizes(target.WithKind(gvk.Kind)) {
+		return nil, fmt.Errorf("conversion of %s %q from %s to %s failed: no conversion available", gvk.Kind, obj.GetName(), gvk.Version, preferred)
+	}
+	obj.SetAPIVersion(target.String())
+	converted, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	// the fields unknown to the preferred version would be dropped by the decoding of the document
+	if _, _, err := strictDecoder.Decode(converted, nil, nil); err != nil {
+		return nil, fmt.Errorf("conversion of %s %q from %s to %s failed: the document can not be converted losslessly: %w", gvk.Kind, obj.GetName(), gvk.Version, preferred, err)
+	}
+	return converted, nil
+}
+
+// strictDecoder decodes the documents of the kinds of the default scheme, failing on unknown or duplicate fields.
+var strictDecoder = serializerjson.NewSerializerWithOptions(serializerjson.DefaultMetaFactory, scheme.Scheme, scheme.Scheme, serializerjson.SerializerOptions{Strict: true})
+
+// convertWithScheme returns obj converted to target, encoded as JSON, when the default scheme registers the
+// conversion of its kind to target.
+func convertWithScheme(obj *unstructured.Unstructured, target schema.GroupVersion) ([]byte, bool) {
+	typed, err := scheme.Scheme.New(obj.GroupVersionKind())
+	if err != nil {
+		return nil, false
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed); err != nil {
+		return nil, false
+	}
+	converted, err := scheme.Scheme.ConvertToVersion(typed, target)
+	if err != nil {
+		return nil, false
+	}
+	data, err := json.Marshal(converted)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// preferredVersion returns the version the API server prefers for the group of gvk, or the version of gvk when
+// the group is not served or its preferred version does not serve the kind.
+func (c *versionConverter) preferredVersion(gvk schema.GroupVersionKind) (string, error) {
+	if preferred, ok := c.preferred[gvk]; ok {
+		return preferred, nil
+	}
+	preferred := gvk.Version
+	groups, err := c.client.ServerGroups()
+	if err != nil {
+		return "", err
+	}
+	for _, group := range groups.Groups {
+		if group.Name != gvk.Group || group.PreferredVersion.Version == gvk.Version {
+			continue
+		}
+		served, err := c.client.ServerResourcesForGroupVersion(group.PreferredVersion.GroupVersion)
+		if err != nil {
+			return "", err
+		}
+		for _, resource := range served.APIResources {
+			if resource.Kind == gvk.Kind {
+				preferred = group.PreferredVersion.Version
+				break
+			}
+		}
+	}
+	c.preferred[gvk] = preferred
+	return preferred, nil
+}
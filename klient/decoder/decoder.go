@@ -20,18 +20,27 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
+	"sort"
 	"strings"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes/scheme"
 	klog "k8s.io/klog/v2"
@@ -46,8 +55,44 @@ import (
 type Options struct {
 	DefaultGVK  *schema.GroupVersionKind
 	MutateFuncs []MutateFunc
+	// Include and Exclude are glob patterns matched against the base name of the files
+	// resolved by DecodeEachFile and DecodeAllFiles. Exclude takes precedence over Include.
+	Include []string
+	Exclude []string
+	// SourceAnnotation, when set, is the annotation key used to record the source template named
+	// by a "# Source:" comment, as emitted by `helm template`, on objects decoded from a stream.
+	SourceAnnotation string
+	// SchemaValidation, when set, is used to validate the documents decoded from a stream against
+	// the OpenAPI schema published by the API server. See WithSchemaValidation.
+	SchemaValidation *resources.Resources
+	// ConvertToPreferredVersion, when set, is used to convert the documents decoded from a stream to
+	// the preferred version of their group. See WithConvertToPreferredVersion.
+	ConvertToPreferredVersion *resources.Resources
+	// DocumentSelector, when set, restricts the documents decoded from a stream to the ones it
+	// selects. See WithDocumentSelector.
+	DocumentSelector DocumentSelector
+	// SkipAnnotation, when set, omits the documents annotated with SkipAnnotation set to "true".
+	// See WithSkipAnnotation.
+	SkipAnnotation bool
+	// ContinueOnError, when set, keeps decoding after the handler fails for an object. See
+	// WithContinueOnError.
+	ContinueOnError bool
+	// PreserveManagedFields, when set, keeps the managed fields declared by the documents on the objects
+	// applied by ApplyWithManifestDirAndPrune. See WithPreservedManagedFields.
+	PreserveManagedFields bool
+	// SopsDecrypt, when set, decrypts the documents encrypted with sops before they are decoded.
+	// See WithSopsDecrypt.
+	SopsDecrypt bool
+	// Progress, when set, is called once per object created by ApplyWithManifestDir. See WithProgress.
+	Progress ProgressFunc
 }
 
+// SkipAnnotation is the annotation marking the documents omitted from decoding by WithSkipAnnotation.
+const SkipAnnotation = "e2e-framework.sigs.k8s.io/skip"
+
+// DocumentSelector reports whether the document declaring the given kind and name is decoded.
+type DocumentSelector func(gvk schema.GroupVersionKind, name string) bool
+
 // DecodeOption is a function that alters the configuration Options used to decode and optionally mutate objects via MutateFuncs
 type DecodeOption func(*Options)
 
@@ -55,34 +100,93 @@ type DecodeOption func(*Options)
 // Returning an error halts decoding of any further objects.
 type MutateFunc func(obj k8s.Object) error
 
-// HandlerFunc is a function executed after an object has been decoded and patched. If an error is returned, further decoding is halted.
+// HandlerFunc is a function executed after an object has been decoded and patched. If an error is returned, further decoding is halted,
+// unless WithContinueOnError is used.
 type HandlerFunc func(ctx context.Context, obj k8s.Object) error
 
+// DecodedDocument pairs a decoded object with the raw bytes of the document it was decoded from.
+// Raw holds the document exactly as it was read, before any MutateFuncs were applied to Object.
+type DecodedDocument struct {
+	Object k8s.Object
+	Raw    []byte
+}
+
+// DocumentHandlerFunc is a function executed after a document has been decoded and patched. If an error is returned, further decoding is halted.
+type DocumentHandlerFunc func(ctx context.Context, doc DecodedDocument) error
+
 // DecodeEachFile resolves files at the filesystem matching the pattern, decoding JSON or YAML files. Supports multi-document files.
 //
 // If handlerFn returns an error, decoding is halted.
 // Options may be provided to configure the behavior of the decoder.
 func DecodeEachFile(ctx context.Context, fsys fs.FS, pattern string, handlerFn HandlerFunc, options ...DecodeOption) error {
+	decodeOpt := &Options{}
+	for _, opt := range options {
+		opt(decodeOpt)
+	}
 	files, err := fs.Glob(fsys, pattern)
 	if err != nil {
 		return err
 	}
+	var objErrs ObjectErrors
 	for _, file := range files {
-		f, err := fsys.Open(file)
-		if err != nil {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-		defer f.Close()
-		if err := DecodeEach(ctx, f, handlerFn, options...); err != nil {
-			return fmt.Errorf("failed to decode file %q: %w", file, err)
+		if ok, err := decodeOpt.matchFile(file); err != nil {
+			return err
+		} else if !ok {
+			continue
 		}
-		if err := f.Close(); err != nil {
+		if err := decodeFile(ctx, fsys, file, handlerFn, options...); err != nil {
+			if decodeOpt.collectObjectErrors(err, &objErrs) {
+				continue
+			}
 			return err
 		}
 	}
+	if len(objErrs) > 0 {
+		return objErrs
+	}
 	return nil
 }
 
+// decodeFile decodes the documents of a single file, which is closed before returning so that
+// no more than one file is held open while decoding a directory.
+func decodeFile(ctx context.Context, fsys fs.FS, file string, handlerFn HandlerFunc, options ...DecodeOption) error {
+	f, err := fsys.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := DecodeEach(ctx, f, handlerFn, options...); err != nil {
+		return fmt.Errorf("failed to decode file %q: %w", file, err)
+	}
+	return f.Close()
+}
+
+// matchFile reports whether the base name of file passes the Include and Exclude filters.
+func (o *Options) matchFile(file string) (bool, error) {
+	name := path.Base(file)
+	for _, pattern := range o.Exclude {
+		if ok, err := path.Match(pattern, name); err != nil {
+			return false, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		} else if ok {
+			return false, nil
+		}
+	}
+	if len(o.Include) == 0 {
+		return true, nil
+	}
+	for _, pattern := range o.Include {
+		if ok, err := path.Match(pattern, name); err != nil {
+			return false, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		} else if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // DecodeAllFiles  resolves files at the filesystem matching the pattern, decoding JSON or YAML files. Supports multi-document files.
 // Falls back to the unstructured.Unstructured type if a matching type cannot be found for the Kind.
 // Options may be provided to configure the behavior of the decoder.
@@ -97,8 +201,24 @@ func DecodeAllFiles(ctx context.Context, fsys fs.FS, pattern string, options ...
 
 // ApplyWithManifestDir resolves all the files in the Directory dirPath against the globbing pattern and creates a kubernetes
 // resource for each of the resources found under the manifest directory.
+// Use WithProgress to follow the creation of large directories.
 func ApplyWithManifestDir(ctx context.Context, r *resources.Resources, dirPath, pattern string, createOptions []resources.CreateOption, options ...DecodeOption) error {
-	err := DecodeEachFile(ctx, os.DirFS(dirPath), pattern, CreateHandler(r, createOptions...), options...)
+	decodeOpt := &Options{}
+	for _, opt := range options {
+		opt(decodeOpt)
+	}
+	fsys := os.DirFS(dirPath)
+	handler := CreateHandler(r, createOptions...)
+	if decodeOpt.Progress != nil {
+		total, err := countObjects(ctx, fsys, pattern, options...)
+		if err != nil {
+			return err
+		}
+		reporter := newProgressReporter(decodeOpt.Progress, total)
+		defer reporter.stop()
+		handler = reporter.handler(handler)
+	}
+	err := DecodeEachFile(ctx, fsys, pattern, handler, options...)
 	return err
 }
 
@@ -111,19 +231,214 @@ func DeleteWithManifestDir(ctx context.Context, r *resources.Resources, dirPath,
 
 // DecodeEach a stream of documents of any Kind using either the innate typing of the scheme.
 // Falls back to the unstructured.Unstructured type if a matching type cannot be found for the Kind.
+// Documents holding a top-level JSON array of objects, as emitted by some tools in place of a List,
+// are decoded element by element, in order.
 //
-// If handlerFn returns an error, decoding is halted.
+// If handlerFn returns an error, or ctx is done, decoding is halted.
 // Options may be provided to configure the behavior of the decoder.
 func DecodeEach(ctx context.Context, manifest io.Reader, handlerFn HandlerFunc, options ...DecodeOption) error {
-	decoder := yaml.NewYAMLReader(bufio.NewReader(manifest))
+	return DecodeEachDocument(ctx, manifest, func(ctx context.Context, doc DecodedDocument) error {
+		return handlerFn(ctx, doc.Object)
+	}, options...)
+}
+
+// DecodeEachDocument behaves like DecodeEach, but hands each decoded object to handlerFn together with
+// the raw bytes of the document it was decoded from.
+//
+// If handlerFn returns an error, decoding is halted.
+// Options may be provided to configure the behavior of the decoder.
+func DecodeEachDocument(ctx context.Context, manifest io.Reader, handlerFn DocumentHandlerFunc, options ...DecodeOption) error {
+	return decodeEachDocument(ctx, &yamlReader{reader: bufio.NewReader(manifest)}, handlerFn, options...)
+}
+
+// DecodeEachJSONL behaves like DecodeEach, but reads a JSON Lines (ndjson) stream holding one
+// JSON object per line, as emitted by log-style tooling. Blank lines are skipped.
+//
+// If handlerFn returns an error, or ctx is done, decoding is halted.
+// Options may be provided to configure the behavior of the decoder.
+func DecodeEachJSONL(ctx context.Context, manifest io.Reader, handlerFn HandlerFunc, options ...DecodeOption) error {
+	return decodeEachDocument(ctx, &jsonLinesReader{reader: bufio.NewReader(manifest)}, func(ctx context.Context, doc DecodedDocument) error {
+		return handlerFn(ctx, doc.Object)
+	}, options...)
+}
+
+// documentReader reads the raw documents of a stream, returning io.EOF once the stream is exhausted.
+type documentReader interface {
+	Read() ([]byte, error)
+}
+
+// documentLiner is implemented by the documentReaders tracking the line of the stream the last
+// document read starts at, which is used to locate the syntax errors of the document.
+type documentLiner interface {
+	documentLine() int
+}
+
+// yamlSeparator is the separator of the documents of a YAML stream.
+const yamlSeparator = "---"
+
+// yamlReader is a documentReader returning the documents of a YAML stream as yaml.YAMLReader does,
+// tracking the lines they start at.
+type yamlReader struct {
+	reader *bufio.Reader
+	line   int
+	start  int
+}
+
+func (r *yamlReader) Read() ([]byte, error) {
+	var buffer bytes.Buffer
 	for {
-		b, err := decoder.Read()
-		if errors.Is(err, io.EOF) {
-			break
-		} else if err != nil {
+		line, err := r.readLine()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if rest, ok := bytes.CutPrefix(line, []byte(yamlSeparator)); ok {
+			// only comments and spaces may follow the separator
+			if trimmed := bytes.TrimSpace(rest); len(trimmed) > 0 && trimmed[0] != '#' {
+				return nil, &SyntaxError{Line: r.line, Err: fmt.Errorf("invalid document separator: %s", trimmed)}
+			}
+			if buffer.Len() != 0 {
+				return buffer.Bytes(), nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err != nil {
+			if buffer.Len() != 0 {
+				// the last line is not terminated by a newline
+				return buffer.Bytes(), nil
+			}
+			return nil, err
+		}
+		if buffer.Len() == 0 {
+			r.start = r.line
+		}
+		buffer.Write(line)
+	}
+}
+
+// readLine returns the next line of the stream, terminated by a newline.
+func (r *yamlReader) readLine() ([]byte, error) {
+	var buffer bytes.Buffer
+	isPrefix := true
+	var err error
+	for isPrefix && err == nil {
+		var line []byte
+		line, isPrefix, err = r.reader.ReadLine()
+		buffer.Write(line)
+	}
+	if err == nil || buffer.Len() > 0 {
+		r.line++
+	}
+	buffer.WriteByte('\n')
+	return buffer.Bytes(), err
+}
+
+func (r *yamlReader) documentLine() int {
+	return r.start
+}
+
+// jsonLinesReader is a documentReader returning each line of a JSON Lines stream as a document.
+type jsonLinesReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func (r *jsonLinesReader) Read() ([]byte, error) {
+	line, err := r.reader.ReadBytes('\n')
+	if len(line) > 0 {
+		r.line++
+	}
+	if len(line) > 0 && errors.Is(err, io.EOF) {
+		// the last line is not terminated by a newline
+		return line, nil
+	}
+	return line, err
+}
+
+func (r *jsonLinesReader) documentLine() int {
+	return r.line
+}
+
+// decodeEachDocument decodes each document read from decoder, as described by DecodeEachDocument.
+func decodeEachDocument(ctx context.Context, decoder documentReader, handlerFn DocumentHandlerFunc, options ...DecodeOption) error {
+	decodeOpt := &Options{}
+	for _, opt := range options {
+		opt(decodeOpt)
+	}
+	var objErrs ObjectErrors
+	var converter *versionConverter
+	if decodeOpt.ConvertToPreferredVersion != nil {
+		var err error
+		if converter, err = newVersionConverter(decodeOpt.ConvertToPreferredVersion); err != nil {
 			return err
 		}
-		obj, err := DecodeAny(bytes.NewReader(b), options...)
+	}
+	var validator *schemaValidator
+	if decodeOpt.SchemaValidation != nil {
+		var err error
+		if validator, err = newSchemaValidator(decodeOpt.SchemaValidation); err != nil {
+			return err
+		}
+	}
+	// the elements of the last JSON array read, which are decoded before the next document is read
+	var elements [][]byte
+	for {
+		// stop promptly once the context is done, e.g. when a feature times out
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var b []byte
+		var err error
+		if len(elements) > 0 {
+			b, elements = elements[0], elements[1:]
+		} else {
+			b, err = decoder.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return err
+			}
+			// Skip empty documents, such as the ones produced by consecutive or leading separators.
+			if len(bytes.TrimSpace(b)) == 0 {
+				continue
+			}
+			if liner, ok := decoder.(documentLiner); ok {
+				if err := checkSyntax(b, liner.documentLine()); err != nil {
+					return err
+				}
+			}
+			if isJSONArray(b) {
+				if elements, err = splitJSONArray(b); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if decodeOpt.SopsDecrypt {
+			if encrypted, err := isSopsEncrypted(b); err != nil {
+				return err
+			} else if encrypted {
+				if b, err = sopsDecrypt(ctx, b); err != nil {
+					return err
+				}
+			}
+		}
+		if decodeOpt.DocumentSelector != nil {
+			if selected, err := selectDocument(b, decodeOpt); err != nil {
+				return err
+			} else if !selected {
+				continue
+			}
+		}
+		// the converted document is decoded and validated, while Raw keeps the document as it was read
+		document := b
+		if converter != nil {
+			if document, err = converter.convert(b); err != nil {
+				return err
+			}
+		}
+		obj, err := DecodeAny(bytes.NewReader(document), options...)
 		if err != nil {
 			// Skip the Missing Kind entries. This will avoid unwanted failures of the yaml apply workflow in cases
 			// if the file has an empty item with just comments in it.
@@ -133,10 +448,37 @@ func DecodeEach(ctx context.Context, manifest io.Reader, handlerFn HandlerFunc,
 			}
 			return err
 		}
-		if err := handlerFn(ctx, obj); err != nil {
-			return err
+		if decodeOpt.SkipAnnotation && obj.GetAnnotations()[SkipAnnotation] == "true" {
+			klog.V(2).InfoS("Skipping document with skip annotation", "kind", obj.GetObjectKind().GroupVersionKind().Kind, "name", obj.GetName())
+			continue
+		}
+		if validator != nil {
+			if err := validator.validate(document, decodeOpt.MutateFuncs); err != nil {
+				return err
+			}
+		}
+		if decodeOpt.SourceAnnotation != "" {
+			if source := sourceComment(b); source != "" {
+				annotations := obj.GetAnnotations()
+				if annotations == nil {
+					annotations = make(map[string]string)
+				}
+				annotations[decodeOpt.SourceAnnotation] = source
+				obj.SetAnnotations(annotations)
+			}
+		}
+		// the object may be altered by the handler, such as when its request fails
+		gvk, namespace, name := obj.GetObjectKind().GroupVersionKind(), obj.GetNamespace(), obj.GetName()
+		if err := handlerFn(ctx, DecodedDocument{Object: obj, Raw: b}); err != nil {
+			if !decodeOpt.ContinueOnError {
+				return err
+			}
+			objErrs = append(objErrs, &ObjectError{GroupVersionKind: gvk, Namespace: namespace, Name: name, Err: err})
 		}
 	}
+	if len(objErrs) > 0 {
+		return objErrs
+	}
 	return nil
 }
 
@@ -152,8 +494,51 @@ func DecodeAll(ctx context.Context, manifest io.Reader, options ...DecodeOption)
 	return objects, err
 }
 
+// sourceComment returns the template path of the first top-level "# Source:" comment in the document.
+func sourceComment(document []byte) string {
+	for _, line := range strings.Split(string(document), "\n") {
+		if source, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), "# Source:"); ok {
+			return strings.TrimSpace(source)
+		}
+	}
+	return ""
+}
+
+// isJSONArray reports whether document is a top-level array, such as the JSON arrays of objects
+// emitted by some tools in place of a List.
+func isJSONArray(document []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(document), []byte("["))
+}
+
+// splitJSONArray returns the elements of the array document, in order.
+func splitJSONArray(document []byte) ([][]byte, error) {
+	var raw []json.RawMessage
+	if err := yaml.Unmarshal(document, &raw); err != nil {
+		return nil, err
+	}
+	elements := make([][]byte, 0, len(raw))
+	for _, element := range raw {
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+// DecodeAllDocuments behaves like DecodeAll, but retains the raw bytes of each document alongside the
+// decoded object. This is useful to re-emit exactly what was read, e.g. for snapshot testing or diffs.
+// Options may be provided to configure the behavior of the decoder.
+func DecodeAllDocuments(ctx context.Context, manifest io.Reader, options ...DecodeOption) ([]DecodedDocument, error) {
+	documents := []DecodedDocument{}
+	err := DecodeEachDocument(ctx, manifest, func(ctx context.Context, doc DecodedDocument) error {
+		documents = append(documents, doc)
+		return nil
+	}, options...)
+	return documents, err
+}
+
 // DecodeAny decodes any single-document YAML or JSON input using either the innate typing of the scheme.
 // Falls back to the unstructured.Unstructured type if a matching type cannot be found for the Kind.
+// YAML anchors, aliases and merge keys are resolved within the document. A JSON array holding a
+// single object is decoded as that object, while an error is returned for larger arrays.
 // Options may be provided to configure the behavior of the decoder.
 func DecodeAny(manifest io.Reader, options ...DecodeOption) (k8s.Object, error) {
 	decodeOpt := &Options{}
@@ -166,6 +551,16 @@ func DecodeAny(manifest io.Reader, options ...DecodeOption) (k8s.Object, error)
 	if err != nil {
 		return nil, err
 	}
+	if isJSONArray(b) {
+		elements, err := splitJSONArray(b)
+		if err != nil {
+			return nil, err
+		}
+		if len(elements) != 1 {
+			return nil, fmt.Errorf("manifest is an array of %d objects, use DecodeEach or DecodeAll to decode it", len(elements))
+		}
+		b = elements[0]
+	}
 	runtimeObj, _, err := k8sDecoder(b, decodeOpt.DefaultGVK, nil)
 	if runtime.IsNotRegisteredError(err) {
 		// fallback to the unstructured.Unstructured type if a type is not registered for the Object to be decoded
@@ -190,14 +585,29 @@ func DecodeAny(manifest io.Reader, options ...DecodeOption) (k8s.Object, error)
 
 // Decode a single-document YAML or JSON file into the provided object. Patches are applied
 // after decoding to the object to update the loaded resource.
+//
+// Empty documents, or documents holding only comments, following the first one are ignored. An error
+// is returned if the manifest holds more than one non-empty document: use DecodeEach or DecodeAll instead.
 func Decode(manifest io.Reader, obj k8s.Object, options ...DecodeOption) error {
 	decodeOpt := &Options{}
 	for _, opt := range options {
 		opt(decodeOpt)
 	}
-	if err := yaml.NewYAMLOrJSONDecoder(manifest, 1024).Decode(obj); err != nil {
+	decoder := yaml.NewYAMLOrJSONDecoder(manifest, 1024)
+	if err := decoder.Decode(obj); err != nil {
 		return err
 	}
+	for {
+		var extra interface{}
+		if err := decoder.Decode(&extra); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if extra != nil {
+			return errors.New("manifest contains more than one document, use DecodeEach or DecodeAll to decode it")
+		}
+	}
 	for _, patch := range decodeOpt.MutateFuncs {
 		if err := patch(obj); err != nil {
 			return err
@@ -207,7 +617,7 @@ func Decode(manifest io.Reader, obj k8s.Object, options ...DecodeOption) error {
 }
 
 // DecodeFile decodes a single-document YAML or JSON file into the provided object. Patches are applied
-// after decoding to the object to update the loaded resource.
+// after decoding to the object to update the loaded resource. See Decode for the handling of multi-document files.
 func DecodeFile(fsys fs.FS, manifestPath string, obj k8s.Object, options ...DecodeOption) error {
 	f, err := fsys.Open(manifestPath)
 	if err != nil {
@@ -231,6 +641,91 @@ func DefaultGVK(defaults *schema.GroupVersionKind) DecodeOption {
 	}
 }
 
+// WithInclude restricts the files decoded by DecodeEachFile and DecodeAllFiles to those whose
+// base name matches at least one of the glob patterns.
+func WithInclude(globs ...string) DecodeOption {
+	return func(do *Options) {
+		do.Include = append(do.Include, globs...)
+	}
+}
+
+// WithExclude skips the files, resolved by DecodeEachFile and DecodeAllFiles, whose base name
+// matches any of the glob patterns, e.g. "kustomization.yaml" or "*-values.yaml".
+// Excluded files are skipped even if they match a WithInclude pattern.
+func WithExclude(globs ...string) DecodeOption {
+	return func(do *Options) {
+		do.Exclude = append(do.Exclude, globs...)
+	}
+}
+
+// WithSourceAnnotation records the template named by the "# Source:" comment that `helm template`
+// emits ahead of each document in the annotation key of the objects decoded from a stream by
+// DecodeEach, DecodeAll and the functions built on them.
+func WithSourceAnnotation(key string) DecodeOption {
+	return func(do *Options) {
+		do.SourceAnnotation = key
+	}
+}
+
+// WithSkipAnnotation omits the documents decoded from a stream by DecodeEach, DecodeAll and the
+// functions built on them whose SkipAnnotation annotation is "true", so that manifest authors can
+// keep optional or disabled objects inline. Omitted documents are not handed to the handler. The
+// annotation is read once the MutateFuncs are applied, so they may set or clear it.
+func WithSkipAnnotation() DecodeOption {
+	return func(do *Options) {
+		do.SkipAnnotation = true
+	}
+}
+
+// WithDocumentSelector restricts the documents decoded from a stream by DecodeEach, DecodeAll and
+// the functions built on them to the ones selected by selector, such as a single object of a large
+// bundle. Only the apiVersion, kind and name of the other documents are read: they are neither
+// decoded nor mutated, and they are not handed to the handler.
+func WithDocumentSelector(selector DocumentSelector) DecodeOption {
+	return func(do *Options) {
+		do.DocumentSelector = selector
+	}
+}
+
+// selectDocument reads the kind and name declared by document and reports whether the
+// DocumentSelector of the options selects it.
+func selectDocument(document []byte, o *Options) (bool, error) {
+	var header struct {
+		metav1.TypeMeta `json:",inline"`
+		Metadata        struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	if err := yaml.Unmarshal(document, &header); err != nil {
+		return false, err
+	}
+	gvk := header.GroupVersionKind()
+	if gvk.Kind == "" && o.DefaultGVK != nil {
+		gvk = *o.DefaultGVK
+	}
+	return o.DocumentSelector(gvk, header.Metadata.Name), nil
+}
+
+// MutateWhen returns a MutateFunc that applies patch only to the objects matching predicate,
+// leaving the other objects untouched. This allows applying a patch that only makes sense for
+// some kinds, such as setting the replicas of Deployments, when decoding a mixed set of objects.
+func MutateWhen(predicate func(k8s.Object) bool, patch MutateFunc) MutateFunc {
+	return func(obj k8s.Object) error {
+		if !predicate(obj) {
+			return nil
+		}
+		return patch(obj)
+	}
+}
+
+// MatchGVK returns a predicate, to be used with MutateWhen, matching the objects of the given
+// group, version and kind.
+func MatchGVK(gvk schema.GroupVersionKind) func(k8s.Object) bool {
+	return func(obj k8s.Object) bool {
+		return obj.GetObjectKind().GroupVersionKind() == gvk
+	}
+}
+
 // MutateOption can be used to add a custom MutateFunc to the DecodeOption
 // used to configure the decoding of objects
 func MutateOption(m MutateFunc) DecodeOption {
@@ -254,6 +749,18 @@ func MutateLabels(overrides map[string]string) DecodeOption {
 	})
 }
 
+// RunLabel is the label set by WithRunLabel on decoded objects.
+const RunLabel = "e2e-framework.sigs.k8s.io/run"
+
+// WithRunLabel returns a DecodeOption setting the RunLabel label of decoded objects to a run ID
+// generated for each call, along with that ID. Reusing the option across decode calls stamps all
+// the objects of a test run with the same label, so that the teardown can select them for deletion,
+// such as with resources.WithLabelSelector(decoder.RunLabel + "=" + runID).
+func WithRunLabel() (DecodeOption, string) {
+	runID := utilrand.String(8)
+	return MutateLabels(map[string]string{RunLabel: runID}), runID
+}
+
 // MutateAnnotations is an optional parameter to decoding functions that will patch an objects metadata.annotations
 func MutateAnnotations(overrides map[string]string) DecodeOption {
 	return MutateOption(func(obj k8s.Object) error {
@@ -269,6 +776,24 @@ func MutateAnnotations(overrides map[string]string) DecodeOption {
 	})
 }
 
+// MutateAddFinalizer is an optional parameter to decoding functions that will add the finalizer to an objects
+// metadata.finalizers, unless it is already present
+func MutateAddFinalizer(name string) DecodeOption {
+	return MutateOption(func(obj k8s.Object) error {
+		controllerutil.AddFinalizer(obj, name)
+		return nil
+	})
+}
+
+// MutateRemoveFinalizer is an optional parameter to decoding functions that will remove the finalizer from an
+// objects metadata.finalizers, if present
+func MutateRemoveFinalizer(name string) DecodeOption {
+	return MutateOption(func(obj k8s.Object) error {
+		controllerutil.RemoveFinalizer(obj, name)
+		return nil
+	})
+}
+
 // MutateOwnerAnnotations is an optional parameter to decoding functions that will patch objects using the given owner object
 func MutateOwnerAnnotations(owner k8s.Object) DecodeOption {
 	return MutateOption(func(obj k8s.Object) error {
@@ -276,7 +801,8 @@ func MutateOwnerAnnotations(owner k8s.Object) DecodeOption {
 	})
 }
 
-// MutateNamespace is an optional parameter to decoding functions that will patch objects with the given namespace name
+// MutateNamespace is an optional parameter to decoding functions that will patch objects with the given namespace name,
+// overriding the namespace they specify. Use MutateDefaultNamespace to only set the namespace of objects without one.
 func MutateNamespace(namespace string) DecodeOption {
 	return MutateOption(func(obj k8s.Object) error {
 		obj.SetNamespace(namespace)
@@ -284,7 +810,277 @@ func MutateNamespace(namespace string) DecodeOption {
 	})
 }
 
-// CreateHandler returns a HandlerFunc that will create objects
+// MutateDefaultNamespace is an optional parameter to decoding functions that will patch objects with the given
+// namespace name only when they do not specify one, so that manifests spanning multiple namespaces keep targeting
+// their own. The namespace of cluster-scoped objects is ignored by the API operations.
+func MutateDefaultNamespace(namespace string) DecodeOption {
+	return MutateOption(func(obj k8s.Object) error {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		return nil
+	})
+}
+
+// clusterScopedKinds holds the group kinds of the built-in cluster-scoped resources, whose namespace
+// is left unset by WithNamespaceFromEnv.
+var clusterScopedKinds = map[schema.GroupKind]bool{
+	{Kind: "Namespace"}:        true,
+	{Kind: "Node"}:             true,
+	{Kind: "PersistentVolume"}: true,
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}:                 true,
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}:                             true,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:     true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}:   true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicy"}:        true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicyBinding"}: true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                         true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                  true,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:                               true,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                                   true,
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:                                      true,
+	{Group: "storage.k8s.io", Kind: "CSINode"}:                                        true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:                               true,
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                                true,
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                                      true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                       true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}:       true,
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}:                 true,
+}
+
+// WithNamespaceFromEnv is an optional parameter to decoding functions that will patch the objects that do not
+// specify a namespace with the value of the environment variable varName, such as the NAMESPACE variable set by
+// CI systems, or with defaultNamespace when the variable is unset or empty. Built-in cluster-scoped objects, such
+// as Namespaces or ClusterRoles, are left untouched.
+func WithNamespaceFromEnv(varName, defaultNamespace string) DecodeOption {
+	return MutateOption(func(obj k8s.Object) error {
+		if obj.GetNamespace() != "" || clusterScopedKinds[obj.GetObjectKind().GroupVersionKind().GroupKind()] {
+			return nil
+		}
+		namespace := os.Getenv(varName)
+		if namespace == "" {
+			namespace = defaultNamespace
+		}
+		obj.SetNamespace(namespace)
+		return nil
+	})
+}
+
+// MutateContainerEnv returns a MutateFunc that sets the environment variables of env on the container
+// named containerName, or on every container when containerName is empty, such as to point a workload at
+// a service deployed by the test. Variables already defined by the container are overridden. The containers
+// of Pods, Deployments, StatefulSets, DaemonSets, ReplicaSets, Jobs and CronJobs are mutated, whether they
+// are decoded as typed or unstructured objects, and other objects are left untouched.
+func MutateContainerEnv(containerName string, env map[string]string) MutateFunc {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return func(obj k8s.Object) error {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			return mutateUnstructuredContainerEnv(u, containerName, names, env)
+		}
+		spec := podSpecOf(obj)
+		if spec == nil {
+			return nil
+		}
+		for i := range spec.Containers {
+			container := &spec.Containers[i]
+			if containerName != "" && container.Name != containerName {
+				continue
+			}
+			for _, name := range names {
+				container.Env = setEnvVar(container.Env, corev1.EnvVar{Name: name, Value: env[name]})
+			}
+		}
+		return nil
+	}
+}
+
+// podSpecOf returns the pod spec of the typed workload obj, or nil if obj is not a workload.
+func podSpecOf(obj k8s.Object) *corev1.PodSpec {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return &o.Spec
+	case *appsv1.Deployment:
+		return &o.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template.Spec
+	case *appsv1.ReplicaSet:
+		return &o.Spec.Template.Spec
+	case *batchv1.Job:
+		return &o.Spec.Template.Spec
+	case *batchv1.CronJob:
+		return &o.Spec.JobTemplate.Spec.Template.Spec
+	}
+	return nil
+}
+
+// setEnvVar sets envVar in vars, replacing the variable with the same name if any.
+func setEnvVar(vars []corev1.EnvVar, envVar corev1.EnvVar) []corev1.EnvVar {
+	for i := range vars {
+		if vars[i].Name == envVar.Name {
+			vars[i] = envVar
+			return vars
+		}
+	}
+	return append(vars, envVar)
+}
+
+// unstructuredContainersPath returns the path to the containers of the workload of the given kind.
+func unstructuredContainersPath(kind string) []string {
+	switch kind {
+	case "Pod":
+		return []string{"spec", "containers"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		return []string{"spec", "template", "spec", "containers"}
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec", "containers"}
+	}
+	return nil
+}
+
+func mutateUnstructuredContainerEnv(u *unstructured.Unstructured, containerName string, names []string, env map[string]string) error {
+	containersPath := unstructuredContainersPath(u.GetKind())
+	if containersPath == nil {
+		return nil
+	}
+	containers, found, err := unstructured.NestedSlice(u.Object, containersPath...)
+	if err != nil || !found {
+		return err
+	}
+	for i, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected container type %T in %s %q", c, u.GetKind(), u.GetName())
+		}
+		if containerName != "" && container["name"] != containerName {
+			continue
+		}
+		vars, _, err := unstructured.NestedSlice(container, "env")
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			vars = setUnstructuredEnvVar(vars, name, env[name])
+		}
+		container["env"] = vars
+		containers[i] = container
+	}
+	return unstructured.SetNestedSlice(u.Object, containers, containersPath...)
+}
+
+// setUnstructuredEnvVar sets the variable name to value in vars, replacing the variable with the same name if any.
+func setUnstructuredEnvVar(vars []interface{}, name, value string) []interface{} {
+	envVar := map[string]interface{}{"name": name, "value": value}
+	for i, v := range vars {
+		if existing, ok := v.(map[string]interface{}); ok && existing["name"] == name {
+			vars[i] = envVar
+			return vars
+		}
+	}
+	return append(vars, envVar)
+}
+
+// MutateClampResources returns a MutateFunc that caps the cpu and memory requests and limits of the
+// containers and init containers of workloads at maxCPU and maxMemory, such as to run manifests sized
+// for production on a kind cluster, whose pods would otherwise stay Pending. Requests and limits below
+// the ceilings are left as is, and a zero ceiling leaves the resource unclamped. The workloads are
+// mutated as by MutateContainerEnv.
+func MutateClampResources(maxCPU, maxMemory resource.Quantity) MutateFunc {
+	ceilings := corev1.ResourceList{}
+	if !maxCPU.IsZero() {
+		ceilings[corev1.ResourceCPU] = maxCPU
+	}
+	if !maxMemory.IsZero() {
+		ceilings[corev1.ResourceMemory] = maxMemory
+	}
+	return func(obj k8s.Object) error {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			return clampUnstructuredResources(u, ceilings)
+		}
+		spec := podSpecOf(obj)
+		if spec == nil {
+			return nil
+		}
+		for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+			for i := range containers {
+				clampResourceList(containers[i].Resources.Requests, ceilings)
+				clampResourceList(containers[i].Resources.Limits, ceilings)
+			}
+		}
+		return nil
+	}
+}
+
+// clampResourceList caps the quantities of list at the ones of ceilings.
+func clampResourceList(list, ceilings corev1.ResourceList) {
+	for name, ceiling := range ceilings {
+		if quantity, ok := list[name]; ok && quantity.Cmp(ceiling) > 0 {
+			list[name] = ceiling.DeepCopy()
+		}
+	}
+}
+
+func clampUnstructuredResources(u *unstructured.Unstructured, ceilings corev1.ResourceList) error {
+	containersPath := unstructuredContainersPath(u.GetKind())
+	if containersPath == nil {
+		return nil
+	}
+	specPath := containersPath[:len(containersPath)-1]
+	for _, field := range []string{"initContainers", "containers"} {
+		path := append(append([]string{}, specPath...), field)
+		containers, found, err := unstructured.NestedSlice(u.Object, path...)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		for i, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("unexpected container type %T in %s %q", c, u.GetKind(), u.GetName())
+			}
+			for _, kind := range []string{"requests", "limits"} {
+				list, found, err := unstructured.NestedMap(container, "resources", kind)
+				if err != nil {
+					return err
+				}
+				if !found {
+					continue
+				}
+				for name, ceiling := range ceilings {
+					value, ok := list[string(name)]
+					if !ok {
+						continue
+					}
+					quantity, err := resource.ParseQuantity(fmt.Sprint(value))
+					if err != nil {
+						return fmt.Errorf("invalid %s %s of %s %q: %w", name, kind, u.GetKind(), u.GetName(), err)
+					}
+					if quantity.Cmp(ceiling) > 0 {
+						list[string(name)] = ceiling.String()
+					}
+				}
+				if err := unstructured.SetNestedMap(container, list, "resources", kind); err != nil {
+					return err
+				}
+			}
+			containers[i] = container
+		}
+		if err := unstructured.SetNestedSlice(u.Object, containers, path...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateHandler returns a HandlerFunc that will create objects. The objects are updated in place
+// with the state returned by the API server, including names generated from metadata.generateName.
 func CreateHandler(r *resources.Resources, opts ...resources.CreateOption) HandlerFunc {
 	return func(ctx context.Context, obj k8s.Object) error {
 		return r.Create(ctx, obj, opts...)
@@ -315,13 +1111,30 @@ func ReadHandler(r *resources.Resources, handler HandlerFunc) HandlerFunc {
 	}
 }
 
-// UpdateHandler returns a HandlerFunc that will update objects
+// UpdateHandler returns a HandlerFunc that will update objects. Unstructured objects are sent as they are,
+// so fields unknown to the scheme are preserved.
 func UpdateHandler(r *resources.Resources, opts ...resources.UpdateOption) HandlerFunc {
 	return func(ctx context.Context, obj k8s.Object) error {
 		return r.Update(ctx, obj, opts...)
 	}
 }
 
+// UpdatePreservingImmutableHandler returns a HandlerFunc that will update objects with
+// Resources.UpdatePreservingImmutable, so that the manifests can omit the immutable fields
+// assigned by the API server, such as the clusterIP of a Service.
+func UpdatePreservingImmutableHandler(r *resources.Resources, opts ...resources.UpdateOption) HandlerFunc {
+	return func(ctx context.Context, obj k8s.Object) error {
+		return r.UpdatePreservingImmutable(ctx, obj, opts...)
+	}
+}
+
+// PatchHandler returns a HandlerFunc that will apply the patch to objects
+func PatchHandler(r *resources.Resources, patch k8s.Patch, opts ...resources.PatchOption) HandlerFunc {
+	return func(ctx context.Context, obj k8s.Object) error {
+		return r.Patch(ctx, obj, patch, opts...)
+	}
+}
+
 // DeleteHandler returns a HandlerFunc that will delete objects
 func DeleteHandler(r *resources.Resources, opts ...resources.DeleteOption) HandlerFunc {
 	return func(ctx context.Context, obj k8s.Object) error {
@@ -17,19 +17,37 @@ limitations under the License.
 package decoder_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/e2e-framework/klient/decoder"
 	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
 )
 
 const (
@@ -104,6 +122,32 @@ func TestDecodeAny(t *testing.T) {
 	}
 }
 
+func TestDecodeAnyWithAnchors(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "example-configmap-anchors.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	objects, err := decoder.DecodeAll(context.TODO(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("expected 1 object, got: %d", len(objects))
+	}
+	cfg, ok := objects[0].(*v1.ConfigMap)
+	if !ok {
+		t.Fatalf("expected a ConfigMap, got: %T", objects[0])
+	}
+	if cfg.Data["secondary"] != "shared-value" {
+		t.Errorf("expected the alias to resolve to %q, got data: %v", "shared-value", cfg.Data)
+	}
+	if cfg.Annotations["app"] != "example" || cfg.Annotations["owner"] != "e2e" {
+		t.Errorf("expected the merge key to resolve the anchored labels, got annotations: %v", cfg.Annotations)
+	}
+}
+
 func TestDecodeFile(t *testing.T) {
 	testYAML := "example-configmap-1.yaml"
 	testdata := os.DirFS("testdata")
@@ -134,6 +178,23 @@ func TestDecodeFile(t *testing.T) {
 	}
 }
 
+func TestDecodeFileMultiDocument(t *testing.T) {
+	testdata := os.DirFS("testdata")
+
+	cfg := v1.ConfigMap{}
+	if err := decoder.DecodeFile(testdata, "example-configmap-trailing-document.yaml", &cfg); err != nil {
+		t.Fatalf("unexpected error for trailing empty documents: %v", err)
+	}
+	if cfg.Name != "example-trailing-document" || cfg.Data["foo"] != "bar" {
+		t.Fatalf("unexpected object decoded: %v", cfg)
+	}
+
+	cfg = v1.ConfigMap{}
+	if err := decoder.DecodeFile(testdata, "example-multidoc-1.yaml", &cfg); err == nil {
+		t.Fatal("expected an error when decoding a file with multiple documents into a single object")
+	}
+}
+
 func TestDecodeEachFile(t *testing.T) {
 	testdata := os.DirFS(filepath.Join("testdata", "examples"))
 
@@ -170,6 +231,118 @@ func TestDecodeEachFile(t *testing.T) {
 	}
 }
 
+func TestDecodeAllFilesWithFilters(t *testing.T) {
+	testdata := os.DirFS(filepath.Join("testdata", "kustomize", "base"))
+	tests := []struct {
+		name     string
+		options  []decoder.DecodeOption
+		expected int
+	}{
+		{
+			name:     "no filters",
+			expected: 2,
+		},
+		{
+			name:     "exclude kustomization",
+			options:  []decoder.DecodeOption{decoder.WithExclude("kustomization.yaml")},
+			expected: 1,
+		},
+		{
+			name:     "include configmaps",
+			options:  []decoder.DecodeOption{decoder.WithInclude("config*.yaml")},
+			expected: 1,
+		},
+		{
+			name:     "exclude takes precedence",
+			options:  []decoder.DecodeOption{decoder.WithInclude("*.yaml"), decoder.WithExclude("kustomization.yaml")},
+			expected: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects, err := decoder.DecodeAllFiles(context.TODO(), testdata, "*", test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(objects) != test.expected {
+				t.Fatalf("expected %d objects, got: %d", test.expected, len(objects))
+			}
+			for _, obj := range objects {
+				if test.expected == 1 && obj.GetObjectKind().GroupVersionKind().Kind != "ConfigMap" {
+					t.Errorf("expected only the ConfigMap to be decoded, got: %s", obj.GetObjectKind().GroupVersionKind().Kind)
+				}
+			}
+		})
+	}
+
+	if _, err := decoder.DecodeAllFiles(context.TODO(), testdata, "*", decoder.WithExclude("[")); err == nil {
+		t.Error("expected an error for a malformed exclude pattern")
+	}
+}
+
+// openCountingFS tracks the number of files opened from the wrapped filesystem
+// that have not been closed yet.
+type openCountingFS struct {
+	fs.FS
+	open int32
+}
+
+type countedFile struct {
+	fs.File
+	fsys   *openCountingFS
+	closed bool
+}
+
+func (f *countedFile) Close() error {
+	if !f.closed {
+		f.closed = true
+		atomic.AddInt32(&f.fsys.open, -1)
+	}
+	return f.File.Close()
+}
+
+func (c *openCountingFS) Open(name string) (fs.File, error) {
+	f, err := c.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&c.open, 1)
+	return &countedFile{File: f, fsys: c}, nil
+}
+
+func (c *openCountingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(c.FS, name)
+}
+
+func TestDecodeEachFileOpenFiles(t *testing.T) {
+	const files = 500
+	manifests := fstest.MapFS{}
+	for i := 0; i < files; i++ {
+		manifests[fmt.Sprintf("configmap-%d.yaml", i)] = &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: configmap-%d\n", i)),
+		}
+	}
+	fsys := &openCountingFS{FS: manifests}
+
+	decoded := 0
+	err := decoder.DecodeEachFile(context.TODO(), fsys, "*.yaml", func(ctx context.Context, obj k8s.Object) error {
+		decoded++
+		if open := atomic.LoadInt32(&fsys.open); open > 1 {
+			t.Fatalf("expected at most one open file while decoding, got %d", open)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != files {
+		t.Errorf("expected %d decoded objects, got %d", files, decoded)
+	}
+	if open := atomic.LoadInt32(&fsys.open); open != 0 {
+		t.Errorf("expected all files to be closed, %d are still open", open)
+	}
+}
+
 func TestDecodeAllFiles(t *testing.T) {
 	// load `testdata/examples/example-sa*`
 	testdata := os.DirFS(filepath.Join("testdata", "examples"))
@@ -217,6 +390,59 @@ func TestDecodeEach(t *testing.T) {
 	}
 }
 
+func TestDecodeEachCanceled(t *testing.T) {
+	testYAML := filepath.Join("testdata", "example-multidoc-1.yaml")
+	f, err := os.Open(testYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	count := 0
+	err = decoder.DecodeEach(ctx, f, func(ctx context.Context, obj k8s.Object) error {
+		count++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected decoding to stop with %v, got: %v", context.Canceled, err)
+	}
+	if count != 1 {
+		t.Fatalf("expected decoding to stop after the first document, got %d documents", count)
+	}
+
+	count = 0
+	err = decoder.DecodeEachFile(ctx, os.DirFS("testdata"), "*.yaml", func(ctx context.Context, obj k8s.Object) error {
+		count++
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || count != 0 {
+		t.Fatalf("expected no file to be decoded with a canceled context, got %d documents and error: %v", count, err)
+	}
+}
+
+func TestDecodeEachJSONL(t *testing.T) {
+	stream := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"first"},"data":{"foo":"bar"}}
+
+{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"second"},"data":{"foo":"baz"}}`
+	var names []string
+	err := decoder.DecodeEachJSONL(context.TODO(), strings.NewReader(stream), func(ctx context.Context, obj k8s.Object) error {
+		if _, ok := obj.(*v1.ConfigMap); !ok {
+			t.Fatalf("unexpected type returned not ConfigMap: %T", obj)
+		}
+		names = append(names, obj.GetName())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"first", "second"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected documents %v, got: %v", expected, names)
+	}
+}
+
 func TestDecodeAll(t *testing.T) {
 	for _, file := range []string{"example-multidoc-1.yaml", "example-multidoc-emptyitemcomment.yaml"} {
 		t.Run(fmt.Sprintf("Testing multi doc with %s", file), func(t *testing.T) {
@@ -236,6 +462,243 @@ func TestDecodeAll(t *testing.T) {
 	}
 }
 
+func TestDecodeAllJSONArray(t *testing.T) {
+	manifest := `[
+  {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "first"}},
+  {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "second"}}
+]`
+	objects, err := decoder.DecodeAll(context.TODO(), strings.NewReader(manifest), decoder.MutateLabels(map[string]string{"injected": "label"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := 2, len(objects); got != expected {
+		t.Fatalf("expected %d objects, got: %d", expected, got)
+	}
+	for i, name := range []string{"first", "second"} {
+		cm, ok := objects[i].(*v1.ConfigMap)
+		if !ok {
+			t.Fatalf("expected object %d to be a ConfigMap, got %T", i, objects[i])
+		}
+		if cm.Name != name {
+			t.Errorf("expected object %d to be named %q, got %q", i, name, cm.Name)
+		}
+		if cm.Labels["injected"] != "label" {
+			t.Errorf("expected ConfigMap %s to be patched, got labels %v", cm.Name, cm.Labels)
+		}
+	}
+
+	if _, err := decoder.DecodeAny(strings.NewReader(manifest)); err == nil {
+		t.Error("expected DecodeAny to fail decoding an array of two objects")
+	}
+}
+
+func TestDecodeAllHelmTemplate(t *testing.T) {
+	testYAML := filepath.Join("testdata", "helm-template.yaml")
+	f, err := os.Open(testYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	const sourceKey = "e2e-framework.sigs.k8s.io/source"
+	objects, err := decoder.DecodeAll(context.TODO(), f, decoder.WithSourceAnnotation(sourceKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := 3, len(objects); got != expected {
+		t.Fatalf("expected %d documents, got: %d", expected, got)
+	}
+	expected := map[string]string{
+		"ServiceAccount": "example/templates/serviceaccount.yaml",
+		"ConfigMap":      "example/templates/configmap.yaml",
+		"Service":        "example/templates/service.yaml",
+	}
+	for _, obj := range objects {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		if got := obj.GetAnnotations()[sourceKey]; got != expected[kind] {
+			t.Errorf("expected %s source annotation %q, got %q", kind, expected[kind], got)
+		}
+	}
+}
+
+func TestDecodeAllDocuments(t *testing.T) {
+	testYAML := filepath.Join("testdata", "example-multidoc-1.yaml")
+	f, err := os.Open(testYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	documents, err := decoder.DecodeAllDocuments(context.TODO(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := 2, len(documents); got != expected {
+		t.Fatalf("expected %d documents, got: %d", expected, got)
+	}
+	for _, doc := range documents {
+		if len(doc.Raw) == 0 {
+			t.Fatalf("expected raw bytes for %s", doc.Object.GetName())
+		}
+		obj, err := decoder.DecodeAny(bytes.NewReader(doc.Raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(obj, doc.Object) {
+			t.Fatalf("raw bytes of %s did not round-trip: expected %v, got %v", doc.Object.GetName(), doc.Object, obj)
+		}
+	}
+}
+
+func TestDecodeAllWithDocumentSelector(t *testing.T) {
+	testYAML := filepath.Join("testdata", "example-multidoc-bundle.yaml")
+	f, err := os.Open(testYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	mutated := 0
+	countMutations := decoder.MutateOption(func(obj k8s.Object) error {
+		mutated++
+		return nil
+	})
+	selectConfigMap := decoder.WithDocumentSelector(func(gvk schema.GroupVersionKind, name string) bool {
+		return gvk.Kind == "ConfigMap" && name == "example-bundle"
+	})
+	objects, err := decoder.DecodeAll(context.TODO(), f, selectConfigMap, countMutations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := 1, len(objects); got != expected {
+		t.Fatalf("expected %d objects, got: %d", expected, got)
+	}
+	cm, ok := objects[0].(*v1.ConfigMap)
+	if !ok || cm.Name != "example-bundle" || cm.Data["foo"] != "bar" {
+		t.Fatalf("expected ConfigMap example-bundle, got: %T %v", objects[0], objects[0])
+	}
+	if mutated != 1 {
+		t.Errorf("expected only the selected document to be mutated, got %d mutations", mutated)
+	}
+}
+
+func TestDecodeAllTar(t *testing.T) {
+	entries := []struct {
+		name string
+		body string
+		dir  bool
+	}{
+		{name: "manifests/", dir: true},
+		{name: "manifests/configmap.yaml", body: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: tar-configmap\n"},
+		{name: "manifests/README.md", body: "not a manifest"},
+		{name: "manifests/serviceaccount.json", body: `{"apiVersion": "v1", "kind": "ServiceAccount", "metadata": {"name": "tar-serviceaccount"}}`},
+	}
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.body)), Typeflag: tar.TypeReg}
+		if entry.dir {
+			header.Mode, header.Typeflag = 0o755, tar.TypeDir
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(archive.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{"tar": archive.Bytes(), "tar.gz": compressed.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			objects, err := decoder.DecodeAllTar(context.TODO(), bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected, got := 2, len(objects); got != expected {
+				t.Fatalf("expected %d objects, got: %d", expected, got)
+			}
+			if cm, ok := objects[0].(*v1.ConfigMap); !ok || cm.Name != "tar-configmap" {
+				t.Fatalf("expected ConfigMap tar-configmap first, got: %T %s", objects[0], objects[0].GetName())
+			}
+			if sa, ok := objects[1].(*v1.ServiceAccount); !ok || sa.Name != "tar-serviceaccount" {
+				t.Fatalf("expected ServiceAccount tar-serviceaccount second, got: %T %s", objects[1], objects[1].GetName())
+			}
+		})
+	}
+}
+
+func TestDecodeEachTarWithContinueOnError(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for _, name := range []string{"first", "second", "third"} {
+		body := fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\n", name)
+		if err := tw.WriteHeader(&tar.Header{Name: name + ".yaml", Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var handled []string
+	err := decoder.DecodeEachTar(context.TODO(), &archive, func(ctx context.Context, obj k8s.Object) error {
+		if obj.GetName() == "second" {
+			return fmt.Errorf("rejected")
+		}
+		handled = append(handled, obj.GetName())
+		return nil
+	}, decoder.WithContinueOnError())
+
+	var objErrs decoder.ObjectErrors
+	if !errors.As(err, &objErrs) {
+		t.Fatalf("expected ObjectErrors, got: %v", err)
+	}
+	if len(objErrs) != 1 || objErrs[0].Name != "second" {
+		t.Fatalf("expected the error to name the second configmap only, got: %v", err)
+	}
+	if !reflect.DeepEqual(handled, []string{"first", "third"}) {
+		t.Errorf("expected the entries after the failure to be decoded, got: %v", handled)
+	}
+}
+
+func TestDecodeKustomize(t *testing.T) {
+	objects, err := decoder.DecodeAllKustomize(context.TODO(), filepath.Join("testdata", "kustomize", "overlay"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := 1, len(objects); got != expected {
+		t.Fatalf("expected %d objects, got: %d", expected, got)
+	}
+	cfg, ok := objects[0].(*v1.ConfigMap)
+	if !ok {
+		t.Fatalf("unexpected type returned not ConfigMap: %T", objects[0])
+	}
+	if expected := "overlay-kustomize-example"; cfg.Name != expected {
+		t.Fatalf("expected name %q, got: %q", expected, cfg.Name)
+	}
+	if expected := "overlay"; cfg.Data["foo"] != expected {
+		t.Fatalf("expected patched value %q for key 'foo', got: %q", expected, cfg.Data["foo"])
+	}
+
+	if err := decoder.DecodeKustomize(context.TODO(), filepath.Join("testdata", "examples"), decoder.NoopHandler(nil)); err == nil {
+		t.Fatal("expected an error decoding a directory without a kustomization")
+	}
+}
+
 func TestDecodersWithMutateFunc(t *testing.T) {
 	t.Run("DecodeAny", func(t *testing.T) {
 		testYAML := filepath.Join("testdata", "example-configmap-3.json")
@@ -265,46 +728,311 @@ func TestDecodersWithMutateFunc(t *testing.T) {
 	})
 }
 
-func TestMutateAnnotations(t *testing.T) {
-	testObj := &v1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Annotations: map[string]string{"injected": "original"},
-		},
-	}
-	options := &decoder.Options{}
-	opt := decoder.MutateAnnotations(map[string]string{"injected": testAnnotation})
-	opt(options)
-	for _, fn := range options.MutateFuncs {
-		if err := fn(testObj); err != nil {
-			t.Fatal(err)
+func TestMutateWhen(t *testing.T) {
+	replicas := int32(3)
+	setReplicas := func(obj k8s.Object) error {
+		deployment, ok := obj.(*appsv1.Deployment)
+		if !ok {
+			return fmt.Errorf("cannot set replicas of %T", obj)
 		}
+		deployment.Spec.Replicas = &replicas
+		return nil
 	}
 
-	if testObj.Annotations["injected"] != testAnnotation {
-		t.Fatal("expected object annotation to be overwritten")
-	}
-}
-
-func TestHandlerFuncs(t *testing.T) {
-	handlerNS := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "handler-test"}}
-	res, err := resources.New(cfg)
+	testdata := os.DirFS(filepath.Join("testdata", "mixed-kinds"))
+	objects, err := decoder.DecodeAllFiles(context.TODO(), testdata, "*.yaml",
+		decoder.MutateOption(decoder.MutateWhen(decoder.MatchGVK(appsv1.SchemeGroupVersion.WithKind("Deployment")), setReplicas)))
 	if err != nil {
-		t.Fatalf("Error creating new resources object: %v", err)
+		t.Fatal(err)
 	}
-	err = res.Create(context.TODO(), handlerNS)
-	if err != nil {
-		t.Fatalf("error while creating namespace %q: %s", handlerNS.Name, err)
+	if expected, got := 3, len(objects); got != expected {
+		t.Fatalf("expected %d objects, got: %d", expected, got)
 	}
-	testdata := os.DirFS(filepath.Join("testdata", "examples"))
-	patches := []decoder.DecodeOption{decoder.MutateNamespace(handlerNS.Name), decoder.MutateLabels(map[string]string{"injected": testLabel})}
-	// lookup all objects to use for verification / deletion steps later on
-	objects, err := decoder.DecodeAllFiles(context.TODO(), testdata, "*", patches...)
-	if err != nil {
-		t.Fatal(err)
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			if o.Spec.Replicas == nil || *o.Spec.Replicas != replicas {
+				t.Errorf("expected deployment %s to have %d replicas, got %v", o.Name, replicas, o.Spec.Replicas)
+			}
+		case *v1.Service:
+			if len(o.Spec.Ports) != 1 {
+				t.Errorf("expected service %s to be untouched, got %v", o.Name, o.Spec)
+			}
+		default:
+			t.Errorf("unexpected object of type %T", obj)
+		}
 	}
+}
 
-	t.Run("DecodeEach_Create", func(t *testing.T) {
-		if err := decoder.DecodeEachFile(context.TODO(), testdata, "*", decoder.CreateHandler(res), patches...); err != nil {
+func TestWithNamespaceFromEnv(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: no-namespace
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: with-namespace
+  namespace: explicit
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cluster-scoped
+`
+	t.Run("from env", func(t *testing.T) {
+		t.Setenv("E2E_TEST_NAMESPACE", "from-env")
+		objects, err := decoder.DecodeAll(context.TODO(), strings.NewReader(manifest), decoder.WithNamespaceFromEnv("E2E_TEST_NAMESPACE", "fallback"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, expected := range []string{"from-env", "explicit", ""} {
+			if got := objects[i].GetNamespace(); got != expected {
+				t.Errorf("expected %s to have namespace %q, got: %q", objects[i].GetName(), expected, got)
+			}
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		t.Setenv("E2E_TEST_NAMESPACE", "")
+		objects, err := decoder.DecodeAll(context.TODO(), strings.NewReader(manifest), decoder.WithNamespaceFromEnv("E2E_TEST_NAMESPACE", "fallback"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := objects[0].GetNamespace(); got != "fallback" {
+			t.Errorf("expected %s to have namespace %q, got: %q", objects[0].GetName(), "fallback", got)
+		}
+	})
+}
+
+func TestMutateContainerEnv(t *testing.T) {
+	env := map[string]string{"API_URL": "http://test-service", "LOG_LEVEL": "debug"}
+
+	t.Run("typed deployment", func(t *testing.T) {
+		deployment := &appsv1.Deployment{
+			Spec: appsv1.DeploymentSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{Name: "app", Env: []v1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "PORT", Value: "8080"}}},
+							{Name: "sidecar"},
+						},
+					},
+				},
+			},
+		}
+		if err := decoder.MutateContainerEnv("app", env)(deployment); err != nil {
+			t.Fatal(err)
+		}
+		expected := []v1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "PORT", Value: "8080"}, {Name: "API_URL", Value: "http://test-service"}}
+		if got := deployment.Spec.Template.Spec.Containers[0].Env; !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected env of container app to be %v, got: %v", expected, got)
+		}
+		if got := deployment.Spec.Template.Spec.Containers[1].Env; len(got) != 0 {
+			t.Errorf("expected env of container sidecar to be untouched, got: %v", got)
+		}
+	})
+
+	t.Run("unstructured cronjob", func(t *testing.T) {
+		cronJob := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "CronJob",
+			"metadata":   map[string]interface{}{"name": "report"},
+			"spec": map[string]interface{}{
+				"schedule": "* * * * *",
+				"jobTemplate": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"template": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"containers": []interface{}{
+									map[string]interface{}{"name": "report", "env": []interface{}{map[string]interface{}{"name": "LOG_LEVEL", "value": "info"}}},
+									map[string]interface{}{"name": "uploader"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}}
+		if err := decoder.MutateContainerEnv("", env)(cronJob); err != nil {
+			t.Fatal(err)
+		}
+		containers, _, err := unstructured.NestedSlice(cronJob.Object, "spec", "jobTemplate", "spec", "template", "spec", "containers")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := [][]interface{}{
+			{map[string]interface{}{"name": "LOG_LEVEL", "value": "debug"}, map[string]interface{}{"name": "API_URL", "value": "http://test-service"}},
+			{map[string]interface{}{"name": "API_URL", "value": "http://test-service"}, map[string]interface{}{"name": "LOG_LEVEL", "value": "debug"}},
+		}
+		for i, c := range containers {
+			got, _, err := unstructured.NestedSlice(c.(map[string]interface{}), "env")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expected[i]) {
+				t.Errorf("expected env of container %d to be %v, got: %v", i, expected[i], got)
+			}
+		}
+	})
+
+	t.Run("other kinds", func(t *testing.T) {
+		cm := &v1.ConfigMap{Data: map[string]string{"foo": "bar"}}
+		if err := decoder.MutateContainerEnv("", env)(cm); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestMutateClampResources(t *testing.T) {
+	clamp := decoder.MutateClampResources(resource.MustParse("500m"), resource.MustParse("256Mi"))
+
+	t.Run("typed deployment", func(t *testing.T) {
+		deployment := &appsv1.Deployment{
+			Spec: appsv1.DeploymentSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{
+							Name: "app",
+							Resources: v1.ResourceRequirements{
+								Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("128Mi")},
+								Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("8Gi")},
+							},
+						}},
+					},
+				},
+			},
+		}
+		if err := clamp(deployment); err != nil {
+			t.Fatal(err)
+		}
+		requirements := deployment.Spec.Template.Spec.Containers[0].Resources
+		expected := map[string]string{
+			"cpu request":    "500m",
+			"memory request": "128Mi",
+			"cpu limit":      "500m",
+			"memory limit":   "256Mi",
+		}
+		got := map[string]string{
+			"cpu request":    requirements.Requests.Cpu().String(),
+			"memory request": requirements.Requests.Memory().String(),
+			"cpu limit":      requirements.Limits.Cpu().String(),
+			"memory limit":   requirements.Limits.Memory().String(),
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected resources %v, got: %v", expected, got)
+		}
+	})
+
+	t.Run("unstructured pod", func(t *testing.T) {
+		pod := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata":   map[string]interface{}{"name": "app"},
+			"spec": map[string]interface{}{
+				"initContainers": []interface{}{
+					map[string]interface{}{"name": "init", "resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": int64(2)}}},
+				},
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app", "resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "250m", "memory": "1Gi"}}},
+				},
+			},
+		}}
+		if err := clamp(pod); err != nil {
+			t.Fatal(err)
+		}
+		initContainers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "initContainers")
+		if got := initContainers[0].(map[string]interface{})["resources"].(map[string]interface{})["requests"].(map[string]interface{})["cpu"]; got != "500m" {
+			t.Errorf("expected init container cpu request to be clamped to 500m, got: %v", got)
+		}
+		containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+		limits := containers[0].(map[string]interface{})["resources"].(map[string]interface{})["limits"].(map[string]interface{})
+		if expected := map[string]interface{}{"cpu": "250m", "memory": "256Mi"}; !reflect.DeepEqual(limits, expected) {
+			t.Errorf("expected container limits %v, got: %v", expected, limits)
+		}
+	})
+}
+func TestMutateAnnotations(t *testing.T) {
+	testObj := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"injected": "original"},
+		},
+	}
+	options := &decoder.Options{}
+	opt := decoder.MutateAnnotations(map[string]string{"injected": testAnnotation})
+	opt(options)
+	for _, fn := range options.MutateFuncs {
+		if err := fn(testObj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if testObj.Annotations["injected"] != testAnnotation {
+		t.Fatal("expected object annotation to be overwritten")
+	}
+}
+
+func TestMutateFinalizers(t *testing.T) {
+	const finalizer = "e2e-framework.sigs.k8s.io/test"
+	testObj := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Finalizers: []string{"existing"},
+		},
+	}
+	mutate := func(opts ...decoder.DecodeOption) {
+		options := &decoder.Options{}
+		for _, opt := range opts {
+			opt(options)
+		}
+		for _, fn := range options.MutateFuncs {
+			if err := fn(testObj); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	mutate(decoder.MutateAddFinalizer(finalizer), decoder.MutateAddFinalizer(finalizer))
+	if expected := []string{"existing", finalizer}; !reflect.DeepEqual(testObj.Finalizers, expected) {
+		t.Fatalf("expected finalizers %v, got %v", expected, testObj.Finalizers)
+	}
+
+	mutate(decoder.MutateRemoveFinalizer(finalizer), decoder.MutateRemoveFinalizer(finalizer), decoder.MutateRemoveFinalizer("absent"))
+	if expected := []string{"existing"}; !reflect.DeepEqual(testObj.Finalizers, expected) {
+		t.Fatalf("expected finalizers %v, got %v", expected, testObj.Finalizers)
+	}
+}
+
+func TestHandlerFuncs(t *testing.T) {
+	handlerNS := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "handler-test"}}
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	err = res.Create(context.TODO(), handlerNS)
+	if err != nil {
+		t.Fatalf("error while creating namespace %q: %s", handlerNS.Name, err)
+	}
+	testdata := os.DirFS(filepath.Join("testdata", "examples"))
+	patches := []decoder.DecodeOption{decoder.MutateNamespace(handlerNS.Name), decoder.MutateLabels(map[string]string{"injected": testLabel})}
+	// lookup all objects to use for verification / deletion steps later on
+	objects, err := decoder.DecodeAllFiles(context.TODO(), testdata, "*", patches...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("DecodeEach_Create", func(t *testing.T) {
+		create := decoder.CreateHandler(res)
+		if err := decoder.DecodeEachFile(context.TODO(), testdata, "*", func(ctx context.Context, obj k8s.Object) error {
+			if err := create(ctx, obj); err != nil {
+				return err
+			}
+			// the object is updated in place with the state returned by the API server
+			if obj.GetUID() == "" || obj.GetResourceVersion() == "" {
+				t.Errorf("expected server assigned fields to be set on %s", obj.GetName())
+			}
+			return nil
+		}, patches...); err != nil {
 			t.Fatal(err)
 		}
 	})
@@ -344,6 +1072,21 @@ func TestHandlerFuncs(t *testing.T) {
 		}
 	})
 
+	t.Run("DecodeEach_Patch", func(t *testing.T) {
+		patch := k8s.Patch{PatchType: types.MergePatchType, Data: []byte(`{"metadata":{"annotations":{"patched":"true"}}}`)}
+		if err := decoder.DecodeEachFile(context.TODO(), testdata, "*", decoder.PatchHandler(res, patch), patches...); err != nil {
+			t.Fatal(err)
+		}
+		if err := decoder.DecodeEachFile(context.TODO(), testdata, "*", decoder.ReadHandler(res, func(ctx context.Context, obj k8s.Object) error {
+			if annotations := obj.GetAnnotations(); annotations["patched"] != "true" {
+				t.Fatalf("expected %s to be patched, got annotations: %v", obj.GetName(), annotations)
+			}
+			return nil
+		}), decoder.MutateNamespace(handlerNS.Name)); err != nil {
+			t.Fatal(err)
+		}
+	})
+
 	t.Run("DecodeEach_Delete", func(t *testing.T) {
 		if err := decoder.DecodeEachFile(context.TODO(), testdata, "*", decoder.DeleteHandler(res), patches...); err != nil {
 			t.Fatal(err)
@@ -373,3 +1116,596 @@ func TestHandlerFuncs(t *testing.T) {
 		}
 	})
 }
+
+func TestApplyWithManifestDirProgress(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "progress-test"}}
+	if err := res.Create(context.TODO(), namespace); err != nil {
+		t.Fatalf("error while creating namespace %q: %s", namespace.Name, err)
+	}
+	defer func() {
+		_ = res.Delete(context.TODO(), namespace)
+	}()
+
+	const count = 5
+	dir := t.TempDir()
+	for i := 0; i < count; i++ {
+		manifest := fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: progress-%d\n", i)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("configmap-%d.yaml", i)), []byte(manifest), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var done []int
+	var names []string
+	progress := decoder.WithProgress(func(d, total int, obj k8s.Object) {
+		if total != count {
+			t.Errorf("expected a total of %d objects, got: %d", count, total)
+		}
+		done = append(done, d)
+		names = append(names, obj.GetName())
+	})
+	if err := decoder.ApplyWithManifestDir(context.TODO(), res, dir, "*", nil, decoder.MutateNamespace(namespace.Name), progress); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(done) != count {
+		t.Fatalf("expected the progress to be reported %d times, got: %d", count, len(done))
+	}
+	for i, d := range done {
+		if d != i+1 {
+			t.Errorf("expected progress %d to report %d objects done, got: %d", i, i+1, d)
+		}
+		if expected := fmt.Sprintf("progress-%d", i); names[i] != expected {
+			t.Errorf("expected progress %d to report object %s, got: %s", i, expected, names[i])
+		}
+	}
+}
+
+func TestManifestDirWithDefaultNamespace(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	namespaces := []*v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "multi-namespace-a"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "multi-namespace-b"}},
+	}
+	for _, ns := range namespaces {
+		if err := res.Create(context.TODO(), ns); err != nil {
+			t.Fatalf("error while creating namespace %q: %s", ns.Name, err)
+		}
+	}
+	defer func() {
+		for _, ns := range namespaces {
+			_ = res.Delete(context.TODO(), ns)
+		}
+	}()
+
+	dir := filepath.Join("testdata", "multi-namespace")
+	defaultNamespace := decoder.MutateDefaultNamespace("multi-namespace-b")
+	if err := decoder.ApplyWithManifestDir(context.TODO(), res, dir, "*", nil, defaultNamespace); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"multi-namespace-explicit": "multi-namespace-a",
+		"multi-namespace-default":  "multi-namespace-b",
+	}
+	for name, namespace := range expected {
+		var cm v1.ConfigMap
+		if err := res.Get(context.TODO(), name, namespace, &cm); err != nil {
+			t.Fatalf("expected configmap %s in namespace %s: %s", name, namespace, err)
+		}
+	}
+
+	if err := decoder.DeleteWithManifestDir(context.TODO(), res, dir, "*", nil, defaultNamespace); err != nil {
+		t.Fatal(err)
+	}
+	for name, namespace := range expected {
+		var cm v1.ConfigMap
+		if err := res.Get(context.TODO(), name, namespace, &cm); !apierrors.IsNotFound(err) {
+			t.Errorf("expected configmap %s in namespace %s to be deleted, got: %v", name, namespace, err)
+		}
+	}
+}
+
+func TestApplyWithManifestDirAndPrune(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	dir := t.TempDir()
+	manifests := map[string]string{
+		"configmap-kept.yaml":   "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: prune-kept\n",
+		"configmap-pruned.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: prune-removed\n",
+		"serviceaccount.yaml":   "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: prune-kept\n",
+	}
+	for name, manifest := range manifests {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(manifest), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defaultNamespace := decoder.MutateNamespace("default")
+	defer func() {
+		_ = decoder.DeleteWithManifestDir(context.TODO(), res, dir, "*", nil, defaultNamespace)
+	}()
+
+	if err := decoder.ApplyWithManifestDirAndPrune(context.TODO(), res, dir, "*", "prune-test", defaultNamespace); err != nil {
+		t.Fatal(err)
+	}
+	var cm v1.ConfigMap
+	if err := res.Get(context.TODO(), "prune-removed", "default", &cm); err != nil {
+		t.Fatal("expected the configmap to be applied", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "configmap-pruned.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoder.ApplyWithManifestDirAndPrune(context.TODO(), res, dir, "*", "prune-test", defaultNamespace); err != nil {
+		t.Fatal(err)
+	}
+
+	err = wait.For(conditions.New(res).ResourceDeleted(&cm), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Error("expected the removed configmap to be pruned", err)
+	}
+	if err := res.Get(context.TODO(), "prune-kept", "default", &v1.ConfigMap{}); err != nil {
+		t.Error("expected the configmap still in the manifests to be kept", err)
+	}
+	if err := res.Get(context.TODO(), "prune-kept", "default", &v1.ServiceAccount{}); err != nil {
+		t.Error("expected the service account still in the manifests to be kept", err)
+	}
+}
+
+func TestApplyWithPreservedManagedFields(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	dir := t.TempDir()
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: managed-fields
+  managedFields:
+    - manager: other-controller
+      operation: Apply
+      apiVersion: v1
+      fieldsType: FieldsV1
+      fieldsV1:
+        f:data:
+          f:owned: {}
+data:
+  owned: other-controller
+`
+	if err := os.WriteFile(filepath.Join(dir, "configmap.yaml"), []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defaultNamespace := decoder.MutateNamespace("default")
+	defer func() {
+		_ = decoder.DeleteWithManifestDir(context.TODO(), res, dir, "*", nil, defaultNamespace)
+	}()
+
+	err = decoder.ApplyWithManifestDirAndPrune(context.TODO(), res, dir, "*", "managed-fields-test", defaultNamespace, decoder.WithPreservedManagedFields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cm v1.ConfigMap
+	if err := res.Get(context.TODO(), "managed-fields", "default", &cm); err != nil {
+		t.Fatal(err)
+	}
+	if managers := cm.GetManagedFields(); len(managers) != 1 || managers[0].Manager != "other-controller" {
+		t.Fatalf("expected the managed fields of the manifest to be preserved, got %v", managers)
+	}
+
+	// applying a field owned by other-controller from another field manager conflicts
+	patch := k8s.Patch{
+		PatchType: types.ApplyPatchType,
+		Data:      []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"managed-fields","namespace":"default"},"data":{"owned":"test"}}`),
+	}
+	err = res.Patch(context.TODO(), &cm, patch, func(po *metav1.PatchOptions) {
+		po.FieldManager = "test"
+	})
+	if !apierrors.IsConflict(err) {
+		t.Errorf("expected a conflict applying a field owned by other-controller, got %v", err)
+	}
+}
+
+func TestDecodeWithSchemaValidation(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	// validation is done by the client, so the namespace of the objects does not have to exist
+	const validationNS = "schema-validation-missing"
+
+	manifest := func(replicasField string) string {
+		return fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: schema-validation
+spec:
+  %s: 1
+  selector:
+    matchLabels:
+      app: schema-validation
+  template:
+    metadata:
+      labels:
+        app: schema-validation
+    spec:
+      containers:
+        - name: nginx
+          image: nginx
+`, replicasField)
+	}
+	options := []decoder.DecodeOption{decoder.WithSchemaValidation(res), decoder.MutateNamespace(validationNS)}
+
+	err = decoder.DecodeEach(context.TODO(), strings.NewReader(manifest("replias")), decoder.NoopHandler(res), options...)
+	if err == nil {
+		t.Fatal("expected a schema validation error for a misspelled field")
+	}
+	if !strings.Contains(err.Error(), "replias") {
+		t.Errorf("expected the validation error to name the misspelled field, got: %v", err)
+	}
+
+	if err := decoder.DecodeEach(context.TODO(), strings.NewReader(manifest("replicas")), decoder.NoopHandler(res), options...); err != nil {
+		t.Fatalf("unexpected schema validation error for a valid manifest: %v", err)
+	}
+}
+
+func TestDecodeWithConvertToPreferredVersion(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	manifest := `apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: converted
+spec:
+  minAvailable: 1
+  selector:
+    matchLabels:
+      app: converted
+`
+	objects, err := decoder.DecodeAll(context.TODO(), strings.NewReader(manifest), decoder.WithConvertToPreferredVersion(res))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("expected 1 object, got %d", len(objects))
+	}
+	pdb, ok := objects[0].(*policyv1.PodDisruptionBudget)
+	if !ok {
+		t.Fatalf("expected a policy/v1 PodDisruptionBudget, got %T", objects[0])
+	}
+	if apiVersion := pdb.GetObjectKind().GroupVersionKind().GroupVersion().String(); apiVersion != "policy/v1" {
+		t.Errorf("expected apiVersion policy/v1, got %q", apiVersion)
+	}
+	if pdb.Spec.MinAvailable == nil || pdb.Spec.MinAvailable.IntValue() != 1 {
+		t.Errorf("expected minAvailable to be carried over, got %v", pdb.Spec.MinAvailable)
+	}
+
+	// documents already at the preferred version are left as is
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: unchanged
+`
+	objects, err = decoder.DecodeAll(context.TODO(), strings.NewReader(deployment), decoder.WithConvertToPreferredVersion(res))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects[0].(*appsv1.Deployment); !ok {
+		t.Errorf("expected an apps/v1 Deployment, got %T", objects[0])
+	}
+
+	// the metric targets of autoscaling/v2beta1 were restructured in autoscaling/v2
+	hpa := `apiVersion: autoscaling/v2beta1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: lossy
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: lossy
+  maxReplicas: 3
+  metrics:
+  - type: Resource
+    resource:
+      name: cpu
+      targetAverageUtilization: 50
+`
+	_, err = decoder.DecodeAll(context.TODO(), strings.NewReader(hpa), decoder.WithConvertToPreferredVersion(res))
+	if err == nil || !strings.Contains(err.Error(), "targetAverageUtilization") {
+		t.Errorf("expected the lossy conversion of the HorizontalPodAutoscaler to be rejected, got %v", err)
+	}
+}
+
+func TestDecodeWithOverlays(t *testing.T) {
+	base := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: overlays
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: overlays
+  template:
+    metadata:
+      labels:
+        app: overlays
+    spec:
+      containers:
+        - name: nginx
+          image: nginx
+          env:
+            - name: MODE
+              value: base
+`
+	replicas := `spec:
+  replicas: 3
+`
+	env := `spec:
+  template:
+    spec:
+      containers:
+        - name: nginx
+          env:
+            - name: LEVEL
+              value: debug
+`
+	obj, err := decoder.DecodeWithOverlays(strings.NewReader(base), strings.NewReader(replicas), strings.NewReader(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		t.Fatalf("expected a Deployment, got %T", obj)
+	}
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 3 {
+		t.Errorf("expected the replicas of the overlay, got %v", deployment.Spec.Replicas)
+	}
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) != 1 || containers[0].Image != "nginx" {
+		t.Fatalf("expected the container of the base to be merged with the overlay, got %+v", containers)
+	}
+	expectedEnv := []v1.EnvVar{{Name: "LEVEL", Value: "debug"}, {Name: "MODE", Value: "base"}}
+	if !reflect.DeepEqual(containers[0].Env, expectedEnv) {
+		t.Errorf("expected env %+v, got %+v", expectedEnv, containers[0].Env)
+	}
+}
+
+func TestDecodeEachFileWithValues(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .name }}
+spec:
+  replicas: {{ .replicas }}
+  template:
+    spec:
+      containers:
+        - name: app
+          image: {{ .image.repository }}:{{ .image.tag }}
+`
+	values := `name: values-test
+image:
+  tag: "1.27"
+`
+	if err := os.WriteFile(filepath.Join(dir, "deployment.yaml"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "values.yaml"), []byte(values), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defaults := map[string]interface{}{
+		"replicas": 1,
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "latest"},
+	}
+
+	var deployments []*appsv1.Deployment
+	err := decoder.DecodeEachFileWithValues(context.TODO(), filepath.Join(dir, "deployment.yaml"), filepath.Join(dir, "values.yaml"), defaults, func(ctx context.Context, obj k8s.Object) error {
+		deployments = append(deployments, obj.(*appsv1.Deployment))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments) != 1 {
+		t.Fatalf("expected 1 deployment, got %d", len(deployments))
+	}
+	if deployments[0].Name != "values-test" {
+		t.Errorf("expected the name of the values file, got %q", deployments[0].Name)
+	}
+	if image := deployments[0].Spec.Template.Spec.Containers[0].Image; image != "nginx:1.27" {
+		t.Errorf("expected the image to be rendered from the values and the defaults, got %q", image)
+	}
+
+	// values missing from both the values file and the defaults are reported
+	err = decoder.DecodeEachFileWithValues(context.TODO(), filepath.Join(dir, "deployment.yaml"), filepath.Join(dir, "values.yaml"), nil, decoder.NoopHandler(nil))
+	if err == nil || !strings.Contains(err.Error(), "replicas") {
+		t.Errorf("expected an error naming the missing value, got: %v", err)
+	}
+}
+
+func TestDecodeEachFileWithContinueOnError(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+	continueNS := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "continue-on-error-test"}}
+	if err := res.Create(context.TODO(), continueNS); err != nil {
+		t.Fatalf("error while creating namespace %q: %s", continueNS.Name, err)
+	}
+	defer func() { _ = res.Delete(context.TODO(), continueNS) }()
+
+	fsys := fstest.MapFS{}
+	for _, name := range []string{"first", "second", "Invalid_Name", "fourth"} {
+		fsys[name+".yaml"] = &fstest.MapFile{Data: []byte(fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\n", name))}
+	}
+	err = decoder.DecodeEachFile(context.TODO(), fsys, "*.yaml", decoder.CreateHandler(res),
+		decoder.WithContinueOnError(), decoder.MutateNamespace(continueNS.Name))
+
+	var objErrs decoder.ObjectErrors
+	if !errors.As(err, &objErrs) {
+		t.Fatalf("expected ObjectErrors, got: %v", err)
+	}
+	if len(objErrs) != 1 || objErrs[0].Name != "Invalid_Name" || objErrs[0].Namespace != continueNS.Name || objErrs[0].GroupVersionKind.Kind != "ConfigMap" {
+		t.Fatalf("expected the error to name the invalid configmap only, got: %v", err)
+	}
+	if !apierrors.IsInvalid(objErrs[0]) {
+		t.Errorf("expected the error of the API server to be preserved, got: %v", objErrs[0].Err)
+	}
+	for _, name := range []string{"first", "second", "fourth"} {
+		var cm v1.ConfigMap
+		if err := res.Get(context.TODO(), name, continueNS.Name, &cm); err != nil {
+			t.Errorf("expected configmap %s to be created despite the failure, got: %v", name, err)
+		}
+	}
+}
+
+func TestDecodeWithSkipAnnotation(t *testing.T) {
+	manifest := fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: kept
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: skipped
+  annotations:
+    %s: "true"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-skipped
+  annotations:
+    %s: "false"
+`, decoder.SkipAnnotation, decoder.SkipAnnotation)
+
+	var handled []string
+	err := decoder.DecodeEach(context.TODO(), strings.NewReader(manifest), func(ctx context.Context, obj k8s.Object) error {
+		handled = append(handled, obj.GetName())
+		return nil
+	}, decoder.WithSkipAnnotation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"kept", "not-skipped"}; !reflect.DeepEqual(handled, expected) {
+		t.Errorf("expected the handler to be called for %v, got %v", expected, handled)
+	}
+
+	// the annotation is ignored without the option
+	objects, err := decoder.DecodeAll(context.TODO(), strings.NewReader(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 3 {
+		t.Errorf("expected 3 objects without WithSkipAnnotation, got %d", len(objects))
+	}
+}
+
+func TestDecodeWithRunLabel(t *testing.T) {
+	runLabel, runID := decoder.WithRunLabel()
+	if runID == "" {
+		t.Fatal("expected a run ID")
+	}
+	objects, err := decoder.DecodeAllFiles(context.TODO(), os.DirFS(filepath.Join("testdata", "examples")), "*", runLabel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) == 0 {
+		t.Fatal("expected objects to be decoded")
+	}
+	for _, obj := range objects {
+		if value := obj.GetLabels()[decoder.RunLabel]; value != runID {
+			t.Errorf("expected %s %q to carry run label %q, got %q", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), runID, value)
+		}
+	}
+
+	if _, otherID := decoder.WithRunLabel(); otherID == runID {
+		t.Errorf("expected a distinct run ID for each call, got %q twice", runID)
+	}
+}
+
+func TestDecodeWithSopsDecrypt(t *testing.T) {
+	if _, err := exec.LookPath("sops"); err != nil {
+		// CI installs sops with make install-sops, so that the test is never skipped there
+		if os.Getenv("CI") != "" {
+			t.Fatalf("sops binary not found in PATH: %v", err)
+		}
+		t.Skip("sops binary not found in PATH")
+	}
+	// the age key the fixture is encrypted with, committed for testing purposes only
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join("testdata", "sops", "age.key"))
+
+	plain := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: plain\ndata:\n  password: plain\n"
+	encrypted, err := os.ReadFile(filepath.Join("testdata", "sops", "configmap.enc.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := plain + "---\n" + string(encrypted)
+
+	objects, err := decoder.DecodeAll(context.TODO(), strings.NewReader(manifest), decoder.WithSopsDecrypt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objects))
+	}
+	expected := map[string]string{"plain": "plain", "sops-encrypted": "s3cr3t"}
+	for _, obj := range objects {
+		cm, ok := obj.(*v1.ConfigMap)
+		if !ok {
+			t.Fatalf("expected a ConfigMap, got %T", obj)
+		}
+		if password := cm.Data["password"]; password != expected[cm.Name] {
+			t.Errorf("expected the password of %s to be %q, got %q", cm.Name, expected[cm.Name], password)
+		}
+	}
+}
+
+func TestDecodeEachFileSyntaxError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"malformed.yaml": &fstest.MapFile{Data: []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: valid
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: malformed
+   labels:
+    app: malformed
+`)},
+	}
+
+	err := decoder.DecodeEachFile(context.TODO(), fsys, "*.yaml", func(ctx context.Context, obj k8s.Object) error {
+		return nil
+	})
+	var syntaxErr *decoder.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected a SyntaxError, got: %v", err)
+	}
+	// the badly indented labels key is on the 10th line of the file
+	if syntaxErr.Line != 10 {
+		t.Errorf("expected the error to be located at line 10, got %d: %v", syntaxErr.Line, err)
+	}
+	if !strings.Contains(err.Error(), "malformed.yaml") {
+		t.Errorf("expected the error to name the file, got: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	goyaml "sigs.k8s.io/yaml/goyaml.v2"
+)
+
+// SyntaxError reports a document of a stream that is not valid YAML, at the line of the stream the
+// YAML parser locates the error at. The files decoded by DecodeEachFile are named by the error
+// wrapping it. The parser does not report the column of syntax errors.
+type SyntaxError struct {
+	Line int
+	Err  error
+}
+
+func (e *SyntaxError) Error() string {
+	msg := e.Err.Error()
+	if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		msg = m[2]
+	}
+	msg = strings.TrimPrefix(msg, "yaml: ")
+	return fmt.Sprintf("yaml syntax error at line %d: %s", e.Line, msg)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// yamlErrorLine matches the errors of the YAML parser located at a line of the parsed document.
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// checkSyntax parses document, whose first line is the line firstLine of its stream, and returns a
+// *SyntaxError locating the error at a line of the stream when the document is not valid YAML.
+func checkSyntax(document []byte, firstLine int) error {
+	var content interface{}
+	err := goyaml.Unmarshal(document, &content)
+	if err == nil {
+		return nil
+	}
+	line := firstLine
+	if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		line += n - 1
+	}
+	return &SyntaxError{Line: line, Err: err}
+}
+
+// ObjectError is the error returned by a handler for a single decoded object.
+type ObjectError struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	Err              error
+}
+
+func (e *ObjectError) Error() string {
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %s: %v", e.GroupVersionKind.Kind, name, e.Err)
+}
+
+func (e *ObjectError) Unwrap() error {
+	return e.Err
+}
+
+// ObjectErrors is returned when decoding with WithContinueOnError and the handler failed for some
+// of the objects, listing each of the failures in the order the objects were decoded.
+type ObjectErrors []*ObjectError
+
+func (e ObjectErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("handler failed for %d object(s): %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e ObjectErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// WithContinueOnError keeps decoding the documents of a stream, the files resolved by DecodeEachFile and
+// the entries of an archive decoded by DecodeEachTar, after the handler fails for an object, such as for a best-effort setup creating what it can of a directory
+// of manifests. Once all objects are handled, the failures are returned as ObjectErrors, naming the kind,
+// namespace and name of each failed object. Errors decoding or mutating a document still halt decoding.
+func WithContinueOnError() DecodeOption {
+	return func(do *Options) {
+		do.ContinueOnError = true
+	}
+}
+
+// collectObjectErrors appends the ObjectErrors of err to errs when decoding continues on error, and
+// reports whether err was collected rather than having to be returned.
+func (o *Options) collectObjectErrors(err error, errs *ObjectErrors) bool {
+	var objErrs ObjectErrors
+	if !o.ContinueOnError || !errors.As(err, &objErrs) {
+		return false
+	}
+	*errs = append(*errs, objErrs...)
+	return true
+}
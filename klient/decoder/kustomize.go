@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// DecodeKustomize renders the kustomization found in the directory dir, the same way `kustomize build` would,
+// and decodes each of the resulting documents using DecodeEach.
+//
+// If the directory does not contain a valid kustomization, an error is returned before any object is handled.
+// If handlerFn returns an error, decoding is halted.
+// Options may be provided to configure the behavior of the decoder.
+func DecodeKustomize(ctx context.Context, dir string, handlerFn HandlerFunc, options ...DecodeOption) error {
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resMap, err := kustomizer.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return fmt.Errorf("failed to build kustomization %q: %w", dir, err)
+	}
+	manifest, err := resMap.AsYaml()
+	if err != nil {
+		return fmt.Errorf("failed to render kustomization %q: %w", dir, err)
+	}
+	if err := DecodeEach(ctx, bytes.NewReader(manifest), handlerFn, options...); err != nil {
+		return fmt.Errorf("failed to decode kustomization %q: %w", dir, err)
+	}
+	return nil
+}
+
+// DecodeAllKustomize renders the kustomization found in the directory dir and returns all of the decoded objects.
+// Falls back to the unstructured.Unstructured type if a matching type cannot be found for the Kind.
+// Options may be provided to configure the behavior of the decoder.
+func DecodeAllKustomize(ctx context.Context, dir string, options ...DecodeOption) ([]k8s.Object, error) {
+	objects := []k8s.Object{}
+	err := DecodeKustomize(ctx, dir, func(ctx context.Context, obj k8s.Object) error {
+		objects = append(objects, obj)
+		return nil
+	}, options...)
+	return objects, err
+}
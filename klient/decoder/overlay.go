@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// DecodeWithOverlays decodes the single object of base, YAML or JSON, and merges each overlay onto it in order,
+// as a lightweight alternative to kustomize for parameterizing a base manifest with small per-case overlays, such
+// as one setting the replicas of a Deployment. Overlays are partial objects, which need not repeat the apiVersion
+// and kind of base.
+//
+// Overlays are applied as strategic merge patches to the kinds registered in the default scheme, so that lists such
+// as the containers of a pod or their env are merged by name, and as JSON merge patches to other kinds, replacing
+// lists as a whole. A null value removes a field in both cases.
+func DecodeWithOverlays(base io.Reader, overlays ...io.Reader) (k8s.Object, error) {
+	document, err := readJSON(base)
+	if err != nil {
+		return nil, fmt.Errorf("decode base: %w", err)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(document); err != nil {
+		return nil, fmt.Errorf("decode base: %w", err)
+	}
+	// the typed object of the kind, if registered, provides the patch strategy of its fields
+	typed, err := scheme.Scheme.New(obj.GroupVersionKind())
+	if err != nil && !runtime.IsNotRegisteredError(err) {
+		return nil, fmt.Errorf("decode base: %w", err)
+	}
+
+	for i, overlay := range overlays {
+		patch, err := readJSON(overlay)
+		if err != nil {
+			return nil, fmt.Errorf("decode overlay %d: %w", i+1, err)
+		}
+		if typed != nil {
+			document, err = strategicpatch.StrategicMergePatch(document, patch, typed)
+		} else {
+			document, err = jsonpatch.MergePatch(document, patch)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("apply overlay %d: %w", i+1, err)
+		}
+	}
+	return DecodeAny(bytes.NewReader(document))
+}
+
+// readJSON reads the YAML or JSON document of r as JSON.
+func readJSON(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.ToJSON(b)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"context"
+	"io/fs"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// ProgressFunc is called once per object created by ApplyWithManifestDir, with the number of objects
+// created so far, the number of objects decoded from the manifests and the created object.
+type ProgressFunc func(done, total int, obj k8s.Object)
+
+// WithProgress reports the progress of ApplyWithManifestDir to progressFn, such as to render a progress
+// bar or to log the throughput of the creation of large directories. The objects of the manifests are
+// counted before they are created, which decodes the manifests twice.
+//
+// progressFn is called from a separate goroutine, in the order the objects are created, so that a slow
+// callback does not hold back the creation of the objects. ApplyWithManifestDir returns once progressFn
+// is done with every created object.
+func WithProgress(progressFn ProgressFunc) DecodeOption {
+	return func(do *Options) {
+		do.Progress = progressFn
+	}
+}
+
+// countObjects returns the number of objects decoded from the files of fsys matching pattern.
+func countObjects(ctx context.Context, fsys fs.FS, pattern string, options ...DecodeOption) (int, error) {
+	total := 0
+	err := DecodeEachFile(ctx, fsys, pattern, func(ctx context.Context, obj k8s.Object) error {
+		total++
+		return nil
+	}, options...)
+	return total, err
+}
+
+// progressReporter hands the objects created by a handler to a ProgressFunc run in its own goroutine.
+type progressReporter struct {
+	progressFn ProgressFunc
+	total      int
+	reported   int
+	objects    chan k8s.Object
+	stopped    chan struct{}
+}
+
+// newProgressReporter starts reporting the progress of the creation of total objects to progressFn.
+func newProgressReporter(progressFn ProgressFunc, total int) *progressReporter {
+	p := &progressReporter{
+		progressFn: progressFn,
+		total:      total,
+		// the channel holds every object, so that the handler never waits for progressFn
+		objects: make(chan k8s.Object, total),
+		stopped: make(chan struct{}),
+	}
+	go func() {
+		defer close(p.stopped)
+		done := 0
+		for obj := range p.objects {
+			done++
+			p.progressFn(done, p.total, obj)
+		}
+	}()
+	return p
+}
+
+// handler returns a HandlerFunc reporting the objects successfully handled by handlerFn.
+func (p *progressReporter) handler(handlerFn HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, obj k8s.Object) error {
+		if err := handlerFn(ctx, obj); err != nil {
+			return err
+		}
+		// the manifests may have changed since they were counted
+		if p.reported < p.total {
+			p.reported++
+			p.objects <- obj
+		}
+		return nil
+	}
+}
+
+// stop waits for progressFn to be done with the objects reported.
+func (p *progressReporter) stop() {
+	close(p.objects)
+	<-p.stopped
+}
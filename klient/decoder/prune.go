@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+const (
+	// AppliedByLabel is the label identifying the set an object was applied with by
+	// ApplyWithManifestDirAndPrune.
+	AppliedByLabel = "e2e-framework.sigs.k8s.io/applied-by"
+
+	// applyFieldManager is the field manager of the objects applied by ApplyWithManifestDirAndPrune.
+	applyFieldManager = "e2e-framework"
+)
+
+// ApplyWithManifestDirAndPrune applies the objects decoded from the files of dirPath matching
+// pattern, and deletes the objects previously applied with the same applySet that are no longer
+// part of the manifests, mirroring kubectl apply --prune. This keeps the cluster in sync with a
+// directory that is edited and re-applied across the iterations of a test.
+//
+// The objects are applied with server-side apply, owned by the e2e-framework field manager unless
+// WithPreservedManagedFields is used, and labeled with AppliedByLabel set to applySet to track
+// them. Every kind served by the API server is searched for objects to prune, so the applySet
+// should be unique to the test run. Namespaced objects must have their namespace set, for
+// instance with MutateNamespace.
+func ApplyWithManifestDirAndPrune(ctx context.Context, r *resources.Resources, dirPath, pattern, applySet string, options ...DecodeOption) error {
+	decodeOpt := &Options{}
+	for _, opt := range options {
+		opt(decodeOpt)
+	}
+	options = append(options, MutateLabels(map[string]string{AppliedByLabel: applySet}))
+	applied := make(map[string]bool)
+	err := DecodeEachFile(ctx, os.DirFS(dirPath), pattern, func(ctx context.Context, obj k8s.Object) error {
+		gvk, err := applyObject(ctx, r, obj, decodeOpt.PreserveManagedFields)
+		if err != nil {
+			return err
+		}
+		applied[pruneKey(gvk.GroupKind(), obj)] = true
+		return nil
+	}, options...)
+	if err != nil {
+		return err
+	}
+	return prune(ctx, r, applySet, applied)
+}
+
+// WithPreservedManagedFields keeps the metadata.managedFields declared by the documents on the objects
+// applied by ApplyWithManifestDirAndPrune, instead of recording the applying field manager as the owner
+// of every applied field, so that tests of server-side apply conflicts can start from a given ownership
+// state. As server-side apply rejects objects carrying managed fields, the declared managed fields are
+// written with a merge patch once the object is applied. Documents without managed fields are applied
+// as usual.
+func WithPreservedManagedFields() DecodeOption {
+	return func(do *Options) {
+		do.PreserveManagedFields = true
+	}
+}
+
+// applyObject applies obj with server-side apply and returns its GroupVersionKind. When preserveManagedFields
+// is set, the managed fields of obj replace the ones recorded by the apply.
+func applyObject(ctx context.Context, r *resources.Resources, obj k8s.Object, preserveManagedFields bool) (schema.GroupVersionKind, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.GetScheme())
+	if err != nil {
+		return gvk, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return gvk, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	// server-side apply rejects objects carrying managed fields
+	u.SetManagedFields(nil)
+	data, err := json.Marshal(u)
+	if err != nil {
+		return gvk, err
+	}
+	patch := k8s.Patch{PatchType: types.ApplyPatchType, Data: data}
+	if err := r.Patch(ctx, u, patch, func(po *metav1.PatchOptions) {
+		force := true
+		po.Force = &force
+		po.FieldManager = applyFieldManager
+	}); err != nil {
+		return gvk, fmt.Errorf("failed to apply %s %q: %w", gvk.Kind, obj.GetName(), err)
+	}
+
+	managedFields := obj.GetManagedFields()
+	if !preserveManagedFields || len(managedFields) == 0 {
+		return gvk, nil
+	}
+	data, err = json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"managedFields": managedFields}})
+	if err != nil {
+		return gvk, err
+	}
+	if err := r.Patch(ctx, u, k8s.Patch{PatchType: types.MergePatchType, Data: data}); err != nil {
+		return gvk, fmt.Errorf("failed to restore the managed fields of %s %q: %w", gvk.Kind, obj.GetName(), err)
+	}
+	return gvk, nil
+}
+
+// prune deletes the objects labeled with applySet whose key is not found in applied.
+func prune(ctx context.Context, r *resources.Resources, applySet string, applied map[string]bool) error {
+	dc, err := discovery.NewDiscoveryClientForConfig(r.GetConfig())
+	if err != nil {
+		return err
+	}
+	resourceLists, err := dc.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return fmt.Errorf("failed to discover resources to prune: %w", err)
+	}
+	selector := resources.WithLabelSelector(AppliedByLabel + "=" + applySet)
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			return err
+		}
+		for _, resource := range resourceList.APIResources {
+			if strings.Contains(resource.Name, "/") || !hasVerbs(resource.Verbs, "list", "delete") {
+				continue
+			}
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(gv.WithKind(resource.Kind + "List"))
+			if err := r.List(ctx, list, selector); err != nil {
+				return fmt.Errorf("failed to list %s to prune: %w", resource.Name, err)
+			}
+			for i := range list.Items {
+				obj := &list.Items[i]
+				if applied[pruneKey(gv.WithKind(resource.Kind).GroupKind(), obj)] {
+					continue
+				}
+				if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+					return fmt.Errorf("failed to prune %s %q: %w", resource.Kind, obj.GetName(), err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pruneKey identifies obj, of the given kind, among the applied objects.
+func pruneKey(gk schema.GroupKind, obj k8s.Object) string {
+	return gk.String() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// hasVerbs reports whether all of the wanted verbs are found in verbs.
+func hasVerbs(verbs metav1.Verbs, wanted ...string) bool {
+	for _, verb := range wanted {
+		found := false
+		for _, v := range verbs {
+			if v == verb {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
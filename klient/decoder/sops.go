@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+	klog "k8s.io/klog/v2"
+)
+
+// sopsCommand is the sops binary used by WithSopsDecrypt to decrypt documents.
+const sopsCommand = "sops"
+
+// WithSopsDecrypt decrypts the documents encrypted with sops, that is the ones carrying a
+// top-level sops stanza, before they are decoded, so that tests can use the secrets committed
+// encrypted at rest as fixtures. Other documents are decoded as is. The decrypted document is the
+// one handed to DocumentHandlerFunc as Raw.
+//
+// Documents are decrypted by the sops binary, which must be found in the PATH, so that the
+// sources of keys it supports, such as age and PGP keys or cloud KMS, are configured as usual,
+// for instance with the SOPS_AGE_KEY_FILE environment variable. The binary is used rather than
+// the sops Go library on purpose: the library would pull the SDKs of every cloud KMS into the
+// module graph of the framework, and raise its minimum Go version, for all of its users, build
+// tags having no effect on go.mod. `make install-sops` installs the binary.
+func WithSopsDecrypt() DecodeOption {
+	return func(do *Options) {
+		do.SopsDecrypt = true
+	}
+}
+
+// isSopsEncrypted reports whether document carries a top-level sops stanza.
+func isSopsEncrypted(document []byte) (bool, error) {
+	var stanza struct {
+		Sops map[string]interface{} `json:"sops"`
+	}
+	if err := yaml.Unmarshal(document, &stanza); err != nil {
+		return false, err
+	}
+	return stanza.Sops != nil, nil
+}
+
+// sopsDecrypt returns document decrypted by sops, as YAML.
+func sopsDecrypt(ctx context.Context, document []byte) ([]byte, error) {
+	inputType := "yaml"
+	if bytes.HasPrefix(bytes.TrimSpace(document), []byte("{")) {
+		inputType = "json"
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, sopsCommand, "--decrypt", "--input-type", inputType, "--output-type", "yaml", "/dev/stdin")
+	cmd.Stdin = bytes.NewReader(document)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	klog.V(4).InfoS("Decrypting document with sops", "command", cmd.String())
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sops decryption failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// gzipMagic is the header every gzip stream starts with.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// DecodeEachTar decodes the manifests contained in a tar archive, such as the ones shipped as CI artifacts.
+// The archive may be gzip compressed, which is detected from its content. Regular files with a .yaml, .yml
+// or .json extension are decoded in the order they appear in the archive and handed to handlerFn, while
+// directories and other files are skipped. Multi-document files are supported, and the Include and Exclude
+// filters apply to the base name of the entries. With WithContinueOnError, the failures of handlerFn are
+// collected as with DecodeEachFile.
+func DecodeEachTar(ctx context.Context, archive io.Reader, handlerFn HandlerFunc, options ...DecodeOption) error {
+	decodeOpt := &Options{}
+	for _, opt := range options {
+		opt(decodeOpt)
+	}
+	br := bufio.NewReader(archive)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("failed to read gzip archive: %w", err)
+		}
+		defer gz.Close()
+		archive = gz
+	} else {
+		archive = br
+	}
+	tr := tar.NewReader(archive)
+	var objErrs ObjectErrors
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !isManifestFile(header.Name) {
+			continue
+		}
+		if ok, err := decodeOpt.matchFile(header.Name); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if err := DecodeEach(ctx, tr, handlerFn, options...); err != nil {
+			if decodeOpt.collectObjectErrors(err, &objErrs) {
+				continue
+			}
+			return fmt.Errorf("failed to decode tar entry %q: %w", header.Name, err)
+		}
+	}
+	if len(objErrs) > 0 {
+		return objErrs
+	}
+	return nil
+}
+
+// DecodeAllTar decodes the manifests contained in a tar archive as described by DecodeEachTar.
+func DecodeAllTar(ctx context.Context, archive io.Reader, options ...DecodeOption) ([]k8s.Object, error) {
+	objects := []k8s.Object{}
+	err := DecodeEachTar(ctx, archive, func(ctx context.Context, obj k8s.Object) error {
+		objects = append(objects, obj)
+		return nil
+	}, options...)
+	return objects, err
+}
+
+// isManifestFile reports whether name has an extension of a YAML or JSON file.
+func isManifestFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
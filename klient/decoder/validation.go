@@ -0,0 +1,263 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/openapi"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// WithSchemaValidation validates each document decoded from a stream against the OpenAPI v3 schema the
+// API server behind r publishes for its kind, before the document is handed to the handler. Unknown or
+// misspelled fields, such as `replias`, as well as values not matching the schema are reported as an
+// error naming the field.
+//
+// The schemas are fetched once per group version through discovery, and the documents are validated by
+// the client, so that validation needs no permission beyond discovery, and does not depend on the
+// namespace of the objects existing or on admission. Fields set to null are ignored, as the API server
+// treats them as unset. The documents of kinds the API server does not serve yet, such as the custom
+// resources of a CRD created by the same manifests, are not validated. The MutateFuncs are applied to
+// the document before it is validated.
+func WithSchemaValidation(r *resources.Resources) DecodeOption {
+	return func(do *Options) {
+		do.SchemaValidation = r
+	}
+}
+
+const (
+	// schemaRefPrefix prefixes the references to the schemas of an OpenAPI v3 document.
+	schemaRefPrefix = "#/components/schemas/"
+	// gvkExtension lists the kinds a schema of an OpenAPI document is the schema of.
+	gvkExtension = "x-kubernetes-group-version-kind"
+	// preserveUnknownFieldsExtension marks the schemas of objects accepting any field.
+	preserveUnknownFieldsExtension = "x-kubernetes-preserve-unknown-fields"
+)
+
+// schemaValidator validates documents against the OpenAPI v3 schemas published by the API server,
+// caching the schemas of each group version.
+type schemaValidator struct {
+	client openapi.Client
+	paths  map[string]openapi.GroupVersion
+	// validators holds the validator of each kind, nil for the kinds without a schema
+	validators map[schema.GroupVersionKind]*validate.SchemaValidator
+	// components holds the schemas of each group version fetched so far
+	components map[schema.GroupVersion]map[string]*spec.Schema
+}
+
+func newSchemaValidator(r *resources.Resources) (*schemaValidator, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(r.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	return newSchemaValidatorForClient(client.OpenAPIV3()), nil
+}
+
+func newSchemaValidatorForClient(client openapi.Client) *schemaValidator {
+	return &schemaValidator{
+		client:     client,
+		validators: make(map[schema.GroupVersionKind]*validate.SchemaValidator),
+		components: make(map[schema.GroupVersion]map[string]*spec.Schema),
+	}
+}
+
+// validate validates the raw document against the schema of its kind. The document is decoded as an
+// unstructured.Unstructured to retain fields that decoding into a typed object would drop.
+func (v *schemaValidator) validate(document []byte, mutateFuncs []MutateFunc) error {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(document, &obj.Object); err != nil {
+		return err
+	}
+	for _, patch := range mutateFuncs {
+		if err := patch(obj); err != nil {
+			return err
+		}
+	}
+	gvk := obj.GroupVersionKind()
+	validator, err := v.validator(gvk)
+	if err != nil {
+		return fmt.Errorf("schema validation of %s %q failed: %w", gvk.Kind, obj.GetName(), err)
+	}
+	if validator == nil {
+		klog.V(2).InfoS("Skipping schema validation of a kind without schema", "kind", gvk.String(), "name", obj.GetName())
+		return nil
+	}
+	if result := validator.Validate(withoutNulls(obj.Object)); !result.IsValid() {
+		return fmt.Errorf("schema validation of %s %q failed: %w", gvk.Kind, obj.GetName(), result.AsError())
+	}
+	return nil
+}
+
+// validator returns the validator of the kind gvk, or nil if the API server publishes no schema for it.
+func (v *schemaValidator) validator(gvk schema.GroupVersionKind) (*validate.SchemaValidator, error) {
+	if validator, ok := v.validators[gvk]; ok {
+		return validator, nil
+	}
+	components, err := v.groupVersionComponents(gvk.GroupVersion())
+	if err != nil {
+		return nil, err
+	}
+	var validator *validate.SchemaValidator
+	for _, s := range components {
+		if hasGroupVersionKind(s, gvk) {
+			expanded := expandSchema(*s, components, map[string]bool{})
+			validator = validate.NewSchemaValidator(&expanded, nil, "", strfmt.Default)
+			break
+		}
+	}
+	v.validators[gvk] = validator
+	return validator, nil
+}
+
+// groupVersionComponents returns the schemas of the OpenAPI document of gv, or nil if the API server
+// does not serve gv.
+func (v *schemaValidator) groupVersionComponents(gv schema.GroupVersion) (map[string]*spec.Schema, error) {
+	if components, ok := v.components[gv]; ok {
+		return components, nil
+	}
+	if v.paths == nil {
+		paths, err := v.client.Paths()
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover the OpenAPI documents: %w", err)
+		}
+		v.paths = paths
+	}
+	path := "apis/" + gv.String()
+	if gv.Group == "" {
+		path = "api/" + gv.Version
+	}
+	var components map[string]*spec.Schema
+	if groupVersion, ok := v.paths[path]; ok {
+		data, err := groupVersion.Schema(runtime.ContentTypeJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the OpenAPI document of %s: %w", gv, err)
+		}
+		doc := &spec3.OpenAPI{}
+		if err := json.Unmarshal(data, doc); err != nil {
+			return nil, fmt.Errorf("failed to parse the OpenAPI document of %s: %w", gv, err)
+		}
+		if doc.Components != nil {
+			components = doc.Components.Schemas
+		}
+	}
+	v.components[gv] = components
+	return components, nil
+}
+
+// hasGroupVersionKind reports whether s is the schema of the kind gvk.
+func hasGroupVersionKind(s *spec.Schema, gvk schema.GroupVersionKind) bool {
+	kinds, _ := s.Extensions[gvkExtension].([]interface{})
+	for _, kind := range kinds {
+		k, _ := kind.(map[string]interface{})
+		if k["group"] == gvk.Group && k["version"] == gvk.Version && k["kind"] == gvk.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// expandSchema returns s with its references to the schemas of components inlined, as the validator
+// does not resolve references, and with the fields missing from the properties of objects forbidden,
+// so that misspelled fields are reported. Recursive references, which the schemas of CRDs hold,
+// accept any value.
+func expandSchema(s spec.Schema, components map[string]*spec.Schema, resolving map[string]bool) spec.Schema {
+	if ref := s.Ref.String(); ref != "" {
+		name := strings.TrimPrefix(ref, schemaRefPrefix)
+		target, ok := components[name]
+		if !ok || resolving[name] {
+			return spec.Schema{}
+		}
+		resolving[name] = true
+		defer delete(resolving, name)
+		return expandSchema(*target, components, resolving)
+	}
+	if s.Format == "int-or-string" {
+		// the validator checks the format against the type of the value, while the oneOf of the schema
+		// accepts both integers and strings
+		s.Format = ""
+	}
+	expandAll := func(schemas []spec.Schema) []spec.Schema {
+		if schemas == nil {
+			return nil
+		}
+		expanded := make([]spec.Schema, len(schemas))
+		for i := range schemas {
+			expanded[i] = expandSchema(schemas[i], components, resolving)
+		}
+		return expanded
+	}
+	if s.Properties != nil {
+		properties := make(map[string]spec.Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			properties[name] = expandSchema(property, components, resolving)
+		}
+		s.Properties = properties
+		if preserve, _ := s.Extensions[preserveUnknownFieldsExtension].(bool); s.AdditionalProperties == nil && !preserve {
+			s.AdditionalProperties = &spec.SchemaOrBool{Allows: false}
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		expanded := expandSchema(*s.AdditionalProperties.Schema, components, resolving)
+		s.AdditionalProperties = &spec.SchemaOrBool{Allows: true, Schema: &expanded}
+	}
+	if s.Items != nil {
+		items := &spec.SchemaOrArray{Schemas: expandAll(s.Items.Schemas)}
+		if s.Items.Schema != nil {
+			expanded := expandSchema(*s.Items.Schema, components, resolving)
+			items.Schema = &expanded
+		}
+		s.Items = items
+	}
+	s.AllOf = expandAll(s.AllOf)
+	s.AnyOf = expandAll(s.AnyOf)
+	s.OneOf = expandAll(s.OneOf)
+	return s
+}
+
+// withoutNulls returns a copy of value without the fields set to null.
+func withoutNulls(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		fields := make(map[string]interface{}, len(value))
+		for name, field := range value {
+			if field != nil {
+				fields[name] = withoutNulls(field)
+			}
+		}
+		return fields
+	case []interface{}:
+		items := make([]interface{}, len(value))
+		for i, item := range value {
+			items[i] = withoutNulls(item)
+		}
+		return items
+	}
+	return value
+}
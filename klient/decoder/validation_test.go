@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/openapi/openapitest"
+)
+
+func TestSchemaValidatorValidate(t *testing.T) {
+	deployment := func(replicasField string) string {
+		return fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: schema-validation
+  creationTimestamp: null
+spec:
+  %s: 1
+  selector:
+    matchLabels:
+      app: schema-validation
+  strategy:
+    rollingUpdate:
+      maxUnavailable: 1
+  template:
+    metadata:
+      labels:
+        app: schema-validation
+    spec:
+      containers:
+        - name: nginx
+          image: nginx
+          resources:
+            limits:
+              cpu: 1
+`, replicasField)
+	}
+	tests := []struct {
+		name     string
+		document string
+		errField string
+	}{
+		{name: "valid", document: deployment("replicas")},
+		{name: "misspelled field", document: deployment("replias"), errField: "spec.replias"},
+		{name: "mistyped value", document: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: mistyped\ndata:\n  count: 1\n", errField: "data.count"},
+		{name: "kind without schema", document: "apiVersion: example.e2e-framework.io/v1\nkind: Widget\nmetadata:\n  name: widget\nspec:\n  unknown: true\n"},
+	}
+	validator := newSchemaValidatorForClient(openapitest.NewEmbeddedFileClient())
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.validate([]byte(test.document), nil)
+			switch {
+			case test.errField == "" && err != nil:
+				t.Errorf("unexpected schema validation error: %v", err)
+			case test.errField != "" && (err == nil || !strings.Contains(err.Error(), test.errField)):
+				t.Errorf("expected a schema validation error naming %s, got: %v", test.errField, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// DecodeEachFileWithValues renders the manifests matching the glob pattern path as text/template templates, using the
+// values of the YAML file at valuesPath as their data, before decoding their documents as DecodeEachFile does. This
+// mimics a minimal Helm values flow: nested values are accessed with `{{ .image.tag }}`, and the values of the file
+// take precedence over defaults, which may be nil, with maps merged key by key.
+//
+// Referencing a value that is neither in the values file nor in defaults is an error, so that typos in templates do
+// not go unnoticed. The valuesPath may be empty to render the manifests with defaults only.
+func DecodeEachFileWithValues(ctx context.Context, path, valuesPath string, defaults map[string]interface{}, handlerFn HandlerFunc, options ...DecodeOption) error {
+	values, err := loadValues(valuesPath, defaults)
+	if err != nil {
+		return err
+	}
+	decodeOpt := &Options{}
+	for _, opt := range options {
+		opt(decodeOpt)
+	}
+	files, err := filepath.Glob(path)
+	if err != nil {
+		return err
+	}
+	var objErrs ObjectErrors
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if ok, err := decodeOpt.matchFile(file); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		rendered, err := renderFile(file, values)
+		if err != nil {
+			return err
+		}
+		if err := DecodeEach(ctx, bytes.NewReader(rendered), handlerFn, options...); err != nil {
+			if decodeOpt.collectObjectErrors(err, &objErrs) {
+				continue
+			}
+			return fmt.Errorf("failed to decode file %q: %w", file, err)
+		}
+	}
+	if len(objErrs) > 0 {
+		return objErrs
+	}
+	return nil
+}
+
+// loadValues reads the values file at valuesPath, if any, merged over defaults.
+func loadValues(valuesPath string, defaults map[string]interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if valuesPath != "" {
+		b, err := os.ReadFile(valuesPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(b, &values); err != nil {
+			return nil, fmt.Errorf("failed to decode values file %q: %w", valuesPath, err)
+		}
+		if values == nil {
+			// an empty values file
+			values = map[string]interface{}{}
+		}
+	}
+	return mergeValues(defaults, values), nil
+}
+
+// mergeValues returns the values of overrides merged over the ones of base. Nested maps are merged key by key,
+// while other values of overrides replace the ones of base.
+func mergeValues(base, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		baseMap, baseIsMap := merged[key].(map[string]interface{})
+		overrideMap, overrideIsMap := value.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			merged[key] = mergeValues(baseMap, overrideMap)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// renderFile renders the template of file with values.
+func renderFile(file string, values map[string]interface{}) ([]byte, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(file)).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %q: %w", file, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("failed to render template %q: %w", file, err)
+	}
+	return buf.Bytes(), nil
+}
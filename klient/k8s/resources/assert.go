@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// AssertMatches fetches the live object of the kind, name and namespace of expected, and returns
+// an error holding a diff of the two objects if the live object does not match expected, or nil.
+//
+// Only the fields set in expected are compared, so that the fields defaulted by the API server
+// do not cause mismatches, and the fields volatile across runs, such as the uid, the
+// resourceVersion or the creationTimestamp, are ignored, as EncodeYAML strips them. ignoreFields
+// are the paths of further fields to ignore, with their keys separated by dots, as in
+// "metadata.labels" or "spec.replicas".
+func (r *Resources) AssertMatches(ctx context.Context, expected k8s.Object, ignoreFields ...string) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(expected.DeepCopyObject())
+	if err != nil {
+		return fmt.Errorf("assert matches %s/%s: %w", expected.GetNamespace(), expected.GetName(), err)
+	}
+	want := &unstructured.Unstructured{Object: content}
+	if want.GetKind() == "" {
+		gvk, err := apiutil.GVKForObject(expected, scheme.Scheme)
+		if err != nil {
+			return fmt.Errorf("assert matches %s/%s: %w", expected.GetNamespace(), expected.GetName(), err)
+		}
+		want.SetGroupVersionKind(gvk)
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(want.GroupVersionKind())
+	if err := r.Get(ctx, expected.GetName(), expected.GetNamespace(), live); err != nil {
+		return err
+	}
+
+	removeVolatileFields(want.Object)
+	for _, field := range ignoreFields {
+		unstructured.RemoveNestedField(want.Object, strings.Split(field, ".")...)
+	}
+	got := restrictToFields(live.Object, want.Object).(map[string]interface{})
+	if equality.Semantic.DeepEqual(want.Object, got) {
+		return nil
+	}
+	return fmt.Errorf("%s %s/%s does not match the expected object (-expected +live):\n%s",
+		want.GetKind(), expected.GetNamespace(), expected.GetName(), cmp.Diff(want.Object, got))
+}
+
+// restrictToFields returns a copy of the unstructured value live holding only the fields set in
+// expected. The elements of lists of the same length are restricted one by one, while other
+// values are returned as is.
+func restrictToFields(live, expected interface{}) interface{} {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		restricted := make(map[string]interface{}, len(expected))
+		for key, value := range expected {
+			if liveValue, found := liveMap[key]; found {
+				restricted[key] = restrictToFields(liveValue, value)
+			}
+		}
+		return restricted
+	case []interface{}:
+		liveList, ok := live.([]interface{})
+		if !ok || len(liveList) != len(expected) {
+			return live
+		}
+		restricted := make([]interface{}, len(expected))
+		for i := range expected {
+			restricted[i] = restrictToFields(liveList[i], expected[i])
+		}
+		return restricted
+	default:
+		return live
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/exec"
+)
+
+// connectivityProbeTimeoutSeconds bounds the connection attempt made by VerifyConnectivity.
+const connectivityProbeTimeoutSeconds = 2
+
+// VerifyConnectivity probes the first port of the service toService from the first container of
+// the pod fromPod, and returns an error unless the outcome is the expected one: a connection when
+// shouldConnect is true, no connection otherwise, such as when a NetworkPolicy denies the traffic.
+//
+// The probe opens a TCP connection to the cluster IP of the service with `nc -z`, which the image
+// of the container must provide, as busybox does. As NetworkPolicies take effect asynchronously,
+// callers asserting the outcome right after applying a policy should retry, e.g. with wait.For.
+func (r *Resources) VerifyConnectivity(ctx context.Context, fromPod *v1.Pod, toService *v1.Service, shouldConnect bool) error {
+	if len(fromPod.Spec.Containers) == 0 {
+		return fmt.Errorf("verify connectivity: pod %s/%s has no container", fromPod.Namespace, fromPod.Name)
+	}
+	if len(toService.Spec.Ports) == 0 {
+		return fmt.Errorf("verify connectivity: service %s/%s exposes no port", toService.Namespace, toService.Name)
+	}
+	clusterIP := toService.Spec.ClusterIP
+	if clusterIP == "" || clusterIP == v1.ClusterIPNone {
+		return fmt.Errorf("verify connectivity: service %s/%s has no cluster IP", toService.Namespace, toService.Name)
+	}
+	port := strconv.Itoa(int(toService.Spec.Ports[0].Port))
+
+	var stderr bytes.Buffer
+	command := []string{"nc", "-z", "-w", strconv.Itoa(connectivityProbeTimeoutSeconds), clusterIP, port}
+	err := r.streamInPod(ctx, fromPod.Namespace, fromPod.Name, fromPod.Spec.Containers[0].Name, command, nil, io.Discard, &stderr)
+	var exitErr exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr) && exitErr.ExitStatus() < 126:
+		// the probe ran and could not connect, 126 and above report that it could not run
+	default:
+		return fmt.Errorf("verify connectivity: probe from pod %s/%s failed: %w: %s", fromPod.Namespace, fromPod.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	connected := err == nil
+	if connected != shouldConnect {
+		outcome := "connected to"
+		if !connected {
+			outcome = "could not connect to"
+		}
+		return fmt.Errorf("verify connectivity: pod %s/%s %s service %s/%s on port %s, expected shouldConnect=%t",
+			fromPod.Namespace, fromPod.Name, outcome, toService.Namespace, toService.Name, port, shouldConnect)
+	}
+	return nil
+}
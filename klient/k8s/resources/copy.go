@@ -0,0 +1,237 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// CopyToPod copies the file or directory at localPath to remotePath in the container of the pod,
+// as `kubectl cp` does. The content is streamed as a tar archive to the tar command of the
+// container, which must be available in its image. Directories are copied recursively and the
+// permission bits of the copied files are preserved, subject to the umask of the container
+// user. The parent directory of remotePath must exist.
+func (r *Resources) CopyToPod(ctx context.Context, namespace, pod, container, localPath, remotePath string) error {
+	if _, err := os.Stat(localPath); err != nil {
+		return fmt.Errorf("copy to pod %s/%s: %w", namespace, pod, err)
+	}
+	remotePath = path.Clean(remotePath)
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeTar(writer, localPath, path.Base(remotePath)))
+	}()
+	defer reader.Close()
+
+	var stderr bytes.Buffer
+	command := []string{"tar", "-xmf", "-", "-C", path.Dir(remotePath)}
+	if err := r.streamInPod(ctx, namespace, pod, container, command, reader, io.Discard, &stderr); err != nil {
+		return fmt.Errorf("copy to pod %s/%s: %w: %s", namespace, pod, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// CopyFromPod copies the file or directory at remotePath in the container of the pod to
+// localPath, as `kubectl cp` does. The content is streamed as a tar archive produced by the
+// tar command of the container, which must be available in its image. Directories are copied
+// recursively and the permission bits of the copied files are preserved. The parent directory
+// of localPath must exist.
+func (r *Resources) CopyFromPod(ctx context.Context, namespace, pod, container, remotePath, localPath string) error {
+	remotePath = path.Clean(remotePath)
+
+	reader, writer := io.Pipe()
+	var stderr bytes.Buffer
+	streamErr := make(chan error, 1)
+	go func() {
+		command := []string{"tar", "-cf", "-", "-C", path.Dir(remotePath), path.Base(remotePath)}
+		err := r.streamInPod(ctx, namespace, pod, container, command, nil, writer, &stderr)
+		writer.CloseWithError(err)
+		streamErr <- err
+	}()
+
+	err := readTar(reader, path.Base(remotePath), localPath)
+	if err == nil {
+		// consume the padding following the end of the archive
+		_, err = io.Copy(io.Discard, reader)
+	}
+	reader.Close()
+	if sErr := <-streamErr; err == nil {
+		err = sErr
+	}
+	if err != nil {
+		return fmt.Errorf("copy from pod %s/%s: %w: %s", namespace, pod, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// writeTar writes the file or directory at localPath to w as a tar archive, naming its root
+// entry name.
+func writeTar(w io.Writer, localPath, name string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(localPath, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localPath, file)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(name, filepath.ToSlash(rel))
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readTar extracts the tar archive read from r to localPath, mapping its root entry name to
+// localPath. Entries outside of name are rejected, as are the symlinks pointing outside of
+// localPath and the entries written through a symlink, which could otherwise escape localPath.
+func readTar(r io.Reader, name, localPath string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := path.Clean(header.Name)
+		rel, ok := strings.CutPrefix(entry, name)
+		if !ok || (rel != "" && !strings.HasPrefix(rel, "/")) {
+			return fmt.Errorf("unexpected entry %q in archive", header.Name)
+		}
+		target := filepath.Join(localPath, filepath.FromSlash(rel))
+		mode := fs.FileMode(header.Mode).Perm()
+		// an entry written through a symlink of the archive could land outside of localPath
+		if err := checkNoSymlink(localPath, rel); err != nil {
+			return fmt.Errorf("unexpected entry %q in archive: %w", header.Name, err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if !linkWithin(localPath, target, header.Linkname) {
+				return fmt.Errorf("symlink %q in archive points outside of %s: %s", header.Name, localPath, header.Linkname)
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkNoSymlink returns an error if the path rel, relative to localPath, or any of its parent
+// directories below localPath is a symlink.
+func checkNoSymlink(localPath, rel string) error {
+	current := localPath
+	for _, element := range strings.Split(strings.Trim(rel, "/"), "/") {
+		if element == "" {
+			continue
+		}
+		current = filepath.Join(current, element)
+		info, err := os.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", current)
+		}
+	}
+	return nil
+}
+
+// linkWithin reports whether the symlink at target pointing at linkname resolves within localPath.
+func linkWithin(localPath, target, linkname string) bool {
+	if filepath.IsAbs(linkname) {
+		return false
+	}
+	resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))
+	rel, err := filepath.Rel(localPath, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// writeFile writes the content read from r to the file at target with the given permissions.
+func writeFile(target string, r io.Reader, mode fs.FileMode) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// the permissions of an existing file are not changed by OpenFile, nor are they exempt from the umask
+	return os.Chmod(target, mode)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadTarHostileArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []*tar.Header
+	}{
+		{
+			name: "symlink to an absolute path",
+			entries: []*tar.Header{
+				{Name: "fixtures", Typeflag: tar.TypeDir, Mode: 0o755},
+				{Name: "fixtures/dir", Typeflag: tar.TypeSymlink, Linkname: "OUTSIDE"},
+				{Name: "fixtures/dir/x", Typeflag: tar.TypeReg, Mode: 0o644},
+			},
+		},
+		{
+			name: "symlink to a parent directory",
+			entries: []*tar.Header{
+				{Name: "fixtures", Typeflag: tar.TypeDir, Mode: 0o755},
+				{Name: "fixtures/dir", Typeflag: tar.TypeSymlink, Linkname: "../outside"},
+				{Name: "fixtures/dir/x", Typeflag: tar.TypeReg, Mode: 0o644},
+			},
+		},
+		{
+			name: "entry written through a symlink",
+			entries: []*tar.Header{
+				{Name: "fixtures", Typeflag: tar.TypeDir, Mode: 0o755},
+				{Name: "fixtures/sub", Typeflag: tar.TypeDir, Mode: 0o755},
+				{Name: "fixtures/dir", Typeflag: tar.TypeSymlink, Linkname: "sub"},
+				{Name: "fixtures/dir/x", Typeflag: tar.TypeReg, Mode: 0o644},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			outside := filepath.Join(root, "outside")
+			if err := os.Mkdir(outside, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			localPath := filepath.Join(root, "local")
+
+			var archive bytes.Buffer
+			tw := tar.NewWriter(&archive)
+			for _, header := range tc.entries {
+				if header.Linkname == "OUTSIDE" {
+					header.Linkname = outside
+				}
+				if header.Typeflag == tar.TypeReg {
+					header.Size = int64(len("escaped"))
+				}
+				if err := tw.WriteHeader(header); err != nil {
+					t.Fatal(err)
+				}
+				if header.Typeflag == tar.TypeReg {
+					if _, err := tw.Write([]byte("escaped")); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := readTar(&archive, "fixtures", localPath); err == nil {
+				t.Error("expected the hostile archive to be rejected")
+			}
+			if _, err := os.Stat(filepath.Join(outside, "x")); !os.IsNotExist(err) {
+				t.Errorf("expected no file to be written outside of the local path, got %v", err)
+			}
+		})
+	}
+}
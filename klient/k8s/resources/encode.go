@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	serializerjson "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// serverManagedFields are the metadata fields set by the API server that EncodeYAML strips.
+var serverManagedFields = []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "selfLink"}
+
+// EncodeYAML serializes objs, typed or unstructured, into a multi-document YAML stream, such as
+// to compare the objects produced by a chain of decoder.MutateFunc against a golden file. The
+// apiVersion and kind of typed objects are resolved from the default scheme when not set.
+//
+// The metadata fields managed by the API server, such as the uid, the resourceVersion or the
+// managedFields, are stripped, along with an empty status and the null creationTimestamp of
+// pod templates, so that the output of objects read from a cluster is stable. objs are left
+// unchanged.
+func EncodeYAML(objs ...k8s.Object) ([]byte, error) {
+	serializer := serializerjson.NewSerializerWithOptions(serializerjson.DefaultMetaFactory, scheme.Scheme, scheme.Scheme, serializerjson.SerializerOptions{Yaml: true})
+	var buf bytes.Buffer
+	for i, obj := range objs {
+		// convert a copy, as the content of unstructured objects is returned as is
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj.DeepCopyObject())
+		if err != nil {
+			return nil, fmt.Errorf("encode %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+		u := &unstructured.Unstructured{Object: content}
+		if u.GetKind() == "" {
+			gvk, err := apiutil.GVKForObject(obj, scheme.Scheme)
+			if err != nil {
+				return nil, fmt.Errorf("encode %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+			}
+			u.SetGroupVersionKind(gvk)
+		}
+		removeVolatileFields(u.Object)
+
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		if err := serializer.Encode(u, &buf); err != nil {
+			return nil, fmt.Errorf("encode %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// GetAsJSON retrieves the object of kind gvk with the given name and namespace as normalized JSON,
+// as NormalizeJSON returns it, such as to compare the object against a golden file committed with
+// the tests. The namespace is ignored for cluster scoped kinds.
+func (r *Resources) GetAsJSON(ctx context.Context, name, namespace string, gvk schema.GroupVersionKind) ([]byte, error) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := r.Get(ctx, name, namespace, u); err != nil {
+		return nil, err
+	}
+	data, err := u.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeJSON(data)
+}
+
+// NormalizeJSON returns the JSON object data in a stable form fit for snapshot testing: the fields
+// volatile across runs are removed, as EncodeYAML does, and the keys are sorted and indented.
+func NormalizeJSON(data []byte) ([]byte, error) {
+	var content map[string]interface{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+	removeVolatileFields(content)
+	out, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// removeVolatileFields removes the metadata fields managed by the API server, an empty status and
+// the null creationTimestamp of nested metadata from the unstructured content of an object.
+func removeVolatileFields(content map[string]interface{}) {
+	for _, field := range serverManagedFields {
+		unstructured.RemoveNestedField(content, "metadata", field)
+	}
+	if status, ok := content["status"].(map[string]interface{}); ok && len(status) == 0 {
+		delete(content, "status")
+	}
+	removeNullCreationTimestamps(content)
+}
+
+// removeNullCreationTimestamps removes the null creationTimestamp of the nested metadata of
+// content, such as the one of pod templates, which typed objects always hold.
+func removeNullCreationTimestamps(content map[string]interface{}) {
+	for key, value := range content {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if key == "metadata" {
+				if ts, ok := v["creationTimestamp"]; ok && ts == nil {
+					delete(v, "creationTimestamp")
+				}
+			}
+			removeNullCreationTimestamps(v)
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					removeNullCreationTimestamps(m)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// NoMatchingKindError is returned when the cluster serves no resource for the kind of an object,
+// most often because the CustomResourceDefinition declaring the kind is not installed, such as
+// with envfuncs.SetupCRDs. The underlying RESTMapping error is available with errors.Unwrap, so
+// meta.IsNoMatchError keeps reporting such errors.
+type NoMatchingKindError struct {
+	GroupVersionKind schema.GroupVersionKind
+	Err              error
+}
+
+func (e *NoMatchingKindError) Error() string {
+	return fmt.Sprintf("no resource of kind %q is served for %s, is the CustomResourceDefinition declaring it installed?", e.GroupVersionKind.Kind, e.GroupVersionKind.GroupVersion())
+}
+
+func (e *NoMatchingKindError) Unwrap() error {
+	return e.Err
+}
+
+// noMatchingKindError wraps err in a NoMatchingKindError naming the kind of obj when err is a
+// RESTMapping error reporting that the kind is not served. Other errors are returned as is.
+func (r *Resources) noMatchingKindError(obj k8s.Object, err error) error {
+	if !meta.IsNoMatchError(err) {
+		return err
+	}
+	gvk, gvkErr := apiutil.GVKForObject(obj, r.scheme)
+	if gvkErr != nil {
+		return err
+	}
+	return &NoMatchingKindError{GroupVersionKind: gvk, Err: err}
+}
@@ -20,25 +20,46 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/version"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/reference"
 	"k8s.io/client-go/tools/remotecommand"
 	klog "k8s.io/klog/v2"
 	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/watcher"
 )
 
+// eventSourceComponent is the source component of the events recorded with RecordEvent.
+const eventSourceComponent = "e2e-framework"
+
 type Resources struct {
 	// config is the rest.Config to talk to an apiserver
 	config *rest.Config
@@ -51,6 +72,41 @@ type Resources struct {
 
 	// namespace for namespaced object requests
 	namespace string
+
+	// fieldValidation caches whether the API server supports server-side field validation
+	fieldValidation *fieldValidationSupport
+
+	// mappings tracks the REST mapping lookups of the kinds requested through the client
+	mappings *mappingLookups
+
+	// default options applied to each request before the options of the call
+	defaultGetOptions    []GetOption
+	defaultListOptions   []ListOption
+	defaultCreateOptions []CreateOption
+	defaultUpdateOptions []UpdateOption
+	defaultPatchOptions  []PatchOption
+	defaultDeleteOptions []DeleteOption
+}
+
+// minFieldValidationVersion is the first Kubernetes version with server-side
+// field validation enabled by default.
+var minFieldValidationVersion = version.MajorMinor(1, 25)
+
+type fieldValidationSupport struct {
+	once      sync.Once
+	supported bool
+}
+
+// mappingLookups holds the REST mapping lookup of each kind, shared by the requests for it.
+type mappingLookups struct {
+	mu      sync.Mutex
+	lookups map[schema.GroupVersionKind]*mappingLookup
+}
+
+// mappingLookup is a REST mapping lookup, whose err is set once done is closed.
+type mappingLookup struct {
+	done chan struct{}
+	err  error
 }
 
 // New instantiates the controller runtime client
@@ -68,9 +124,11 @@ func New(cfg *rest.Config) (*Resources, error) {
 	}
 
 	res := &Resources{
-		config: cfg,
-		scheme: scheme.Scheme,
-		client: cl,
+		config:          cfg,
+		scheme:          scheme.Scheme,
+		client:          cl,
+		fieldValidation: &fieldValidationSupport{},
+		mappings:        &mappingLookups{lookups: make(map[schema.GroupVersionKind]*mappingLookup)},
 	}
 
 	return res, nil
@@ -86,46 +144,378 @@ func (r *Resources) WithNamespace(ns string) *Resources {
 	return r
 }
 
-func (r *Resources) Get(ctx context.Context, name, namespace string, obj k8s.Object) error {
-	return r.client.Get(ctx, cr.ObjectKey{Namespace: namespace, Name: name}, obj)
+// WithDefaultGetOptions returns a copy of r applying opts to every Get request. The options passed
+// to a call are applied after them, so they take precedence. As for the other WithDefault options,
+// r is left unchanged, so that the defaults do not leak to the other users of a shared instance,
+// such as the one returned by klient.Client.Resources.
+func (r *Resources) WithDefaultGetOptions(opts ...GetOption) *Resources {
+	res := *r
+	res.defaultGetOptions = opts
+	return &res
+}
+
+// WithDefaultListOptions returns a copy of r applying opts to every List and Watch request, such
+// as a label selector restricting the objects of a suite. The options passed to a call are applied
+// after them, so they take precedence.
+func (r *Resources) WithDefaultListOptions(opts ...ListOption) *Resources {
+	res := *r
+	res.defaultListOptions = opts
+	return &res
+}
+
+// WithDefaultCreateOptions returns a copy of r applying opts to every Create request, such as a
+// field manager. The options passed to a call are applied after them, so they take precedence.
+func (r *Resources) WithDefaultCreateOptions(opts ...CreateOption) *Resources {
+	res := *r
+	res.defaultCreateOptions = opts
+	return &res
+}
+
+// WithDefaultUpdateOptions returns a copy of r applying opts to every Update request. The options
+// passed to a call are applied after them, so they take precedence.
+func (r *Resources) WithDefaultUpdateOptions(opts ...UpdateOption) *Resources {
+	res := *r
+	res.defaultUpdateOptions = opts
+	return &res
+}
+
+// WithDefaultPatchOptions returns a copy of r applying opts to every Patch request, such as the
+// field manager of server-side apply patches. The options passed to a call are applied after
+// them, so they take precedence.
+func (r *Resources) WithDefaultPatchOptions(opts ...PatchOption) *Resources {
+	res := *r
+	res.defaultPatchOptions = opts
+	return &res
+}
+
+// WithDefaultDeleteOptions returns a copy of r applying opts to every Delete request. The options
+// passed to a call are applied after them, so they take precedence.
+func (r *Resources) WithDefaultDeleteOptions(opts ...DeleteOption) *Resources {
+	res := *r
+	res.defaultDeleteOptions = opts
+	return &res
+}
+
+// GetOption is used to provide additional arguments to the Get call.
+type GetOption func(*metav1.GetOptions)
+
+// Get retrieves the object with the given name and namespace into obj. The underlying client is
+// not backed by an informer cache: each call is served by the API server and decoded into obj,
+// so mutating obj locally never affects the objects returned by subsequent calls.
+//
+// Unless WithResourceVersion is used, the API server serves the most recent version of the
+// object, read from etcd, so an object is visible as soon as the request that created or
+// updated it returned.
+func (r *Resources) Get(ctx context.Context, name, namespace string, obj k8s.Object, opts ...GetOption) error {
+	getOptions := &metav1.GetOptions{}
+	for _, fn := range r.defaultGetOptions {
+		fn(getOptions)
+	}
+	for _, fn := range opts {
+		fn(getOptions)
+	}
+
+	if err := r.resolveMapping(ctx, obj); err != nil {
+		return err
+	}
+	return r.client.Get(ctx, cr.ObjectKey{Namespace: namespace, Name: name}, obj, &cr.GetOptions{Raw: getOptions})
+}
+
+// WithResourceVersion sets the resourceVersion the Get request is served at, following the
+// Kubernetes consistency semantics: "0" allows the API server to serve any version of the
+// object from its watch cache, which is cheaper but may be stale, while a specific version
+// requires a version at least as recent. Use WithExactReads for a consistent read.
+func WithResourceVersion(rv string) GetOption {
+	return func(o *metav1.GetOptions) { o.ResourceVersion = rv }
+}
+
+// WithExactReads requests a consistent read of the object, served from etcd rather than from
+// the watch cache of the API server, so the result reflects every write that completed before
+// the request. This is the default for Get; the option overrides an earlier WithResourceVersion.
+func WithExactReads() GetOption {
+	return func(o *metav1.GetOptions) { o.ResourceVersion = "" }
+}
+
+// resolveMapping resolves the REST mapping of obj before a request is sent for it. The REST
+// mapper of the controller runtime client lazily discovers the resources of a group version
+// without a context, so a call blocked on an unresponsive API server would ignore the
+// cancellation of ctx. The mapping of each kind is looked up once, in a separate goroutine that
+// the requests for the kind wait for until their ctx is done; once resolved, the mapping is cached
+// by the mapper and the requests themselves honor ctx. Failed lookups are retried by the next
+// request for the kind.
+func (r *Resources) resolveMapping(ctx context.Context, obj k8s.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		// let the client report the error
+		return nil
+	}
+
+	lookup := r.mappings.lookup(gvk, func() error {
+		_, err := r.client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		return err
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-lookup.done:
+		if lookup.err != nil && !meta.IsNoMatchError(lookup.err) {
+			return lookup.err
+		}
+		// no match errors are reported by the client along with the request
+		return nil
+	}
+}
+
+// lookup returns the lookup of the mapping of gvk, starting it with resolve unless it succeeded
+// or is in progress.
+func (m *mappingLookups) lookup(gvk schema.GroupVersionKind, resolve func() error) *mappingLookup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lookup, ok := m.lookups[gvk]; ok {
+		return lookup
+	}
+	lookup := &mappingLookup{done: make(chan struct{})}
+	m.lookups[gvk] = lookup
+	go func() {
+		lookup.err = resolve()
+		if lookup.err != nil {
+			m.mu.Lock()
+			delete(m.lookups, gvk)
+			m.mu.Unlock()
+		}
+		close(lookup.done)
+	}()
+	return lookup
+}
+
+// Exists reports whether the object of kind gvk with the given name and namespace exists. Only the
+// metadata of the object is retrieved, which is cheaper than Get for large objects when the
+// presence of the object is all that matters. NotFound errors are reported as false.
+func (r *Resources) Exists(ctx context.Context, gvk schema.GroupVersionKind, name, namespace string) (bool, error) {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(gvk)
+	if err := r.Get(ctx, name, namespace, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ErrNoControllerOwner is returned by GetOwner when the object has no owner reference
+// marked as its controller.
+var ErrNoControllerOwner = errors.New("object has no controller owner")
+
+// GetOwner retrieves into owner the object referenced by the controller owner reference of
+// obj, such as the ReplicaSet of a Pod created by a Deployment. The error wraps
+// ErrNoControllerOwner when obj has no controller owner. The kind of owner must match the one
+// of the reference; the apiVersion and kind of an empty unstructured owner are set from it.
+func (r *Resources) GetOwner(ctx context.Context, obj k8s.Object, owner k8s.Object) error {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil {
+		return fmt.Errorf("%s/%s: %w", obj.GetNamespace(), obj.GetName(), ErrNoControllerOwner)
+	}
+	refGVK := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	if u, ok := owner.(*unstructured.Unstructured); ok && u.GetKind() == "" {
+		u.SetGroupVersionKind(refGVK)
+	}
+	gvk, err := r.client.GroupVersionKindFor(owner)
+	if err != nil {
+		return err
+	}
+	if gvk.GroupKind() != refGVK.GroupKind() {
+		return fmt.Errorf("controller owner of %s/%s is a %s, not a %s", obj.GetNamespace(), obj.GetName(), refGVK.GroupKind(), gvk.GroupKind())
+	}
+	if err := r.Get(ctx, ref.Name, obj.GetNamespace(), owner); err != nil {
+		return err
+	}
+	if owner.GetUID() != ref.UID {
+		return fmt.Errorf("controller owner %s of %s/%s has been replaced: expected UID %s, got %s", ref.Name, obj.GetNamespace(), obj.GetName(), ref.UID, owner.GetUID())
+	}
+	return nil
 }
 
 type CreateOption func(*metav1.CreateOptions)
 
-func (r *Resources) Create(ctx context.Context, obj k8s.Object, opts ...CreateOption) error {
+// ClientCreateOptions are the options of CreateWithOptions applied by the client rather than sent
+// to the API server.
+type ClientCreateOptions struct {
+	// EnsureNamespace, when set, creates the namespace of the object first. See WithEnsureNamespace.
+	EnsureNamespace bool
+	// NameCollisionRetries is the number of retries of a creation failing on a name already taken.
+	// See WithNameCollisionRetry.
+	NameCollisionRetries int
+}
+
+type ClientCreateOption func(*ClientCreateOptions)
+
+// createOptions returns the options built from the default create options of r and opts.
+func (r *Resources) createOptions(opts []CreateOption) *metav1.CreateOptions {
 	createOptions := &metav1.CreateOptions{}
+	for _, fn := range r.defaultCreateOptions {
+		fn(createOptions)
+	}
 	for _, fn := range opts {
 		fn(createOptions)
 	}
+	return createOptions
+}
+
+// Create creates the object obj in the cluster. Upon success, obj is updated in place with
+// the object returned by the API server, so server assigned values such as the name generated
+// from metadata.generateName, the UID or the resourceVersion can be read from obj directly.
+// A *NoMatchingKindError is returned when the cluster does not serve the kind of obj.
+func (r *Resources) Create(ctx context.Context, obj k8s.Object, opts ...CreateOption) error {
+	return r.CreateWithOptions(ctx, obj, opts)
+}
+
+// CreateWithOptions creates the object obj in the cluster as Create does with the create options
+// createOpts, along with the options applied by the client, such as WithEnsureNamespace.
+func (r *Resources) CreateWithOptions(ctx context.Context, obj k8s.Object, createOpts []CreateOption, opts ...ClientCreateOption) error {
+	createOptions := r.createOptions(createOpts)
+	clientOptions := &ClientCreateOptions{}
+	for _, fn := range opts {
+		fn(clientOptions)
+	}
 
 	o := &cr.CreateOptions{
-		Raw:          createOptions,
-		DryRun:       createOptions.DryRun,
-		FieldManager: createOptions.FieldManager,
+		Raw:             createOptions,
+		DryRun:          createOptions.DryRun,
+		FieldManager:    createOptions.FieldManager,
+		FieldValidation: r.fieldValidationDirective(ctx, createOptions.FieldValidation),
+	}
+
+	if clientOptions.EnsureNamespace && obj.GetNamespace() != "" {
+		if err := r.ensureNamespace(ctx, obj.GetNamespace()); err != nil {
+			return err
+		}
+	}
+
+	err := r.client.Create(ctx, obj, o)
+	name := obj.GetName()
+	for i := 0; i < clientOptions.NameCollisionRetries && apierrors.IsAlreadyExists(err) && name != ""; i++ {
+		obj.SetName(collisionFreeName(name))
+		klog.FromContext(ctx).V(2).Info("Name already taken, retrying with a random suffix", "namespace", obj.GetNamespace(), "name", name, "retry", obj.GetName())
+		err = r.client.Create(ctx, obj, o)
+	}
+	return r.noMatchingKindError(obj, err)
+}
+
+// WithNameCollisionRetry retries the creation of an object whose name is already taken, such as
+// by the features tested in parallel creating objects of the same fixed name, up to n times. Each
+// retry appends a random suffix to the name, as metadata.generateName does, and the name the object
+// was created with is set on the object. The AlreadyExists error is returned once the retries are
+// exhausted.
+func WithNameCollisionRetry(n int) ClientCreateOption {
+	return func(co *ClientCreateOptions) { co.NameCollisionRetries = n }
+}
+
+// collisionFreeName returns name followed by a random suffix, truncated to the length of the
+// names generated by the API server from metadata.generateName.
+func collisionFreeName(name string) string {
+	const maxBaseLength, suffixLength = 58, 5
+	base := name + "-"
+	if len(base) > maxBaseLength {
+		base = base[:maxBaseLength]
+	}
+	return base + utilrand.String(suffixLength)
+}
+
+// WithEnsureNamespace creates the namespace of the object before creating the object, unless
+// the namespace already exists. It has no effect on cluster scoped objects.
+func WithEnsureNamespace() ClientCreateOption {
+	return func(co *ClientCreateOptions) { co.EnsureNamespace = true }
+}
+
+// ensureNamespace creates the namespace with the given name if it does not exist.
+func (r *Resources) ensureNamespace(ctx context.Context, name string) error {
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	// look the namespace up first, so that creating objects does not require the permission
+	// to create namespaces when they already exist
+	err := r.client.Get(ctx, cr.ObjectKeyFromObject(ns), ns)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("ensure namespace %s: %w", name, err)
+	}
+	if err := r.client.Create(ctx, ns); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("ensure namespace %s: %w", name, err)
 	}
+	return nil
+}
 
-	return r.client.Create(ctx, obj, o)
+// WithFieldValidation sets the server-side field validation directive of the create request.
+// With metav1.FieldValidationStrict, the API server rejects objects holding unknown or
+// duplicate fields, such as a misspelled field in a manifest, instead of silently dropping them.
+// The directive is not sent to API servers older than Kubernetes 1.25, which do not enable
+// server-side field validation by default.
+func WithFieldValidation(validation string) CreateOption {
+	return func(co *metav1.CreateOptions) { co.FieldValidation = validation }
 }
 
 type UpdateOption func(*metav1.UpdateOptions)
 
 func (r *Resources) Update(ctx context.Context, obj k8s.Object, opts ...UpdateOption) error {
 	updateOptions := &metav1.UpdateOptions{}
+	for _, fn := range r.defaultUpdateOptions {
+		fn(updateOptions)
+	}
 	for _, fn := range opts {
 		fn(updateOptions)
 	}
 
 	o := &cr.UpdateOptions{
-		Raw:          updateOptions,
-		DryRun:       updateOptions.DryRun,
-		FieldManager: updateOptions.FieldManager,
+		Raw:             updateOptions,
+		DryRun:          updateOptions.DryRun,
+		FieldManager:    updateOptions.FieldManager,
+		FieldValidation: r.fieldValidationDirective(ctx, updateOptions.FieldValidation),
 	}
 	return r.client.Update(ctx, obj, o)
 }
 
+// WithUpdateFieldValidation sets the server-side field validation directive of the update
+// request, as WithFieldValidation does for create requests.
+func WithUpdateFieldValidation(validation string) UpdateOption {
+	return func(uo *metav1.UpdateOptions) { uo.FieldValidation = validation }
+}
+
+// fieldValidationDirective returns the field validation directive to send to the API server.
+// The directive is dropped, with a warning, when the server does not support it.
+func (r *Resources) fieldValidationDirective(ctx context.Context, validation string) string {
+	if validation == "" || r.fieldValidation == nil {
+		return validation
+	}
+	support := r.fieldValidation
+	support.once.Do(func() {
+		// assume support when the version cannot be determined, as servers ignore unknown directives
+		support.supported = true
+		dc, err := discovery.NewDiscoveryClientForConfig(r.config)
+		if err != nil {
+			return
+		}
+		info, err := dc.ServerVersion()
+		if err != nil {
+			return
+		}
+		if v, err := version.ParseGeneric(info.GitVersion); err == nil {
+			support.supported = v.AtLeast(minFieldValidationVersion)
+		}
+	})
+	if !support.supported {
+		klog.FromContext(ctx).Info("API server does not support field validation, ignoring directive", "fieldValidation", validation)
+		return ""
+	}
+	return validation
+}
+
 // UpdateSubresource updates the subresource of the object
 func (r *Resources) UpdateSubresource(ctx context.Context, obj k8s.Object, subresource string, opts ...UpdateOption) error {
 	updateOptions := &metav1.UpdateOptions{}
+	for _, fn := range r.defaultUpdateOptions {
+		fn(updateOptions)
+	}
 	for _, fn := range opts {
 		fn(updateOptions)
 	}
@@ -140,10 +530,334 @@ func (r *Resources) UpdateStatus(ctx context.Context, obj k8s.Object, opts ...Up
 	return r.UpdateSubresource(ctx, obj, "status", opts...)
 }
 
+// BatchMode selects how CreateAll and DeleteAll handle the failure of an individual object.
+type BatchMode int
+
+const (
+	// BatchStopOnError stops at the first object that fails and returns its error.
+	BatchStopOnError BatchMode = iota
+	// BatchContinueOnError processes every object and returns the errors of the failed ones joined together.
+	BatchContinueOnError
+)
+
+// dependencyOrder lists the kinds other objects commonly depend on, in the order they are created by
+// SortByDependency. Kinds missing from the list are created afterwards.
+var dependencyOrder = []string{
+	"Namespace",
+	"CustomResourceDefinition",
+	"PriorityClass",
+	"StorageClass",
+	"ServiceAccount",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Secret",
+	"ConfigMap",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"Service",
+}
+
+// SortByDependency returns a copy of objs sorted so that the objects other objects commonly depend on,
+// such as Namespaces, CustomResourceDefinitions and ServiceAccounts, come first. The relative order of
+// objects of the same kind is kept. Deleting the sorted objects with DeleteAll removes the dependents
+// first, as DeleteAll processes objects in reverse order.
+func SortByDependency(objs []k8s.Object) []k8s.Object {
+	rank := func(obj k8s.Object) int {
+		kind := objectKind(obj)
+		for i, k := range dependencyOrder {
+			if k == kind {
+				return i
+			}
+		}
+		return len(dependencyOrder)
+	}
+	sorted := make([]k8s.Object, len(objs))
+	copy(sorted, objs)
+	sort.SliceStable(sorted, func(i, j int) bool { return rank(sorted[i]) < rank(sorted[j]) })
+	return sorted
+}
+
+// objectKind returns the kind of obj, resolved from the default scheme for typed objects
+// whose apiVersion and kind are not set.
+func objectKind(obj k8s.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	if gvk, err := apiutil.GVKForObject(obj, scheme.Scheme); err == nil {
+		return gvk.Kind
+	}
+	return ""
+}
+
+// CreateAll creates the objects objs in order, such as the ones returned by decoder.DecodeAllFiles,
+// handling failures as selected by mode. Use SortByDependency to create the objects in dependency order.
+func (r *Resources) CreateAll(ctx context.Context, objs []k8s.Object, mode BatchMode, opts ...CreateOption) error {
+	var errs []error
+	for _, obj := range objs {
+		if err := r.Create(ctx, obj, opts...); err != nil {
+			err = fmt.Errorf("create %s %s/%s: %w", objectKind(obj), obj.GetNamespace(), obj.GetName(), err)
+			if mode == BatchStopOnError {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DeleteAll deletes the objects objs in reverse order, so that the objects passed to CreateAll are deleted
+// after their dependents, handling failures as selected by mode.
+func (r *Resources) DeleteAll(ctx context.Context, objs []k8s.Object, mode BatchMode, opts ...DeleteOption) error {
+	var errs []error
+	for i := len(objs) - 1; i >= 0; i-- {
+		obj := objs[i]
+		if err := r.Delete(ctx, obj, opts...); err != nil {
+			err = fmt.Errorf("delete %s %s/%s: %w", objectKind(obj), obj.GetNamespace(), obj.GetName(), err)
+			if mode == BatchStopOnError {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// UpdateAllWithRollback updates the objects objs in order, such as several related objects whose
+// updates only make sense together. The state of each object is read from the cluster before it
+// is updated; when an update fails, the objects updated so far are restored to that state, in
+// reverse order, and the error of the failed update is returned along with the errors of the
+// restores that failed.
+//
+// The rollback is best-effort, not atomic: other clients observe the intermediate states, and an
+// object changed by another client after its update is not restored, as the restore conflicts.
+func (r *Resources) UpdateAllWithRollback(ctx context.Context, objs []k8s.Object, opts ...UpdateOption) error {
+	snapshots := make([]k8s.Object, 0, len(objs))
+	for _, obj := range objs {
+		// the snapshot is read into an empty object, as decoding merges into the existing content
+		snapshot := emptyObject(obj)
+		err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), snapshot)
+		if err == nil {
+			err = r.Update(ctx, obj, opts...)
+		}
+		if err != nil {
+			err = fmt.Errorf("update %s %s/%s: %w", objectKind(obj), obj.GetNamespace(), obj.GetName(), err)
+			return errors.Join(err, r.rollbackUpdates(ctx, objs[:len(snapshots)], snapshots, opts...))
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return nil
+}
+
+// emptyObject returns a new empty object of the type of obj, with the apiVersion and kind of obj.
+func emptyObject(obj k8s.Object) k8s.Object {
+	empty := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(k8s.Object)
+	empty.GetObjectKind().SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	return empty
+}
+
+// rollbackUpdates restores the updated objects objs to their snapshots, in reverse order.
+func (r *Resources) rollbackUpdates(ctx context.Context, objs, snapshots []k8s.Object, opts ...UpdateOption) error {
+	var errs []error
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		restore := snapshots[i]
+		// the restore must be based on the version the update produced
+		restore.SetResourceVersion(objs[i].GetResourceVersion())
+		if err := r.Update(ctx, restore, opts...); err != nil {
+			errs = append(errs, fmt.Errorf("rollback %s %s/%s: %w", objectKind(restore), restore.GetNamespace(), restore.GetName(), err))
+			continue
+		}
+		klog.FromContext(ctx).V(2).Info("Rolled back update", "kind", objectKind(restore), "namespace", restore.GetNamespace(), "name", restore.GetName())
+	}
+	return errors.Join(errs...)
+}
+
+// OperationResult is the action performed by CreateOrUpdate.
+type OperationResult string
+
+const (
+	// OperationResultNone means the object was not changed.
+	OperationResultNone OperationResult = "unchanged"
+	// OperationResultCreated means the object was created.
+	OperationResultCreated OperationResult = "created"
+	// OperationResultUpdated means the existing object was updated.
+	OperationResultUpdated OperationResult = "updated"
+)
+
+// CreateOrUpdate fetches the object obj from the cluster using its name and namespace, applies mutate
+// to it and then either creates it, if it does not exist yet, or updates it, if mutate changed it.
+// The mutate function is expected to modify obj in place, which holds the current in-cluster state
+// when the object exists; it must not change the name or namespace of obj.
+//
+// The provided options are used for the create call, and their DryRun and FieldManager values are used
+// for the update call.
+func (r *Resources) CreateOrUpdate(ctx context.Context, obj k8s.Object, mutate func() error, opts ...CreateOption) (OperationResult, error) {
+	key := cr.ObjectKeyFromObject(obj)
+	if err := r.client.Get(ctx, key, obj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return OperationResultNone, err
+		}
+		if err := mutateObject(key, obj, mutate); err != nil {
+			return OperationResultNone, err
+		}
+		if err := r.Create(ctx, obj, opts...); err != nil {
+			return OperationResultNone, err
+		}
+		return OperationResultCreated, nil
+	}
+
+	existing := obj.DeepCopyObject()
+	if err := mutateObject(key, obj, mutate); err != nil {
+		return OperationResultNone, err
+	}
+	if equality.Semantic.DeepEqual(existing, obj) {
+		return OperationResultNone, nil
+	}
+
+	createOptions := r.createOptions(opts)
+	if err := r.Update(ctx, obj, func(uo *metav1.UpdateOptions) {
+		uo.DryRun = createOptions.DryRun
+		uo.FieldManager = createOptions.FieldManager
+	}); err != nil {
+		return OperationResultNone, err
+	}
+	return OperationResultUpdated, nil
+}
+
+// CreateOrGet creates the object obj in the cluster or, if an object with the same name and
+// namespace already exists, fetches the existing object into obj and reports existed. Unlike
+// CreateOrUpdate, the existing object is left unchanged, so the caller can inspect it to decide
+// what to do next.
+func (r *Resources) CreateOrGet(ctx context.Context, obj k8s.Object, opts ...CreateOption) (existed bool, err error) {
+	err = r.Create(ctx, obj, opts...)
+	if err == nil {
+		return false, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return false, err
+	}
+	if err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// PreserveFunc copies onto obj the fields of the live object that can not be changed by an
+// update, or that are assigned by the API server, when obj does not set them.
+type PreserveFunc func(live, obj *unstructured.Unstructured) error
+
+// PreserveFuncs holds the PreserveFunc used by UpdatePreservingImmutable for each kind. Funcs
+// can be added or replaced to handle other kinds, before the tests update any object.
+var PreserveFuncs = map[schema.GroupKind]PreserveFunc{
+	{Kind: "Service"}:               preserveServiceFields,
+	{Kind: "PersistentVolumeClaim"}: preservePersistentVolumeClaimFields,
+}
+
+// UpdatePreservingImmutable updates the object obj in the cluster like Update does, after copying
+// the resourceVersion of the live object, and the fields registered in PreserveFuncs for its kind,
+// onto obj. This allows updating an object decoded from a manifest omitting the immutable fields
+// assigned by the API server, such as the clusterIP of a Service.
+func (r *Resources) UpdatePreservingImmutable(ctx context.Context, obj k8s.Object, opts ...UpdateOption) error {
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return err
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	if err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), live); err != nil {
+		return err
+	}
+
+	target, isUnstructured := obj.(*unstructured.Unstructured)
+	if !isUnstructured {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		target = &unstructured.Unstructured{Object: content}
+	}
+	target.SetResourceVersion(live.GetResourceVersion())
+	if preserve, ok := PreserveFuncs[gvk.GroupKind()]; ok {
+		if err := preserve(live, target); err != nil {
+			return fmt.Errorf("failed to preserve immutable fields of %s %q: %w", gvk.Kind, obj.GetName(), err)
+		}
+	}
+	if !isUnstructured {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(target.Object, obj); err != nil {
+			return err
+		}
+	}
+	return r.Update(ctx, obj, opts...)
+}
+
+// preserveNestedField copies the field of live at the given path onto obj, unless obj sets it.
+func preserveNestedField(live, obj map[string]interface{}, fields ...string) error {
+	if _, found, err := unstructured.NestedFieldNoCopy(obj, fields...); found || err != nil {
+		return err
+	}
+	value, found, err := unstructured.NestedFieldCopy(live, fields...)
+	if !found || err != nil {
+		return err
+	}
+	return unstructured.SetNestedField(obj, value, fields...)
+}
+
+// preserveServiceFields preserves the cluster IPs and the node ports allocated to a Service.
+func preserveServiceFields(live, obj *unstructured.Unstructured) error {
+	for _, field := range []string{"clusterIP", "clusterIPs", "healthCheckNodePort"} {
+		if err := preserveNestedField(live.Object, obj.Object, "spec", field); err != nil {
+			return err
+		}
+	}
+	livePorts, _, err := unstructured.NestedSlice(live.Object, "spec", "ports")
+	if err != nil {
+		return err
+	}
+	ports, found, err := unstructured.NestedSlice(obj.Object, "spec", "ports")
+	if !found || err != nil {
+		return err
+	}
+	for _, p := range ports {
+		port, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, lp := range livePorts {
+			livePort, ok := lp.(map[string]interface{})
+			if ok && livePort["name"] == port["name"] && livePort["port"] == port["port"] {
+				if err := preserveNestedField(livePort, port, "nodePort"); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return unstructured.SetNestedSlice(obj.Object, ports, "spec", "ports")
+}
+
+// preservePersistentVolumeClaimFields preserves the volume bound to a PersistentVolumeClaim.
+func preservePersistentVolumeClaimFields(live, obj *unstructured.Unstructured) error {
+	return preserveNestedField(live.Object, obj.Object, "spec", "volumeName")
+}
+
+func mutateObject(key cr.ObjectKey, obj k8s.Object, mutate func() error) error {
+	if err := mutate(); err != nil {
+		return err
+	}
+	if newKey := cr.ObjectKeyFromObject(obj); key != newKey {
+		return fmt.Errorf("mutate function cannot change the object key from %s to %s", key, newKey)
+	}
+	return nil
+}
+
 type DeleteOption func(*metav1.DeleteOptions)
 
 func (r *Resources) Delete(ctx context.Context, obj k8s.Object, opts ...DeleteOption) error {
 	deleteOptions := &metav1.DeleteOptions{}
+	for _, fn := range r.defaultDeleteOptions {
+		fn(deleteOptions)
+	}
 	for _, fn := range opts {
 		fn(deleteOptions)
 	}
@@ -158,21 +872,80 @@ func (r *Resources) Delete(ctx context.Context, obj k8s.Object, opts ...DeleteOp
 	return r.client.Delete(ctx, obj, o)
 }
 
-func WithGracePeriod(gpt time.Duration) DeleteOption {
-	t := gpt.Milliseconds()
-	return func(do *metav1.DeleteOptions) { do.GracePeriodSeconds = &t }
+// WithGracePeriod sets the duration in seconds the object is given to terminate
+// gracefully before it is deleted. A value of zero requests immediate deletion.
+func WithGracePeriod(seconds int64) DeleteOption {
+	return func(do *metav1.DeleteOptions) { do.GracePeriodSeconds = &seconds }
+}
+
+// WithDeletePropagation sets the policy used by the garbage collector to handle the
+// dependents of the deleted object. metav1.DeletePropagationForeground keeps the object
+// around until all of its dependents are deleted, while metav1.DeletePropagationBackground
+// deletes the object immediately and lets the dependents be collected afterwards.
+func WithDeletePropagation(policy metav1.DeletionPropagation) DeleteOption {
+	return func(do *metav1.DeleteOptions) { do.PropagationPolicy = &policy }
+}
+
+// deletionPollInterval is the interval between the checks made by WaitForDeletion.
+const deletionPollInterval = time.Second
+
+// WaitForDeletion polls the API server until the object obj is gone, which includes its
+// finalizers being cleared, or until timeout elapses. On timeout, the returned error lists
+// the finalizers still holding the object when it was last read. obj itself is not modified.
+func (r *Resources) WaitForDeletion(ctx context.Context, obj k8s.Object, timeout time.Duration) error {
+	var finalizers []string
+	err := apimachinerywait.PollUntilContextTimeout(ctx, deletionPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		// each read decodes into an empty object, so that no finalizer of an earlier read or of
+		// obj is mistaken for a remaining one
+		current := emptyObject(obj)
+		if err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), current); err != nil {
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		}
+		finalizers = current.GetFinalizers()
+		return false, nil
+	})
+	if err != nil && apimachinerywait.Interrupted(err) {
+		return fmt.Errorf("%s/%s was not deleted within %s, remaining finalizers: %v: %w", obj.GetNamespace(), obj.GetName(), timeout, finalizers, err)
+	}
+	return err
 }
 
-func WithDeletePropagation(prop string) DeleteOption {
-	p := metav1.DeletionPropagation(prop)
-	return func(do *metav1.DeleteOptions) { do.PropagationPolicy = &p }
+// countPollInterval is the interval between the lists made by WaitForCount.
+const countPollInterval = time.Second
+
+// WaitForCount polls the API server until the list of the objects matching the provided options
+// holds exactly expected items, such as the pods of a Deployment scaled to 3 replicas, or until
+// timeout elapses. On timeout, the returned error reports the number of items last listed. The
+// items of the last list are left in objs. Objects being deleted, such as terminating pods, are
+// counted until they are gone.
+func (r *Resources) WaitForCount(ctx context.Context, objs k8s.ObjectList, expected int, timeout time.Duration, opts ...ListOption) error {
+	count := -1
+	err := apimachinerywait.PollUntilContextTimeout(ctx, countPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		if err := r.List(ctx, objs, opts...); err != nil {
+			return false, err
+		}
+		count = meta.LenList(objs)
+		return count == expected, nil
+	})
+	if err != nil && apimachinerywait.Interrupted(err) {
+		return fmt.Errorf("expected %d objects within %s, last listed %d: %w", expected, timeout, count, err)
+	}
+	return err
 }
 
 type ListOption func(*metav1.ListOptions)
 
+// List retrieves the objects matching the provided options into objs. As with Get, the items are
+// decoded from the API server response and can be mutated freely.
 func (r *Resources) List(ctx context.Context, objs k8s.ObjectList, opts ...ListOption) error {
 	listOptions := &metav1.ListOptions{}
 
+	for _, fn := range r.defaultListOptions {
+		fn(listOptions)
+	}
 	for _, fn := range opts {
 		fn(listOptions)
 	}
@@ -200,6 +973,154 @@ func (r *Resources) List(ctx context.Context, objs k8s.ObjectList, opts ...ListO
 	return r.client.List(ctx, objs, o)
 }
 
+// ListWithRetry behaves like List, but retries the request with the given backoff when it fails
+// with an error indicating the API server is transiently unavailable, such as during a restart of
+// the control plane: server timeouts, throttling, unavailable or internal errors and refused or
+// reset connections. Other errors are returned immediately. Once the backoff is exhausted, the
+// error of the last attempt is returned.
+func (r *Resources) ListWithRetry(ctx context.Context, objs k8s.ObjectList, backoff apimachinerywait.Backoff, opts ...ListOption) error {
+	var lastErr error
+	err := apimachinerywait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		lastErr = r.List(ctx, objs, opts...)
+		if lastErr == nil {
+			return true, nil
+		}
+		if !isRetryableListError(lastErr) {
+			return false, lastErr
+		}
+		klog.FromContext(ctx).V(2).Info("Retrying list after transient error", "error", lastErr.Error())
+		return false, nil
+	})
+	if apimachinerywait.Interrupted(err) && ctx.Err() == nil && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
+
+// ListOwnedBy retrieves into objs the objects matching the provided options whose owner references
+// include owner, such as the ReplicaSets of a Deployment, to assert that a controller created
+// exactly the expected children. Owner references are matched by the UID of owner, so objects
+// owned by a former object of the same name are left out. The filtering is done client-side.
+func (r *Resources) ListOwnedBy(ctx context.Context, owner k8s.Object, objs k8s.ObjectList, opts ...ListOption) error {
+	if owner.GetUID() == "" {
+		return fmt.Errorf("list owned by %s/%s: owner has no UID", owner.GetNamespace(), owner.GetName())
+	}
+	if err := r.List(ctx, objs, opts...); err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(objs)
+	if err != nil {
+		return err
+	}
+	owned := make([]runtime.Object, 0, len(items))
+	for _, item := range items {
+		accessor, err := meta.Accessor(item)
+		if err != nil {
+			return err
+		}
+		for _, ref := range accessor.GetOwnerReferences() {
+			if ref.UID == owner.GetUID() {
+				owned = append(owned, item)
+				break
+			}
+		}
+	}
+	return meta.SetList(objs, owned)
+}
+
+// ErrNoMatch is returned by GetBy when no object matches the provided options.
+var ErrNoMatch = errors.New("no object matches")
+
+// ErrMultipleMatches is returned by GetBy when more than one object matches the provided options.
+var ErrMultipleMatches = errors.New("multiple objects match")
+
+// GetBy lists into objs the objects matching the provided options, such as a label or field
+// selector, and returns the single one expected to match. This retrieves objects identified by
+// their labels rather than by their name, such as the pod created by a Job. The error wraps
+// ErrNoMatch when no object matches, and ErrMultipleMatches, naming the matching objects, when
+// several do.
+func (r *Resources) GetBy(ctx context.Context, objs k8s.ObjectList, opts ...ListOption) (k8s.Object, error) {
+	if err := r.List(ctx, objs, opts...); err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(objs)
+	if err != nil {
+		return nil, err
+	}
+	switch len(items) {
+	case 0:
+		return nil, ErrNoMatch
+	case 1:
+		obj, ok := items[0].(k8s.Object)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T in list, does not satisfy k8s.Object", items[0])
+		}
+		return obj, nil
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		accessor, err := meta.Accessor(item)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, accessor.GetNamespace()+"/"+accessor.GetName())
+	}
+	return nil, fmt.Errorf("%w: %s", ErrMultipleMatches, strings.Join(names, ", "))
+}
+
+// isRetryableListError reports whether err indicates the API server is transiently unavailable.
+func isRetryableListError(err error) bool {
+	return apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) ||
+		utilnet.IsConnectionRefused(err) ||
+		utilnet.IsConnectionReset(err) ||
+		utilnet.IsProbableEOF(err)
+}
+
+// ListByGVK retrieves the objects of the kind identified by gvk matching the provided options, without
+// requiring the caller to construct the list type. The list type is built from the scheme, falling back
+// to unstructured.UnstructuredList for kinds the scheme does not know about, such as custom resources.
+func (r *Resources) ListByGVK(ctx context.Context, gvk schema.GroupVersionKind, opts ...ListOption) ([]k8s.Object, error) {
+	listGVK := gvk.GroupVersion().WithKind(gvk.Kind + "List")
+	var list k8s.ObjectList
+	if r.scheme.Recognizes(listGVK) {
+		obj, err := r.scheme.New(listGVK)
+		if err != nil {
+			return nil, err
+		}
+		typed, ok := obj.(k8s.ObjectList)
+		if !ok {
+			return nil, fmt.Errorf("type %T of %s is not a list", obj, listGVK)
+		}
+		list = typed
+	} else {
+		unstructuredList := &unstructured.UnstructuredList{}
+		unstructuredList.SetGroupVersionKind(listGVK)
+		list = unstructuredList
+	}
+
+	if err := r.List(ctx, list, opts...); err != nil {
+		return nil, err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]k8s.Object, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(k8s.Object)
+		if !ok {
+			return nil, fmt.Errorf("item of type %T in %s is not an object", item, listGVK)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
 func WithLabelSelector(sel string) ListOption {
 	return func(lo *metav1.ListOptions) { lo.LabelSelector = sel }
 }
@@ -208,6 +1129,22 @@ func WithFieldSelector(sel string) ListOption {
 	return func(lo *metav1.ListOptions) { lo.FieldSelector = sel }
 }
 
+// WithListResourceVersion sets the resourceVersion the List request is served at, as
+// WithResourceVersion does for Get requests. With "0", the API server may serve a stale list
+// from its watch cache.
+func WithListResourceVersion(rv string) ListOption {
+	return func(lo *metav1.ListOptions) { lo.ResourceVersion = rv }
+}
+
+// WithListExactReads requests a consistent list, served from etcd, as WithExactReads does for
+// Get requests. This is the default for List.
+func WithListExactReads() ListOption {
+	return func(lo *metav1.ListOptions) {
+		lo.ResourceVersion = ""
+		lo.ResourceVersionMatch = ""
+	}
+}
+
 func WithTimeout(to time.Duration) ListOption {
 	t := to.Milliseconds()
 	return func(lo *metav1.ListOptions) { lo.TimeoutSeconds = &t }
@@ -216,10 +1153,18 @@ func WithTimeout(to time.Duration) ListOption {
 // PatchOption is used to provide additional arguments to the Patch call.
 type PatchOption func(*metav1.PatchOptions)
 
-// Patch patches portion of object `obj` with data from object `patch`
+// Patch patches portion of object `obj` with data from object `patch`. The patch.PatchType
+// selects how patch.Data is interpreted: types.MergePatchType, types.JSONPatchType,
+// types.StrategicMergePatchType (built-in types only) or types.ApplyPatchType.
+// The GroupVersionKind is resolved from the scheme for typed objects and from the
+// apiVersion and kind of unstructured objects. Use PatchSubresource to patch a subresource.
+// Upon success, obj is updated in place with the patched object returned by the API server.
 func (r *Resources) Patch(ctx context.Context, obj k8s.Object, patch k8s.Patch, opts ...PatchOption) error {
 	patchOptions := &metav1.PatchOptions{}
 
+	for _, fn := range r.defaultPatchOptions {
+		fn(patchOptions)
+	}
 	for _, fn := range opts {
 		fn(patchOptions)
 	}
@@ -227,10 +1172,11 @@ func (r *Resources) Patch(ctx context.Context, obj k8s.Object, patch k8s.Patch,
 	p := cr.RawPatch(patch.PatchType, patch.Data)
 
 	o := &cr.PatchOptions{
-		Raw:          patchOptions,
-		DryRun:       patchOptions.DryRun,
-		Force:        patchOptions.Force,
-		FieldManager: patchOptions.FieldManager,
+		Raw:             patchOptions,
+		DryRun:          patchOptions.DryRun,
+		Force:           patchOptions.Force,
+		FieldManager:    patchOptions.FieldManager,
+		FieldValidation: patchOptions.FieldValidation,
 	}
 	return r.client.Patch(ctx, obj, p, o)
 }
@@ -239,6 +1185,9 @@ func (r *Resources) Patch(ctx context.Context, obj k8s.Object, patch k8s.Patch,
 func (r *Resources) PatchSubresource(ctx context.Context, obj k8s.Object, subresource string, patch k8s.Patch, opts ...PatchOption) error {
 	patchOptions := &metav1.PatchOptions{}
 
+	for _, fn := range r.defaultPatchOptions {
+		fn(patchOptions)
+	}
 	for _, fn := range opts {
 		fn(patchOptions)
 	}
@@ -265,6 +1214,36 @@ func (r *Resources) Label(obj k8s.Object, label map[string]string) {
 	obj.SetLabels(label)
 }
 
+// RecordEvent creates a Normal Event for the involved object, so that the actions performed by a test
+// show up alongside the events of the controllers, e.g. with `kubectl get events`. Events of
+// cluster-scoped objects are recorded in the default namespace.
+func (r *Resources) RecordEvent(ctx context.Context, involved k8s.Object, reason, message string) error {
+	ref, err := reference.GetReference(r.scheme, involved)
+	if err != nil {
+		return fmt.Errorf("failed to get reference of the involved object: %w", err)
+	}
+	namespace := involved.GetNamespace()
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", ref.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        message,
+		Type:           v1.EventTypeNormal,
+		Source:         v1.EventSource{Component: eventSourceComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	return r.client.Create(ctx, event)
+}
+
 func (r *Resources) GetScheme() *runtime.Scheme {
 	return r.scheme
 }
@@ -277,6 +1256,9 @@ func (r *Resources) GetControllerRuntimeClient() cr.Client {
 func (r *Resources) Watch(object k8s.ObjectList, opts ...ListOption) *watcher.EventHandlerFuncs {
 	listOptions := &metav1.ListOptions{}
 
+	for _, fn := range r.defaultListOptions {
+		fn(listOptions)
+	}
 	for _, fn := range opts {
 		fn(listOptions)
 	}
@@ -291,6 +1273,12 @@ func (r *Resources) Watch(object k8s.ObjectList, opts ...ListOption) *watcher.Ev
 }
 
 func (r *Resources) ExecInPod(ctx context.Context, namespaceName, podName, containerName string, command []string, stdout, stderr *bytes.Buffer) error {
+	return r.streamInPod(ctx, namespaceName, podName, containerName, command, nil, stdout, stderr)
+}
+
+// streamInPod runs command in the container of the pod, streaming stdin to the command when
+// not nil, and its output to stdout and stderr.
+func (r *Resources) streamInPod(ctx context.Context, namespaceName, podName, containerName string, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	clientset, err := kubernetes.NewForConfig(r.config)
 	if err != nil {
 		return err
@@ -309,24 +1297,21 @@ func (r *Resources) ExecInPod(ctx context.Context, namespaceName, podName, conta
 	req.VersionedParams(&v1.PodExecOptions{
 		Container: containerName,
 		Command:   command,
+		Stdin:     stdin != nil,
 		Stdout:    true,
 		Stderr:    true,
 	}, parameterCodec)
 
 	exec, err := remotecommand.NewSPDYExecutor(r.config, "POST", req.URL())
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+	return exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
 		Stdout: stdout,
 		Stderr: stderr,
 	})
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
 
 func init() {
@@ -20,21 +20,42 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/vladimirvivien/gexe"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
 	log "k8s.io/klog/v2"
 
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
 	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources/testdata/projectExample"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
 )
 
 func TestCreate(t *testing.T) {
@@ -60,6 +81,563 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestCreateWithGenerateName(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "generate-name-test-", Namespace: namespace.Name},
+		Data:       map[string]string{"foo": "bar"},
+	}
+	if err := res.Create(context.TODO(), cm); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+	if cm.GetName() == "" {
+		t.Fatal("expected the generated name to be set on the created object")
+	}
+	if !strings.HasPrefix(cm.GetName(), cm.GetGenerateName()) {
+		t.Errorf("expected generated name %q to start with %q", cm.GetName(), cm.GetGenerateName())
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind("ConfigMap")
+	u.SetGenerateName("generate-name-test-")
+	u.SetNamespace(namespace.Name)
+	if err := res.Create(context.TODO(), u); err != nil {
+		t.Fatal("error while creating unstructured configmap", err)
+	}
+	if u.GetName() == "" {
+		t.Fatal("expected the generated name to be set on the created unstructured object")
+	}
+
+	var cmObj corev1.ConfigMap
+	if err := res.Get(context.TODO(), cm.GetName(), namespace.Name, &cmObj); err != nil {
+		t.Error("error while getting the configmap by its generated name", err)
+	}
+}
+
+func TestCreateWithServerDefaults(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "server-defaults-test", Namespace: namespace.Name},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "server-defaults-test"},
+			Ports:    []corev1.ServicePort{{Port: 80}},
+		},
+	}
+	if err := res.Create(context.TODO(), svc); err != nil {
+		t.Fatal("error while creating service", err)
+	}
+	if svc.UID == "" {
+		t.Error("expected the server assigned UID to be set on the created object")
+	}
+	if svc.Spec.ClusterIP == "" {
+		t.Error("expected the server assigned cluster IP to be set on the created object")
+	}
+	if svc.Spec.Type != corev1.ServiceTypeClusterIP {
+		t.Errorf("expected the defaulted service type %q, got %q", corev1.ServiceTypeClusterIP, svc.Spec.Type)
+	}
+}
+
+func TestCreateWithFieldValidationStrict(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	newConfigMap := func(name string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": name, "namespace": namespace.Name},
+			"data":       map[string]interface{}{"foo": "bar"},
+			"dataa":      map[string]interface{}{"foo": "bar"},
+		}}
+	}
+
+	err = res.Create(context.TODO(), newConfigMap("field-validation-strict"), resources.WithFieldValidation(metav1.FieldValidationStrict))
+	if err == nil {
+		t.Fatal("expected the configmap with an unknown field to be rejected")
+	}
+	if !apierrors.IsBadRequest(err) || !strings.Contains(err.Error(), "dataa") {
+		t.Errorf("expected a bad request naming the unknown field, got: %v", err)
+	}
+
+	if err := res.Create(context.TODO(), newConfigMap("field-validation-default")); err != nil {
+		t.Errorf("expected the unknown field to be dropped without strict validation, got: %v", err)
+	}
+}
+
+func TestCreateWithEnsureNamespace(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	nsName := "ensure-namespace-test"
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ensure-namespace-cm", Namespace: nsName},
+		Data:       map[string]string{"foo": "bar"},
+	}
+	err = res.CreateWithOptions(context.TODO(), cm, nil, resources.WithEnsureNamespace())
+	if err != nil {
+		t.Fatal("error while creating configmap into a missing namespace", err)
+	}
+	defer deleteNamespace(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName}})
+
+	if _, err := clientset.CoreV1().Namespaces().Get(context.TODO(), nsName, metav1.GetOptions{}); err != nil {
+		t.Error("expected the namespace to be created", err)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps(nsName).Get(context.TODO(), cm.Name, metav1.GetOptions{}); err != nil {
+		t.Error("expected the configmap to be created", err)
+	}
+
+	// the option is a no-op when the namespace already exists
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "ensure-namespace-cm-2", Namespace: nsName}}
+	if err := res.CreateWithOptions(context.TODO(), other, nil, resources.WithEnsureNamespace()); err != nil {
+		t.Error("error while creating configmap into an existing namespace", err)
+	}
+}
+
+func TestCreateOrUpdate(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "create-or-update-test", Namespace: namespace.Name}}
+	mutate := func() error {
+		cm.Data = map[string]string{"foo": "bar"}
+		return nil
+	}
+
+	result, err := res.CreateOrUpdate(context.TODO(), cm, mutate)
+	if err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+	if result != resources.OperationResultCreated {
+		t.Errorf("expected result %q on first call, got %q", resources.OperationResultCreated, result)
+	}
+
+	result, err = res.CreateOrUpdate(context.TODO(), cm, mutate)
+	if err != nil {
+		t.Fatal("error during unchanged create or update", err)
+	}
+	if result != resources.OperationResultNone {
+		t.Errorf("expected result %q on unchanged second call, got %q", resources.OperationResultNone, result)
+	}
+
+	result, err = res.CreateOrUpdate(context.TODO(), cm, func() error {
+		cm.Data["foo"] = "baz"
+		return nil
+	})
+	if err != nil {
+		t.Fatal("error while updating configmap", err)
+	}
+	if result != resources.OperationResultUpdated {
+		t.Errorf("expected result %q after mutation, got %q", resources.OperationResultUpdated, result)
+	}
+
+	var cmObj corev1.ConfigMap
+	if err := res.Get(context.TODO(), cm.Name, namespace.Name, &cmObj); err != nil {
+		t.Fatal("error while getting configmap", err)
+	}
+	if cmObj.Data["foo"] != "baz" {
+		t.Errorf("expected updated data, got %v", cmObj.Data)
+	}
+}
+
+func TestCreateOrGet(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "create-or-get-test", Namespace: namespace.Name}, Data: map[string]string{"foo": "bar"}}
+	existed, err := res.CreateOrGet(context.TODO(), cm)
+	if err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+	if existed {
+		t.Error("expected the configmap not to exist on first call")
+	}
+
+	second := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "create-or-get-test", Namespace: namespace.Name}, Data: map[string]string{"foo": "baz"}}
+	existed, err = res.CreateOrGet(context.TODO(), second)
+	if err != nil {
+		t.Fatal("error during second create or get", err)
+	}
+	if !existed {
+		t.Error("expected the configmap to exist on second call")
+	}
+	if second.UID != cm.UID || second.ResourceVersion == "" {
+		t.Errorf("expected the existing object to be fetched, got uid %q and resourceVersion %q", second.UID, second.ResourceVersion)
+	}
+	if second.Data["foo"] != "bar" {
+		t.Errorf("expected the existing data to be left unchanged, got %v", second.Data)
+	}
+}
+
+func TestExists(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "exists-test", Namespace: namespace.Name}}
+	if err := res.Create(context.TODO(), cm); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+
+	gvk := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	exists, err := res.Exists(context.TODO(), gvk, cm.Name, namespace.Name)
+	if err != nil {
+		t.Fatal("error while checking configmap existence", err)
+	}
+	if !exists {
+		t.Error("expected the created configmap to exist")
+	}
+
+	exists, err = res.Exists(context.TODO(), gvk, "exists-test-missing", namespace.Name)
+	if err != nil {
+		t.Fatal("error while checking missing configmap existence", err)
+	}
+	if exists {
+		t.Error("expected the missing configmap not to exist")
+	}
+}
+
+func TestGetWithExactReads(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "exact-reads-test", Namespace: namespace.Name}}
+	if err := res.Create(context.TODO(), cm); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+
+	// an exact read issued right after the create must observe it
+	var actual corev1.ConfigMap
+	if err := res.Get(context.TODO(), cm.Name, namespace.Name, &actual, resources.WithResourceVersion("0"), resources.WithExactReads()); err != nil {
+		t.Fatal("error while getting configmap", err)
+	}
+	if actual.ResourceVersion != cm.ResourceVersion {
+		t.Errorf("expected resourceVersion %s of the created configmap, got %s", cm.ResourceVersion, actual.ResourceVersion)
+	}
+
+	var list corev1.ConfigMapList
+	if err := res.WithNamespace(namespace.Name).List(context.TODO(), &list, resources.WithListExactReads()); err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	found := false
+	for _, item := range list.Items {
+		found = found || item.Name == cm.Name
+	}
+	if !found {
+		t.Error("expected the created configmap to be listed")
+	}
+}
+
+func TestUpdatePreservingImmutable(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	newService := func() *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "update-preserving-test", Namespace: namespace.Name},
+			Spec: corev1.ServiceSpec{
+				Type:     corev1.ServiceTypeNodePort,
+				Selector: map[string]string{"app": "update-preserving-test"},
+				Ports:    []corev1.ServicePort{{Name: "http", Port: 80}},
+			},
+		}
+	}
+	created := newService()
+	if err := res.Create(context.TODO(), created); err != nil {
+		t.Fatal("error while creating service", err)
+	}
+
+	// the service decoded from disk does not hold the fields allocated by the API server
+	updated := newService()
+	updated.Labels = map[string]string{"updated": "true"}
+	if err := res.Update(context.TODO(), updated.DeepCopy()); err == nil {
+		t.Fatal("expected a plain update omitting the resourceVersion to fail")
+	}
+	if err := res.UpdatePreservingImmutable(context.TODO(), updated); err != nil {
+		t.Fatal("error while updating service", err)
+	}
+	if updated.Labels["updated"] != "true" {
+		t.Errorf("expected the labels to be updated, got %v", updated.Labels)
+	}
+	if updated.Spec.ClusterIP != created.Spec.ClusterIP {
+		t.Errorf("expected clusterIP %s to be preserved, got %s", created.Spec.ClusterIP, updated.Spec.ClusterIP)
+	}
+	if updated.Spec.Ports[0].NodePort != created.Spec.Ports[0].NodePort {
+		t.Errorf("expected nodePort %d to be preserved, got %d", created.Spec.Ports[0].NodePort, updated.Spec.Ports[0].NodePort)
+	}
+}
+
+func TestCreateAll(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	ns := "create-all-test"
+	examples := os.DirFS(filepath.Join("..", "..", "decoder", "testdata", "examples"))
+	objs, err := decoder.DecodeAllFiles(context.TODO(), examples, "*", decoder.MutateNamespace(ns))
+	if err != nil {
+		t.Fatal("error while decoding the examples", err)
+	}
+	// the namespace is passed last, dependency ordering creates it first
+	objs = append(objs, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}})
+	objs = resources.SortByDependency(objs)
+	if _, ok := objs[0].(*corev1.Namespace); !ok {
+		t.Fatalf("expected the namespace to be sorted first, got %T", objs[0])
+	}
+
+	if err := res.CreateAll(context.TODO(), objs, resources.BatchStopOnError); err != nil {
+		t.Fatal("error while creating the examples", err)
+	}
+	for _, obj := range objs {
+		if err := res.Get(context.TODO(), obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			t.Errorf("expected %s/%s to exist: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+
+	for _, obj := range objs {
+		obj.SetResourceVersion("")
+	}
+	err = res.CreateAll(context.TODO(), objs, resources.BatchContinueOnError)
+	if err == nil || strings.Count(err.Error(), "already exists") != len(objs) {
+		t.Errorf("expected an aggregated error for each object, got: %v", err)
+	}
+
+	if err := res.DeleteAll(context.TODO(), objs, resources.BatchStopOnError); err != nil {
+		t.Error("error while deleting the examples", err)
+	}
+}
+
+func TestGetUnaffectedByLocalMutation(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "local-mutation-test", Namespace: namespace.Name},
+		Data:       map[string]string{"foo": "bar"},
+	}
+	if err := res.Create(context.TODO(), cm); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+
+	first := &corev1.ConfigMap{}
+	if err := res.Get(context.TODO(), cm.Name, cm.Namespace, first); err != nil {
+		t.Fatal("error while getting configmap", err)
+	}
+	first.Data["foo"] = "mutated"
+	first.Labels = map[string]string{"mutated": "true"}
+
+	second := &corev1.ConfigMap{}
+	if err := res.Get(context.TODO(), cm.Name, cm.Namespace, second); err != nil {
+		t.Fatal("error while getting configmap", err)
+	}
+	if second.Data["foo"] != "bar" || len(second.Labels) != 0 {
+		t.Errorf("local mutation leaked into subsequent Get: data %v, labels %v", second.Data, second.Labels)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := res.List(context.TODO(), &cms); err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	for i := range cms.Items {
+		if cms.Items[i].Name == cm.Name && cms.Items[i].Data["foo"] != "bar" {
+			t.Errorf("local mutation leaked into List: data %v", cms.Items[i].Data)
+		}
+	}
+}
+
+func TestGetOwner(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "get-owner-test", Namespace: namespace.Name},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "get-owner-test"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "get-owner-test"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+			},
+		},
+	}
+	if err := res.Create(context.TODO(), deployment); err != nil {
+		t.Fatal("error while creating deployment", err)
+	}
+
+	var pods corev1.PodList
+	err = wait.For(conditions.New(res.WithNamespace(namespace.Name)).ResourceListN(&pods, 1, resources.WithLabelSelector("app=get-owner-test")), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Fatal("error while waiting for the deployment pod", err)
+	}
+
+	var rs appsv1.ReplicaSet
+	if err := res.GetOwner(context.TODO(), &pods.Items[0], &rs); err != nil {
+		t.Fatal("error while getting the owner of the pod", err)
+	}
+	var owner appsv1.Deployment
+	if err := res.GetOwner(context.TODO(), &rs, &owner); err != nil {
+		t.Fatal("error while getting the owner of the replicaset", err)
+	}
+	if owner.UID != deployment.UID {
+		t.Errorf("expected the pod to be traversed back to deployment %s, got %s", deployment.Name, owner.Name)
+	}
+
+	if err := res.GetOwner(context.TODO(), &owner, &appsv1.Deployment{}); !errors.Is(err, resources.ErrNoControllerOwner) {
+		t.Errorf("expected ErrNoControllerOwner for the deployment, got: %v", err)
+	}
+}
+
+func TestListOwnedBy(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	deployments := make([]*appsv1.Deployment, 2)
+	for i, name := range []string{"list-owned-by-test", "list-owned-by-other"} {
+		deployments[i] = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace.Name},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+				},
+			},
+		}
+		if err := res.Create(context.TODO(), deployments[i]); err != nil {
+			t.Fatal("error while creating deployment", err)
+		}
+	}
+
+	var all appsv1.ReplicaSetList
+	err = wait.For(conditions.New(res.WithNamespace(namespace.Name)).ResourceListMatchN(&all, 2, func(obj k8s.Object) bool {
+		name := obj.GetLabels()["app"]
+		return name == "list-owned-by-test" || name == "list-owned-by-other"
+	}), wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Fatal("error while waiting for the replicasets", err)
+	}
+
+	var owned appsv1.ReplicaSetList
+	if err := res.ListOwnedBy(context.TODO(), deployments[0], &owned); err != nil {
+		t.Fatal("error while listing the owned replicasets", err)
+	}
+	if len(owned.Items) != 1 {
+		t.Fatalf("expected 1 replicaset owned by the deployment, got %d", len(owned.Items))
+	}
+	if ref := metav1.GetControllerOf(&owned.Items[0]); ref == nil || ref.UID != deployments[0].UID {
+		t.Errorf("expected the replicaset to be owned by deployment %s, got %+v", deployments[0].Name, ref)
+	}
+}
+
+func TestGetBy(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	pods := make([]*corev1.Pod, 2)
+	for i, role := range []string{"unique", "other"} {
+		pods[i] = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "get-by-" + role,
+				Namespace: namespace.Name,
+				Labels:    map[string]string{"app": "get-by", "get-by-role": role},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+		}
+		if err := res.Create(context.TODO(), pods[i]); err != nil {
+			t.Fatal("error while creating pod", err)
+		}
+	}
+	defer func() {
+		for _, pod := range pods {
+			_ = res.Delete(context.TODO(), pod)
+		}
+	}()
+
+	res = res.WithNamespace(namespace.Name)
+	obj, err := res.GetBy(context.TODO(), &corev1.PodList{}, resources.WithLabelSelector("get-by-role=unique"))
+	if err != nil {
+		t.Fatal("error while getting the pod by selector", err)
+	}
+	if pod, ok := obj.(*corev1.Pod); !ok || pod.UID != pods[0].UID {
+		t.Errorf("expected pod %s, got %v", pods[0].Name, obj)
+	}
+
+	_, err = res.GetBy(context.TODO(), &corev1.PodList{}, resources.WithLabelSelector("app=get-by"))
+	if !errors.Is(err, resources.ErrMultipleMatches) {
+		t.Errorf("expected ErrMultipleMatches, got %v", err)
+	}
+	_, err = res.GetBy(context.TODO(), &corev1.PodList{}, resources.WithLabelSelector("get-by-role=missing"))
+	if !errors.Is(err, resources.ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch, got %v", err)
+	}
+}
+
+func TestAssertMatches(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	expected := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "assert-matches", Namespace: namespace.Name, Labels: map[string]string{"app": "assert-matches"}},
+		Data:       map[string]string{"key": "value"},
+	}
+	if err := res.Create(context.TODO(), expected.DeepCopy()); err != nil {
+		t.Fatal("error while creating config map", err)
+	}
+	defer func() {
+		_ = res.Delete(context.TODO(), expected)
+	}()
+
+	if err := res.AssertMatches(context.TODO(), expected); err != nil {
+		t.Errorf("expected config map to match its source, got %v", err)
+	}
+
+	changed := expected.DeepCopy()
+	changed.Data["key"] = "other"
+	err = res.AssertMatches(context.TODO(), changed)
+	if err == nil {
+		t.Fatal("expected config map with changed data not to match")
+	}
+	if !strings.Contains(err.Error(), `"other"`) || !strings.Contains(err.Error(), `"value"`) {
+		t.Errorf("expected a diff of the data in the error, got %v", err)
+	}
+	if err := res.AssertMatches(context.TODO(), changed, "data.key"); err != nil {
+		t.Errorf("expected config map to match when ignoring the changed field, got %v", err)
+	}
+}
+
 func TestRes(t *testing.T) {
 	res, err := resources.New(cfg)
 	if err != nil {
@@ -98,6 +676,180 @@ func TestResNoConfig(t *testing.T) {
 	}
 }
 
+func TestGetCancelledContext(t *testing.T) {
+	received := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		<-unblock
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	res, err := resources.New(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	go func() {
+		<-received
+		cancel()
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- res.Get(ctx, "blocked", namespace.Name, &corev1.ConfigMap{})
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context cancellation error, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get did not return after its context was cancelled")
+	}
+}
+
+func TestGetCancelledContextSharesMappingLookup(t *testing.T) {
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	res, err := resources.New(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	// the requests for a kind wait for the same lookup of its mapping rather than each leaving
+	// a goroutine blocked on the API server behind
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+		err := res.Get(ctx, "blocked", namespace.Name, &corev1.ConfigMap{})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context deadline error, got: %v", err)
+		}
+	}
+	if leaked := runtime.NumGoroutine() - goroutines; leaked >= 10 {
+		t.Errorf("expected the blocked lookup to be shared by the requests, got %d more goroutines", leaked)
+	}
+}
+
+func TestCreateUnknownKind(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "e2e-framework.example", Version: "v1", Kind: "Unknown"})
+	obj.SetName("unknown-kind")
+	obj.SetNamespace(namespace.Name)
+
+	err = res.Create(context.TODO(), obj)
+	var noMatch *resources.NoMatchingKindError
+	if !errors.As(err, &noMatch) {
+		t.Fatalf("expected a NoMatchingKindError, got: %v", err)
+	}
+	if noMatch.GroupVersionKind != obj.GroupVersionKind() {
+		t.Errorf("expected the error to name %v, got %v", obj.GroupVersionKind(), noMatch.GroupVersionKind)
+	}
+	if !strings.Contains(err.Error(), `"Unknown"`) || !strings.Contains(err.Error(), "CustomResourceDefinition") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	if !meta.IsNoMatchError(err) {
+		t.Errorf("expected the underlying no match error to be preserved, got: %v", err)
+	}
+}
+
+func TestCreateWithNameCollisionRetry(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	configMaps := make([]*corev1.ConfigMap, 2)
+	errs := make([]error, len(configMaps))
+	var wg sync.WaitGroup
+	for i := range configMaps {
+		configMaps[i] = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "name-collision", Namespace: namespace.Name}}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = res.CreateWithOptions(context.TODO(), configMaps[i], nil, resources.WithNameCollisionRetry(3))
+		}(i)
+	}
+	wg.Wait()
+	defer func() {
+		for _, cm := range configMaps {
+			_ = res.Delete(context.TODO(), cm)
+		}
+	}()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("expected configmap %d to be created, got: %v", i, err)
+		}
+	}
+	if configMaps[0].Name == configMaps[1].Name {
+		t.Fatalf("expected distinct names, got %q twice", configMaps[0].Name)
+	}
+	for _, cm := range configMaps {
+		if !strings.HasPrefix(cm.Name, "name-collision") {
+			t.Errorf("expected the name %q to start with the requested name", cm.Name)
+		}
+		if err := res.Get(context.TODO(), cm.Name, cm.Namespace, &corev1.ConfigMap{}); err != nil {
+			t.Errorf("expected configmap %q to exist: %v", cm.Name, err)
+		}
+	}
+}
+
+func TestUpdateAllWithRollback(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	first := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "rollback-first", Namespace: namespace.Name}, Data: map[string]string{"version": "1"}}
+	second := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "rollback-second", Namespace: namespace.Name}, Data: map[string]string{"version": "1"}}
+	for _, cm := range []*corev1.ConfigMap{first, second} {
+		if err := res.Create(context.TODO(), cm); err != nil {
+			t.Fatal("error while creating configmap", err)
+		}
+	}
+
+	first.Data["version"] = "2"
+	second.Data["version"] = "2"
+	// an invalid label value fails the update of the second configmap
+	second.Labels = map[string]string{"invalid": "not a valid value!"}
+	err = res.UpdateAllWithRollback(context.TODO(), []k8s.Object{first, second})
+	if !apierrors.IsInvalid(err) {
+		t.Fatalf("expected the invalid update to fail, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), second.Name) {
+		t.Errorf("expected the error to name the failed object, got: %v", err)
+	}
+
+	for _, name := range []string{first.Name, second.Name} {
+		var cm corev1.ConfigMap
+		if err := res.Get(context.TODO(), name, namespace.Name, &cm); err != nil {
+			t.Fatal("error while getting configmap", err)
+		}
+		if cm.Data["version"] != "1" {
+			t.Errorf("expected configmap %s to be left at its original version, got %q", name, cm.Data["version"])
+		}
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	res, err := resources.New(cfg)
 	if err != nil {
@@ -133,71 +885,251 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
-func TestUpdateStatus(t *testing.T) {
+func TestUpdateStatus(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	depActual := getDeployment("update-status-test-dep-name")
+
+	err = res.Create(context.TODO(), depActual)
+	if err != nil {
+		t.Error("error while creating deployment", err)
+	}
+
+	depUpdated := depActual
+	depUpdated.Status.Conditions = append(depUpdated.Status.Conditions,
+		appsv1.DeploymentCondition{
+			Type:               "UpdateStatusTest",
+			Status:             corev1.ConditionTrue,
+			LastUpdateTime:     metav1.NewTime(time.Now()),
+			LastTransitionTime: metav1.NewTime(time.Now()),
+		},
+	)
+
+	err = res.UpdateStatus(context.TODO(), depUpdated)
+	if err != nil {
+		t.Error("error while updating deployment status", err)
+	}
+
+	var depObj appsv1.Deployment
+	err = res.Get(context.TODO(), depUpdated.Name, namespace.Name, &depObj)
+	if err != nil {
+		t.Error("error while getting the deployment", err)
+	}
+
+	var cond *appsv1.DeploymentCondition
+	for i := range depObj.Status.Conditions {
+		if depObj.Status.Conditions[i].Type == "UpdateStatusTest" {
+			cond = &depObj.Status.Conditions[i]
+			break
+		}
+	}
+
+	if cond == nil {
+		t.Error("deployment status not updated")
+	} else if cond.Status != corev1.ConditionTrue {
+		t.Error("deployment status value mismatch, expected : ", corev1.ConditionTrue, "obtained :", cond.Status)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	depActual := getDeployment("delete-test-dep-name")
+
+	err = res.Create(context.TODO(), depActual)
+	if err != nil {
+		t.Error("error while creating deployment", err)
+	}
+
+	err = res.Delete(context.TODO(), depActual)
+	if err != nil {
+		t.Error("error while deleting deployment", err)
+	}
+}
+
+func TestWaitForCount(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	replicas := int32(1)
+	labels := map[string]string{"app": "wait-for-count"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "wait-for-count", Namespace: namespace.Name},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+			},
+		},
+	}
+	if err := res.Create(context.TODO(), deployment); err != nil {
+		t.Fatal("error while creating deployment", err)
+	}
+	defer func() { _ = res.Delete(context.TODO(), deployment) }()
+
+	res = res.WithNamespace(namespace.Name)
+	var pods corev1.PodList
+	if err := res.WaitForCount(context.TODO(), &pods, 1, time.Minute, resources.WithLabelSelector("app=wait-for-count")); err != nil {
+		t.Fatal("error while waiting for the pod of the deployment", err)
+	}
+
+	if err := res.Get(context.TODO(), deployment.Name, deployment.Namespace, deployment); err != nil {
+		t.Fatal("error while getting deployment", err)
+	}
+	replicas = 3
+	deployment.Spec.Replicas = &replicas
+	if err := res.Update(context.TODO(), deployment); err != nil {
+		t.Fatal("error while scaling deployment", err)
+	}
+	if err := res.WaitForCount(context.TODO(), &pods, 3, time.Minute, resources.WithLabelSelector("app=wait-for-count")); err != nil {
+		t.Fatal("error while waiting for the pods of the scaled deployment", err)
+	}
+	if len(pods.Items) != 3 {
+		t.Errorf("expected the 3 pods to be listed, got %d", len(pods.Items))
+	}
+
+	err = res.WaitForCount(context.TODO(), &pods, 5, 2*time.Second, resources.WithLabelSelector("app=wait-for-count"))
+	if err == nil || !strings.Contains(err.Error(), "last listed 3") {
+		t.Errorf("expected a timeout error reporting the last count, got: %v", err)
+	}
+}
+
+func TestWaitForDeletion(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "wait-for-deletion-test", Namespace: namespace.Name}}
+	if err := res.Create(context.TODO(), cm); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+	if err := res.Delete(context.TODO(), cm); err != nil {
+		t.Fatal("error while deleting configmap", err)
+	}
+	if err := res.WaitForDeletion(context.TODO(), cm, time.Minute); err != nil {
+		t.Error("error while waiting for the configmap deletion", err)
+	}
+
+	finalizer := "e2e-framework.sigs.k8s.io/wait-for-deletion"
+	held := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "wait-for-deletion-finalizer-test", Namespace: namespace.Name, Finalizers: []string{finalizer}}}
+	if err := res.Create(context.TODO(), held); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+	if err := res.Delete(context.TODO(), held); err != nil {
+		t.Fatal("error while deleting configmap", err)
+	}
+	err = res.WaitForDeletion(context.TODO(), held, 3*time.Second)
+	if err == nil || !strings.Contains(err.Error(), finalizer) {
+		t.Errorf("expected a timeout error naming the remaining finalizer, got: %v", err)
+	}
+
+	if err := res.Get(context.TODO(), held.Name, held.Namespace, held); err != nil {
+		t.Fatal("error while getting configmap", err)
+	}
+	held.Finalizers = nil
+	if err := res.Update(context.TODO(), held); err != nil {
+		t.Fatal("error while removing the finalizer", err)
+	}
+	if err := res.WaitForDeletion(context.TODO(), held, time.Minute); err != nil {
+		t.Error("error while waiting for the configmap deletion", err)
+	}
+}
+
+func TestWaitForDeletionReportsRemainingFinalizers(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	removed, remaining := "e2e-framework.sigs.k8s.io/removed", "e2e-framework.sigs.k8s.io/remaining"
+	held := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "wait-for-deletion-finalizers-test", Namespace: namespace.Name, Finalizers: []string{removed, remaining}}}
+	if err := res.Create(context.TODO(), held); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+	if err := res.Delete(context.TODO(), held); err != nil {
+		t.Fatal("error while deleting configmap", err)
+	}
+	defer func() {
+		var cm corev1.ConfigMap
+		if err := res.Get(context.TODO(), held.Name, held.Namespace, &cm); err == nil {
+			cm.Finalizers = nil
+			_ = res.Update(context.TODO(), &cm)
+		}
+	}()
+
+	// one of the finalizers is removed while waiting, as a controller would
+	updated := make(chan error, 1)
+	go func() {
+		time.Sleep(1500 * time.Millisecond)
+		var cm corev1.ConfigMap
+		if err := res.Get(context.TODO(), held.Name, held.Namespace, &cm); err != nil {
+			updated <- err
+			return
+		}
+		cm.Finalizers = []string{remaining}
+		updated <- res.Update(context.TODO(), &cm)
+	}()
+
+	err = res.WaitForDeletion(context.TODO(), held, 4*time.Second)
+	if err := <-updated; err != nil {
+		t.Fatal("error while removing a finalizer", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "["+remaining+"]") {
+		t.Errorf("expected a timeout error naming the remaining finalizer only, got: %v", err)
+	}
+}
+
+func TestDeleteWithForegroundPropagation(t *testing.T) {
 	res, err := resources.New(cfg)
 	if err != nil {
 		t.Fatalf("Error creating new resources object: %v", err)
 	}
 
-	depActual := getDeployment("update-status-test-dep-name")
+	depActual := getDeployment("delete-foreground-test-dep-name")
+	podLabels := map[string]string{"app": "delete-foreground-test"}
+	depActual.Spec.Selector.MatchLabels = podLabels
+	depActual.Spec.Template.ObjectMeta.Labels = podLabels
 
 	err = res.Create(context.TODO(), depActual)
 	if err != nil {
-		t.Error("error while creating deployment", err)
+		t.Fatal("error while creating deployment", err)
 	}
 
-	depUpdated := depActual
-	depUpdated.Status.Conditions = append(depUpdated.Status.Conditions,
-		appsv1.DeploymentCondition{
-			Type:               "UpdateStatusTest",
-			Status:             corev1.ConditionTrue,
-			LastUpdateTime:     metav1.NewTime(time.Now()),
-			LastTransitionTime: metav1.NewTime(time.Now()),
-		},
-	)
-
-	err = res.UpdateStatus(context.TODO(), depUpdated)
+	selector := resources.WithLabelSelector(labels.FormatLabels(podLabels))
+	err = wait.For(conditions.New(res).ResourceListN(&corev1.PodList{}, int(replicaCount), selector), wait.WithTimeout(time.Minute*2))
 	if err != nil {
-		t.Error("error while updating deployment status", err)
+		t.Fatal("error while waiting for deployment pods", err)
 	}
 
-	var depObj appsv1.Deployment
-	err = res.Get(context.TODO(), depUpdated.Name, namespace.Name, &depObj)
+	err = res.Delete(context.TODO(), depActual, resources.WithDeletePropagation(metav1.DeletePropagationForeground), resources.WithGracePeriod(0))
 	if err != nil {
-		t.Error("error while getting the deployment", err)
-	}
-
-	var cond *appsv1.DeploymentCondition
-	for i := range depObj.Status.Conditions {
-		if depObj.Status.Conditions[i].Type == "UpdateStatusTest" {
-			cond = &depObj.Status.Conditions[i]
-			break
-		}
-	}
-
-	if cond == nil {
-		t.Error("deployment status not updated")
-	} else if cond.Status != corev1.ConditionTrue {
-		t.Error("deployment status value mismatch, expected : ", corev1.ConditionTrue, "obtained :", cond.Status)
+		t.Fatal("error while deleting deployment", err)
 	}
-}
 
-func TestDelete(t *testing.T) {
-	res, err := resources.New(cfg)
+	// with foreground propagation the deployment is only removed once all of its dependents are gone
+	err = wait.For(conditions.New(res).ResourceDeleted(depActual), wait.WithTimeout(time.Minute*2))
 	if err != nil {
-		t.Fatalf("Error creating new resources object: %v", err)
+		t.Fatal("error while waiting for deployment deletion", err)
 	}
 
-	depActual := getDeployment("delete-test-dep-name")
-
-	err = res.Create(context.TODO(), depActual)
+	pods := &corev1.PodList{}
+	err = res.WithNamespace(namespace.Name).List(context.TODO(), pods, selector)
 	if err != nil {
-		t.Error("error while creating deployment", err)
+		t.Fatal("error while listing pods", err)
 	}
-
-	err = res.Delete(context.TODO(), depActual)
-	if err != nil {
-		t.Error("error while deleting deployment", err)
+	if len(pods.Items) != 0 {
+		t.Errorf("expected all pods to be deleted with their deployment, found %d", len(pods.Items))
 	}
 }
 
@@ -230,6 +1162,170 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListWithDefaultOptions(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	for _, name := range []string{"default-options-selected", "default-options-other"} {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace.Name, Labels: map[string]string{"suite": name}}}
+		if err := res.Create(context.TODO(), cm); err != nil {
+			t.Fatal("error while creating configmap", err)
+		}
+	}
+
+	res = res.WithNamespace(namespace.Name).WithDefaultListOptions(resources.WithLabelSelector("suite=default-options-selected"))
+	cms := &corev1.ConfigMapList{}
+	if err := res.List(context.TODO(), cms); err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	if len(cms.Items) != 1 || cms.Items[0].Name != "default-options-selected" {
+		t.Errorf("expected only the configmap matching the default label selector, got %d configmaps", len(cms.Items))
+	}
+
+	// options of the call take precedence over the default ones
+	if err := res.List(context.TODO(), cms, resources.WithLabelSelector("suite=default-options-other")); err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	if len(cms.Items) != 1 || cms.Items[0].Name != "default-options-other" {
+		t.Errorf("expected the label selector of the call to override the default one, got %d configmaps", len(cms.Items))
+	}
+}
+
+func TestDefaultOptionsDoNotLeakToSharedClient(t *testing.T) {
+	client, err := klient.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new client: %v", err)
+	}
+
+	for _, name := range []string{"shared-client-selected", "shared-client-other"} {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace.Name, Labels: map[string]string{"suite": name}}}
+		if err := client.Resources().Create(context.TODO(), cm); err != nil {
+			t.Fatal("error while creating configmap", err)
+		}
+	}
+
+	selected := client.Resources(namespace.Name).WithDefaultListOptions(resources.WithLabelSelector("suite=shared-client-selected"))
+	cms := &corev1.ConfigMapList{}
+	if err := selected.List(context.TODO(), cms); err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	if len(cms.Items) != 1 {
+		t.Errorf("expected only the configmap matching the default label selector, got %d configmaps", len(cms.Items))
+	}
+
+	// the default label selector is not applied to the resources returned by the shared client
+	if err := client.Resources(namespace.Name).List(context.TODO(), cms); err != nil {
+		t.Fatal("error while listing configmaps", err)
+	}
+	listed := make(map[string]bool)
+	for _, cm := range cms.Items {
+		listed[cm.Name] = true
+	}
+	if !listed["shared-client-selected"] || !listed["shared-client-other"] {
+		t.Errorf("expected the shared client to list both configmaps, got %v", listed)
+	}
+}
+
+// transientFailureTransport fails the first list requests of pods, the first with a refused
+// connection and the following ones with the given status code.
+type transientFailureTransport struct {
+	next     http.RoundTripper
+	failures int
+	status   int
+	attempts int32
+}
+
+func (tr *transientFailureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || !strings.HasSuffix(req.URL.Path, "/pods") {
+		return tr.next.RoundTrip(req)
+	}
+	attempt := int(atomic.AddInt32(&tr.attempts, 1))
+	switch {
+	case attempt > tr.failures:
+		return tr.next.RoundTrip(req)
+	case attempt == 1:
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	default:
+		return &http.Response{
+			StatusCode: tr.status,
+			Status:     http.StatusText(tr.status),
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}
+}
+
+func TestListWithRetry(t *testing.T) {
+	backoff := apimachinerywait.Backoff{Duration: 10 * time.Millisecond, Factor: 2, Steps: 5}
+	newRes := func(tr *transientFailureTransport) *resources.Resources {
+		config := rest.CopyConfig(cfg)
+		config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+			tr.next = rt
+			return tr
+		}
+		res, err := resources.New(config)
+		if err != nil {
+			t.Fatalf("Error creating new resources object: %v", err)
+		}
+		return res
+	}
+
+	tr := &transientFailureTransport{failures: 3, status: http.StatusServiceUnavailable}
+	pods := &corev1.PodList{}
+	if err := newRes(tr).ListWithRetry(context.TODO(), pods, backoff); err != nil {
+		t.Fatal("expected list to succeed after transient failures", err)
+	}
+	if attempts := atomic.LoadInt32(&tr.attempts); attempts != 4 {
+		t.Errorf("expected 4 list attempts, got %d", attempts)
+	}
+
+	tr = &transientFailureTransport{failures: 3, status: http.StatusForbidden}
+	err := newRes(tr).ListWithRetry(context.TODO(), pods, backoff)
+	if !apierrors.IsForbidden(err) {
+		t.Fatalf("expected forbidden error, got: %v", err)
+	}
+	if attempts := atomic.LoadInt32(&tr.attempts); attempts != 2 {
+		t.Errorf("expected non-retryable error to stop after 2 list attempts, got %d", attempts)
+	}
+
+	tr = &transientFailureTransport{failures: 10, status: http.StatusServiceUnavailable}
+	err = newRes(tr).ListWithRetry(context.TODO(), pods, backoff)
+	if !apierrors.IsServiceUnavailable(err) {
+		t.Fatalf("expected the error of the last attempt once the backoff is exhausted, got: %v", err)
+	}
+}
+
+func TestListByGVK(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	for _, name := range []string{"list-by-gvk-1", "list-by-gvk-2"} {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace.Name, Labels: map[string]string{"test": "list-by-gvk"}}}
+		if err := res.Create(context.TODO(), cm); err != nil {
+			t.Fatal("error while creating configmap", err)
+		}
+	}
+
+	gvk := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	objs, err := res.WithNamespace(namespace.Name).ListByGVK(context.TODO(), gvk, resources.WithLabelSelector("test=list-by-gvk"))
+	if err != nil {
+		t.Fatal("error while listing configmaps by gvk", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 configmaps, got %d", len(objs))
+	}
+	for _, obj := range objs {
+		if _, ok := obj.(*corev1.ConfigMap); !ok {
+			t.Errorf("expected a typed configmap, got %T", obj)
+		}
+	}
+}
+
 func TestPatch(t *testing.T) {
 	res, err := resources.New(cfg)
 	if err != nil {
@@ -263,6 +1359,75 @@ func TestPatch(t *testing.T) {
 	}
 }
 
+func TestPatchMerge(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "merge-patch-test", Namespace: namespace.Name},
+		Data:       map[string]string{"foo": "bar", "keep": "me"},
+	}
+	if err := res.Create(context.TODO(), cm); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+
+	mergePatch := []byte(`{"data":{"foo":"baz"}}`)
+	if err := res.Patch(context.TODO(), cm, k8s.Patch{PatchType: types.MergePatchType, Data: mergePatch}); err != nil {
+		t.Fatal("error while patching the configmap", err)
+	}
+
+	obj := &corev1.ConfigMap{}
+	if err := res.Get(context.TODO(), cm.Name, cm.Namespace, obj); err != nil {
+		t.Fatal("error while getting patched configmap", err)
+	}
+	if obj.Data["foo"] != "baz" || obj.Data["keep"] != "me" {
+		t.Errorf("merge patch not applied correctly, got data: %v", obj.Data)
+	}
+}
+
+func TestPatchStrategicMerge(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	deployment := getDeployment("strategic-patch-test")
+	if err := res.Create(context.TODO(), deployment); err != nil {
+		t.Fatal("error while creating deployment", err)
+	}
+
+	// containers are merged by name with a strategic merge patch instead of replacing the list
+	strategicPatch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []map[string]interface{}{{"name": "sidecar", "image": "busybox"}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("error while json marshalling", err)
+	}
+	if err := res.Patch(context.TODO(), deployment, k8s.Patch{PatchType: types.StrategicMergePatchType, Data: strategicPatch}); err != nil {
+		t.Fatal("error while patching the deployment", err)
+	}
+
+	obj := &appsv1.Deployment{}
+	if err := res.Get(context.TODO(), deployment.Name, deployment.Namespace, obj); err != nil {
+		t.Fatal("error while getting patched deployment", err)
+	}
+	names := []string{}
+	for _, c := range obj.Spec.Template.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	if len(names) != 2 {
+		t.Errorf("expected both the original and the patched containers, got: %v", names)
+	}
+}
+
 func TestPatchStatus(t *testing.T) {
 	res, err := resources.New(cfg)
 	if err != nil {
@@ -330,6 +1495,86 @@ func TestListAllPods(t *testing.T) {
 	t.Logf("pod list contains %d pods", len(pods.Items))
 }
 
+func TestEncodeYAML(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: encode-yaml
+  namespace: default
+  uid: 7d1c5c3e-0000-0000-0000-000000000000
+  resourceVersion: "42"
+data:
+  foo: bar
+`
+	cm := &corev1.ConfigMap{}
+	if err := decoder.DecodeString(manifest, cm, decoder.MutateLabels(map[string]string{"app": "encode"})); err != nil {
+		t.Fatal(err)
+	}
+	deployment := getDeployment("encode-yaml")
+
+	out, err := resources.EncodeYAML(cm, deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"uid:", "resourceVersion:", "creationTimestamp:", "status:"} {
+		if strings.Contains(string(out), field) {
+			t.Errorf("expected %s to be stripped, got:\n%s", field, out)
+		}
+	}
+	if cm.GetResourceVersion() != "42" {
+		t.Error("expected the encoded object to be left unchanged")
+	}
+
+	objects, err := decoder.DecodeAll(context.TODO(), bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 documents, got %d:\n%s", len(objects), out)
+	}
+	decoded, ok := objects[0].(*corev1.ConfigMap)
+	if !ok {
+		t.Fatalf("unexpected type returned not ConfigMap: %T", objects[0])
+	}
+	if decoded.Name != cm.Name || decoded.Labels["app"] != "encode" || decoded.Data["foo"] != "bar" {
+		t.Errorf("configmap did not round-trip, got: %+v", decoded)
+	}
+	if _, ok := objects[1].(*appsv1.Deployment); !ok {
+		t.Errorf("unexpected type returned not Deployment: %T", objects[1])
+	}
+}
+
+func TestGetAsJSON(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "get-as-json", Namespace: namespace.Name, Labels: map[string]string{"app": "golden"}},
+		Data:       map[string]string{"replicas": "3", "mode": "golden"},
+	}
+	if err := res.Create(context.TODO(), cm); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+
+	actual, err := res.GetAsJSON(context.TODO(), cm.Name, cm.Namespace, corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	if err != nil {
+		t.Fatal("error while getting the configmap as JSON", err)
+	}
+	golden, err := os.ReadFile(filepath.Join("testdata", "golden", "configmap.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := resources.NormalizeJSON(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("configmap does not match the golden file, expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
 func TestGetCRDs(t *testing.T) {
 	res, err := resources.New(cfg)
 	if err != nil {
@@ -370,6 +1615,79 @@ func TestGetCRDs(t *testing.T) {
 	}
 }
 
+func TestUpdateUnstructuredPreservesUnknownFields(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	// Register type for the API server.
+	e := gexe.New()
+	p := e.RunProc(`kubectl apply -f ./testdata/unstructuredExample/resourcedefinition.yaml`)
+	if p.Err() != nil {
+		t.Fatalf("Failed to register CRD: %v %v", p.Err(), p.Result())
+	}
+	// Sometimes CRDs need just a bit of time before being ready to use.
+	time.Sleep(5 * time.Second)
+
+	widget := &unstructured.Unstructured{}
+	if err := decoder.DecodeFile(os.DirFS("testdata/unstructuredExample"), "widget.yaml", widget, decoder.MutateNamespace(namespace.Name)); err != nil {
+		t.Fatal("error while decoding widget", err)
+	}
+	if err := res.Create(context.TODO(), widget); err != nil {
+		t.Fatal("error while creating widget", err)
+	}
+
+	if err := unstructured.SetNestedField(widget.Object, int64(2), "spec", "size"); err != nil {
+		t.Fatal(err)
+	}
+	if err := res.Update(context.TODO(), widget); err != nil {
+		t.Fatal("error while updating widget", err)
+	}
+
+	updated := &unstructured.Unstructured{}
+	updated.SetGroupVersionKind(widget.GroupVersionKind())
+	if err := res.Get(context.TODO(), widget.GetName(), widget.GetNamespace(), updated); err != nil {
+		t.Fatal("error while getting widget", err)
+	}
+	if size, _, _ := unstructured.NestedInt64(updated.Object, "spec", "size"); size != 2 {
+		t.Errorf("expected spec.size to be updated to 2, got %d", size)
+	}
+	if foo, _, _ := unstructured.NestedString(updated.Object, "spec", "foo", "bar"); foo != "baz" {
+		t.Errorf("expected unknown field spec.foo.bar to survive the update, got object: %v", updated.Object["spec"])
+	}
+}
+
+func TestRecordEvent(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "record-event-test", Namespace: namespace.Name}}
+	if err := res.Create(context.TODO(), cm); err != nil {
+		t.Fatal("error while creating configmap", err)
+	}
+	if err := res.RecordEvent(context.TODO(), cm, "TestStep", "configmap created by the test"); err != nil {
+		t.Fatal("error while recording event", err)
+	}
+
+	var events corev1.EventList
+	if err := res.WithNamespace(namespace.Name).List(context.TODO(), &events, resources.WithFieldSelector("involvedObject.name="+cm.Name)); err != nil {
+		t.Fatal("error while listing events", err)
+	}
+	if len(events.Items) != 1 {
+		t.Fatalf("expected 1 event for %s, got %d", cm.Name, len(events.Items))
+	}
+	event := events.Items[0]
+	if event.Reason != "TestStep" || event.Message != "configmap created by the test" {
+		t.Errorf("unexpected event reason %q and message %q", event.Reason, event.Message)
+	}
+	if event.InvolvedObject.Kind != "ConfigMap" || event.InvolvedObject.UID != cm.UID {
+		t.Errorf("unexpected involved object: %+v", event.InvolvedObject)
+	}
+}
+
 func TestExecInPod(t *testing.T) {
 	res, err := resources.New(cfg)
 	containerName := "nginx"
@@ -435,3 +1753,63 @@ func TestExecInPod(t *testing.T) {
 		t.Fatal("Couldn't find proper env")
 	}
 }
+
+func TestCopyToAndFromPod(t *testing.T) {
+	res, err := resources.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating new resources object: %v", err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-copy", Namespace: namespace.Name},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:    "busybox",
+			Image:   "busybox",
+			Command: []string{"sleep", "3600"},
+		}}},
+	}
+	if err := res.Create(context.TODO(), pod); err != nil {
+		t.Fatal("Error while creating pod resource", err)
+	}
+	err = wait.For(conditions.New(res).PodRunning(pod), wait.WithTimeout(time.Minute*5))
+	if err != nil {
+		t.Fatal("error while waiting for pod to run", err)
+	}
+
+	localDir := filepath.Join(t.TempDir(), "fixtures")
+	if err := os.MkdirAll(filepath.Join(localDir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(localDir, "script.sh"), []byte("echo fixture\n"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(localDir, "nested", "data.txt"), []byte("nested data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := res.CopyToPod(context.TODO(), namespace.Name, pod.Name, "busybox", localDir, "/tmp/fixtures"); err != nil {
+		t.Fatal("error while copying to pod", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	command := []string{"sh", "-c", "cat /tmp/fixtures/nested/data.txt && stat -c ' %a' /tmp/fixtures/script.sh"}
+	if err := res.ExecInPod(context.TODO(), namespace.Name, pod.Name, "busybox", command, &stdout, &stderr); err != nil {
+		t.Log(stderr.String())
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "nested data 750" {
+		t.Errorf("unexpected content or permissions of the copied files: %q", got)
+	}
+
+	copied := filepath.Join(t.TempDir(), "copied")
+	if err := res.CopyFromPod(context.TODO(), namespace.Name, pod.Name, "busybox", "/tmp/fixtures", copied); err != nil {
+		t.Fatal("error while copying from pod", err)
+	}
+	data, err := os.ReadFile(filepath.Join(copied, "nested", "data.txt"))
+	if err != nil || string(data) != "nested data" {
+		t.Errorf("unexpected content of the file copied from the pod: %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(copied, "script.sh")); err != nil || info.Mode().Perm() != 0o750 {
+		t.Errorf("expected the permissions of the file copied from the pod to be preserved, got %v, %v", info, err)
+	}
+}
@@ -19,6 +19,9 @@ package env
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 
 	klog "k8s.io/klog/v2"
@@ -76,7 +79,7 @@ func (a *action) runWithT(ctx context.Context, cfg *envconf.Config, t *testing.T
 	switch a.role {
 	case roleBeforeTest, roleAfterTest:
 		if cfg.DryRunMode() {
-			klog.V(2).Info("Skipping execution of roleBeforeTest and roleAfterTest due to framework being in dry-run mode")
+			klog.FromContext(ctx).V(2).Info("Skipping execution of roleBeforeTest and roleAfterTest due to framework being in dry-run mode")
 			return ctx, nil
 		}
 		for _, f := range a.testFuncs {
@@ -103,7 +106,7 @@ func (a *action) runWithFeature(ctx context.Context, cfg *envconf.Config, t *tes
 	switch a.role {
 	case roleBeforeFeature, roleAfterFeature:
 		if cfg.DryRunMode() {
-			klog.V(2).Info("Skipping execution of roleBeforeFeature and roleAfterFeature due to framework being in dry-run mode")
+			klog.FromContext(ctx).V(2).Info("Skipping execution of roleBeforeFeature and roleAfterFeature due to framework being in dry-run mode")
 			return ctx, nil
 		}
 		for _, f := range a.featureFuncs {
@@ -125,10 +128,10 @@ func (a *action) runWithFeature(ctx context.Context, cfg *envconf.Config, t *tes
 
 func (a *action) run(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
 	if cfg.DryRunMode() {
-		klog.V(2).InfoS("Skipping processing of action due to framework being in dry-run mode")
+		klog.FromContext(ctx).V(2).Info("Skipping processing of action due to framework being in dry-run mode")
 		return ctx, nil
 	}
-	for _, f := range a.funcs {
+	for i, f := range a.funcs {
 		if f == nil {
 			continue
 		}
@@ -136,9 +139,20 @@ func (a *action) run(ctx context.Context, cfg *envconf.Config) (context.Context,
 		var err error
 		ctx, err = f(ctx, cfg)
 		if err != nil {
-			return ctx, err
+			return ctx, fmt.Errorf("%s func %d of %d (%s): %w", a.role, i+1, len(a.funcs), funcName(f), err)
 		}
 	}
 
 	return ctx, nil
 }
+
+// funcName returns the name of the function f without its package path, such as
+// "envfuncs.CreateCluster.func1" for the closure returned by envfuncs.CreateCluster.
+func funcName(f interface{}) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
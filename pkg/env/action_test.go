@@ -18,6 +18,8 @@ package env
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
@@ -105,6 +107,37 @@ func TestAction_Run(t *testing.T) {
 	}
 }
 
+var errFailingSetup = errors.New("setup failed")
+
+func failingSetup(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+	return ctx, errFailingSetup
+}
+
+func TestAction_Run_ErrorPosition(t *testing.T) {
+	var calls []int
+	funcs := []types.EnvFunc{
+		func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+			calls = append(calls, 1)
+			return ctx, nil
+		},
+		failingSetup,
+		func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+			calls = append(calls, 3)
+			return ctx, nil
+		},
+	}
+	_, err := (&action{role: roleSetup, funcs: funcs}).run(context.TODO(), &envconf.Config{})
+	if !errors.Is(err, errFailingSetup) {
+		t.Fatalf("expected the error of the failing func, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "Setup func 2 of 3") || !strings.Contains(err.Error(), "env.failingSetup") {
+		t.Errorf("expected the error to name the position and name of the failing func, got: %v", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("expected the funcs following the failing one to be skipped, got calls %v", calls)
+	}
+}
+
 func TestActionRole_String(t *testing.T) {
 	tests := []struct {
 		name string
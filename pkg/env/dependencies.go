@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+// featureDependencies returns the names of the features f depends on, if any.
+func featureDependencies(f types.Feature) []string {
+	if df, ok := f.(types.DependentFeature); ok {
+		return df.DependsOn()
+	}
+	return nil
+}
+
+// orderFeatures sorts the features so that each of them comes after the features
+// it depends on. Features keep their relative order otherwise. An error is returned
+// if a dependency is not part of testFeatures or if the dependencies form a cycle.
+func orderFeatures(testFeatures []types.Feature) ([]types.Feature, error) {
+	byName := make(map[string][]int)
+	hasDependencies := false
+	for i, f := range testFeatures {
+		byName[f.Name()] = append(byName[f.Name()], i)
+		if len(featureDependencies(f)) > 0 {
+			hasDependencies = true
+		}
+	}
+	if !hasDependencies {
+		return testFeatures, nil
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(testFeatures))
+	ordered := make([]types.Feature, 0, len(testFeatures))
+	var path []string
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("feature dependency cycle detected: %s -> %s", strings.Join(path, " -> "), testFeatures[i].Name())
+		}
+		state[i] = visiting
+		path = append(path, testFeatures[i].Name())
+		for _, dep := range featureDependencies(testFeatures[i]) {
+			deps, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("feature %q depends on unknown feature %q", testFeatures[i].Name(), dep)
+			}
+			for _, j := range deps {
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		ordered = append(ordered, testFeatures[i])
+		return nil
+	}
+
+	for i := range testFeatures {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// featureResults tracks the outcome of the features tested by a single
+// Environment.Test or Environment.TestInParallel call.
+type featureResults struct {
+	mu       sync.Mutex
+	features []types.Feature
+	byName   map[string][]int
+	failed   []bool
+	done     []chan struct{}
+	results  []types.FeatureResult
+}
+
+func newFeatureResults(testFeatures []types.Feature) *featureResults {
+	r := &featureResults{
+		features: testFeatures,
+		byName:   make(map[string][]int),
+		failed:   make([]bool, len(testFeatures)),
+		done:     make([]chan struct{}, len(testFeatures)),
+		results:  make([]types.FeatureResult, len(testFeatures)),
+	}
+	for i, f := range testFeatures {
+		r.byName[f.Name()] = append(r.byName[f.Name()], i)
+		r.done[i] = make(chan struct{})
+	}
+	return r
+}
+
+// finish records the outcome of the feature at index i.
+func (r *featureResults) finish(i int, passed bool) {
+	r.mu.Lock()
+	r.failed[i] = !passed
+	r.mu.Unlock()
+	close(r.done[i])
+}
+
+// record stores the result of the feature at index i.
+func (r *featureResults) record(i int, result types.FeatureResult) {
+	r.mu.Lock()
+	r.results[i] = result
+	r.mu.Unlock()
+}
+
+// recorded returns the results stored for the features that were tested.
+func (r *featureResults) recorded() []types.FeatureResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var results []types.FeatureResult
+	for _, result := range r.results {
+		if result.Status != "" {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// failedDependency waits for the dependencies of the feature at index i to
+// finish and returns the name of the first one that did not succeed, if any.
+func (r *featureResults) failedDependency(i int) string {
+	for _, dep := range featureDependencies(r.features[i]) {
+		for _, j := range r.byName[dep] {
+			<-r.done[j]
+			r.mu.Lock()
+			failed := r.failed[j]
+			r.mu.Unlock()
+			if failed {
+				return dep
+			}
+		}
+	}
+	return ""
+}
+
+// skipFeature reports the feature as skipped for the given reason.
+func skipFeature(t *testing.T, featName string, err error) types.FeatureResult {
+	t.Helper()
+	t.Run(featName, func(t *testing.T) {
+		t.Skip(err.Error())
+	})
+	return types.FeatureResult{Name: featName, Status: types.FeatureSkipped, Err: err}
+}
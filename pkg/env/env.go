@@ -20,13 +20,19 @@ package env
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"runtime/debug"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	klog "k8s.io/klog/v2"
 
 	"sigs.k8s.io/e2e-framework/klient"
@@ -44,9 +50,47 @@ type (
 )
 
 type testEnv struct {
-	ctx     context.Context
-	cfg     *envconf.Config
-	actions []action
+	ctx           context.Context
+	cfg           *envconf.Config
+	actions       []action
+	defaultLabels types.Labels
+	logger        logr.Logger
+	finishErr     error
+	failFast      *failFastState
+	// sharedNamespace is the namespace set by WithSharedNamespace.
+	sharedNamespace string
+}
+
+// failFastState records the first feature that failed in an environment
+// created with WithFailFast. It is shared by the environments derived from it.
+type failFastState struct {
+	mu     sync.Mutex
+	failed string
+}
+
+// fail records featName as failed, unless another feature failed first.
+func (s *failFastState) fail(featName string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed == "" {
+		s.failed = featName
+	}
+}
+
+// skipReason returns the reason to skip the next features, if a feature failed.
+func (s *failFastState) skipReason() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed == "" {
+		return nil
+	}
+	return fmt.Errorf("skipping feature: fail fast after feature %q failed", s.failed)
 }
 
 // New creates a test environment with no config attached.
@@ -100,20 +144,22 @@ func NewWithContext(ctx context.Context, cfg *envconf.Config) (types.Environment
 	if cfg == nil {
 		return nil, fmt.Errorf("environment config is nil")
 	}
-	return &testEnv{ctx: ctx, cfg: cfg}, nil
+	return &testEnv{ctx: ctx, cfg: cfg, logger: klog.Background()}, nil
 }
 
 func newTestEnv() *testEnv {
 	return &testEnv{
-		ctx: context.Background(),
-		cfg: envconf.New(),
+		ctx:    context.Background(),
+		cfg:    envconf.New(),
+		logger: klog.Background(),
 	}
 }
 
 func newTestEnvWithParallel() *testEnv {
 	return &testEnv{
-		ctx: context.Background(),
-		cfg: envconf.New().WithParallelTestEnabled(),
+		ctx:    context.Background(),
+		cfg:    envconf.New().WithParallelTestEnabled(),
+		logger: klog.Background(),
 	}
 }
 
@@ -126,9 +172,13 @@ type ctxName string
 func newChildTestEnv(e *testEnv) *testEnv {
 	childCtx := context.WithValue(e.ctx, ctxName("parent"), fmt.Sprintf("%s", e.ctx))
 	return &testEnv{
-		ctx:     childCtx,
-		cfg:     e.deepCopyConfig(),
-		actions: append([]action{}, e.actions...),
+		ctx:             childCtx,
+		cfg:             e.deepCopyConfig(),
+		actions:         append([]action{}, e.actions...),
+		defaultLabels:   e.defaultLabels,
+		logger:          e.logger,
+		failFast:        e.failFast,
+		sharedNamespace: e.sharedNamespace,
 	}
 }
 
@@ -139,13 +189,64 @@ func (e *testEnv) WithContext(ctx context.Context) types.Environment {
 		panic("nil context") // this should never happen
 	}
 	env := &testEnv{
-		ctx: ctx,
-		cfg: e.cfg,
+		ctx:             ctx,
+		cfg:             e.cfg,
+		defaultLabels:   e.defaultLabels,
+		logger:          e.logger,
+		failFast:        e.failFast,
+		sharedNamespace: e.sharedNamespace,
 	}
 	env.actions = append(env.actions, e.actions...)
 	return env
 }
 
+// WithDefaultLabels registers a set of labels that every feature tested in
+// the environment inherits. The defaults are merged with the labels of each
+// feature before they are evaluated against the label filters, with the
+// feature's own labels overriding the defaults on key conflict.
+func (e *testEnv) WithDefaultLabels(labels types.Labels) types.Environment {
+	e.defaultLabels = labels
+	return e
+}
+
+// WithLogger sets the logger used by the environment to report the execution of
+// its actions, features and steps. The logger also gets injected into the context
+// handed to the env and step functions so that they can retrieve it using
+// klog.FromContext. By default, the klog global logger is used.
+func (e *testEnv) WithLogger(logger logr.Logger) types.Environment {
+	e.logger = logger
+	return e
+}
+
+// WithFailFast skips the features that remain to be tested, including the ones
+// of the subsequent Test and TestInParallel calls, once a feature tested in the
+// environment fails. The skipped features are reported as such, and the Finish
+// funcs are still executed. Unlike the --fail-fast flag, the teardowns of the
+// failed feature are executed.
+func (e *testEnv) WithFailFast() types.Environment {
+	if e.failFast == nil {
+		e.failFast = &failFastState{}
+	}
+	return e
+}
+
+// WithSharedNamespace runs every feature tested in the environment in the
+// namespace name, which cfg.Namespace() returns to each of them. The namespace
+// is created by Run once the Setup funcs executed, such as the one creating the
+// cluster, and deleted before the Finish funcs execute.
+//
+// Sharing a namespace saves creating and deleting one for each feature or test,
+// at the expense of the isolation between the features: the objects a feature
+// leaves behind are seen by the next ones, and the features creating objects of
+// the same name collide, notably when tested in parallel. Features sharing the
+// namespace should name their objects uniquely, for instance with
+// envconf.RandomName, and delete them in their teardowns.
+func (e *testEnv) WithSharedNamespace(name string) types.Environment {
+	e.sharedNamespace = name
+	e.cfg.WithNamespace(name)
+	return e
+}
+
 // Setup registers environment operations that are executed once
 // prior to the environment being ready and prior to any test.
 func (e *testEnv) Setup(funcs ...Func) types.Environment {
@@ -222,7 +323,7 @@ func (e *testEnv) processTestActions(ctx context.Context, t *testing.T, actions
 // processTestFeature is used to trigger the execution of the actual feature. This function wraps the entire
 // workflow of orchestrating the feature execution be running the action configured by BeforeEachFeature /
 // AfterEachFeature.
-func (e *testEnv) processTestFeature(ctx context.Context, t *testing.T, featureName string, feature types.Feature) context.Context {
+func (e *testEnv) processTestFeature(ctx context.Context, t *testing.T, featureName string, feature types.Feature) (context.Context, types.FeatureResult) {
 	t.Helper()
 	skipped, message := e.requireFeatureProcessing(feature)
 	if skipped {
@@ -232,10 +333,10 @@ func (e *testEnv) processTestFeature(ctx context.Context, t *testing.T, featureN
 	ctx = e.processFeatureActions(ctx, t, feature, e.getBeforeFeatureActions())
 
 	// execute feature test
-	ctx = e.execFeature(ctx, t, featureName, feature)
+	ctx, result := e.execFeature(ctx, t, featureName, feature)
 
 	// execute afterEachFeature actions
-	return e.processFeatureActions(ctx, t, feature, e.getAfterFeatureActions())
+	return e.processFeatureActions(ctx, t, feature, e.getAfterFeatureActions()), result
 }
 
 // processFeatureActions is used to run a series of feature action that were configured as
@@ -259,18 +360,19 @@ func (e *testEnv) processFeatureActions(ctx context.Context, t *testing.T, featu
 //
 // In case if the parallel run of test features are enabled, this function will invoke the processTestFeature
 // as a go-routine to get them to run in parallel
-func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallelRun bool, testFeatures ...types.Feature) context.Context {
+func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallelRun bool, testFeatures ...types.Feature) types.Result {
 	t.Helper()
 	dedicatedTestEnv := newChildTestEnv(e)
 	if dedicatedTestEnv.cfg.DryRunMode() {
-		klog.V(2).Info("e2e-framework is being run in dry-run mode. This will skip all the before/after step functions configured around your test assessments and features")
+		e.logger.V(2).Info("e2e-framework is being run in dry-run mode. This will skip all the before/after step functions configured around your test assessments and features")
 	}
 	if ctx == nil {
 		panic("nil context") // this should never happen
 	}
+	ctx = klog.NewContext(ctx, e.logger)
 	if len(testFeatures) == 0 {
 		t.Log("No test testFeatures provided, skipping test")
-		return ctx
+		return types.Result{Context: ctx}
 	}
 	beforeTestActions := dedicatedTestEnv.getBeforeTestActions()
 	afterTestActions := dedicatedTestEnv.getAfterTestActions()
@@ -278,9 +380,15 @@ func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallel
 	runInParallel := dedicatedTestEnv.cfg.ParallelTestEnabled() && enableParallelRun
 
 	if runInParallel {
-		klog.V(4).Info("Running test features in parallel")
+		e.logger.V(4).Info("Running test features in parallel")
 	}
 
+	testFeatures, err := orderFeatures(testFeatures)
+	if err != nil {
+		t.Fatalf("failed to order features: %s", err)
+	}
+	results := newFeatureResults(testFeatures)
+
 	ctx = dedicatedTestEnv.processTestActions(ctx, t, beforeTestActions)
 
 	var wg sync.WaitGroup
@@ -293,12 +401,44 @@ func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallel
 		}
 		if runInParallel {
 			wg.Add(1)
-			go func(ctx context.Context, w *sync.WaitGroup, featName string, f types.Feature) {
+			go func(ctx context.Context, w *sync.WaitGroup, i int, featName string, f types.Feature) {
 				defer w.Done()
-				_ = featureTestEnv.processTestFeature(ctx, t, featName, f)
-			}(ctx, &wg, featName, featureCopy)
+				passed := false
+				defer func() { results.finish(i, passed) }()
+				if dep := results.failedDependency(i); dep != "" {
+					results.record(i, skipFeature(t, featName, fmt.Errorf("skipping feature: dependency %q did not succeed", dep)))
+					return
+				}
+				if reason := featureTestEnv.failFast.skipReason(); reason != nil {
+					results.record(i, skipFeature(t, featName, reason))
+					return
+				}
+				_, result := featureTestEnv.processTestFeature(ctx, t, featName, f)
+				results.record(i, result)
+				// a feature skipped by its own SkipIf or t.Skip did not run, so its dependents are skipped as well
+				passed = result.Status == types.FeaturePassed
+				if result.Status == types.FeatureFailed {
+					featureTestEnv.failFast.fail(featName)
+				}
+			}(ctx, &wg, i, featName, featureCopy)
 		} else {
-			ctx = featureTestEnv.processTestFeature(ctx, t, featName, featureCopy)
+			if dep := results.failedDependency(i); dep != "" {
+				results.record(i, skipFeature(t, featName, fmt.Errorf("skipping feature: dependency %q did not succeed", dep)))
+				results.finish(i, false)
+				continue
+			}
+			if reason := featureTestEnv.failFast.skipReason(); reason != nil {
+				results.record(i, skipFeature(t, featName, reason))
+				results.finish(i, false)
+				continue
+			}
+			var result types.FeatureResult
+			ctx, result = featureTestEnv.processTestFeature(ctx, t, featName, featureCopy)
+			results.record(i, result)
+			results.finish(i, result.Status == types.FeaturePassed)
+			if result.Status == types.FeatureFailed {
+				featureTestEnv.failFast.fail(featName)
+			}
 			// In case if the feature under test has failed, skip reset of the features
 			// that are part of the same test
 			if featureTestEnv.cfg.FailFast() && t.Failed() {
@@ -309,7 +449,8 @@ func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallel
 	if runInParallel {
 		wg.Wait()
 	}
-	return dedicatedTestEnv.processTestActions(ctx, t, afterTestActions)
+	ctx = dedicatedTestEnv.processTestActions(ctx, t, afterTestActions)
+	return types.Result{Context: ctx, Features: results.recorded()}
 }
 
 // TestInParallel executes a series a feature tests from within a
@@ -332,7 +473,7 @@ func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallel
 // in BeforeTest and AfterTest actions
 func (e *testEnv) TestInParallel(t *testing.T, testFeatures ...types.Feature) context.Context {
 	t.Helper()
-	return e.processTests(e.ctx, t, true, testFeatures...)
+	return e.processTests(e.ctx, t, true, testFeatures...).Context
 }
 
 // Test executes a feature test from within a TestXXX function.
@@ -348,6 +489,15 @@ func (e *testEnv) TestInParallel(t *testing.T, testFeatures ...types.Feature) co
 // BeforeTest and AfterTest operations are executed before and after
 // the feature is tested respectively.
 func (e *testEnv) Test(t *testing.T, testFeatures ...types.Feature) context.Context {
+	t.Helper()
+	return e.processTests(e.ctx, t, false, testFeatures...).Context
+}
+
+// TestWithResult executes a feature test from within a TestXXX function,
+// the same way Test does. The returned Result holds the context surfaced
+// by the features and whether each of them passed, failed or was skipped,
+// which can be used to build custom reports of the test.
+func (e *testEnv) TestWithResult(t *testing.T, testFeatures ...types.Feature) types.Result {
 	t.Helper()
 	return e.processTests(e.ctx, t, false, testFeatures...)
 }
@@ -368,13 +518,32 @@ func (e *testEnv) Finish(funcs ...Func) types.Environment {
 // package.  This method will all Env.Setup operations prior to
 // starting the tests and run all Env.Finish operations after
 // before completing the suite.
+//
+// The returned exit code, meant to be passed to os.Exit, is the
+// one returned by m.Run(), unless a Setup operation fails, the
+// test suite panics or a Finish operation fails, in which case
+// it is non-zero even if all the tests passed. The errors of the
+// Finish operations are available from FinishError.
 func (e *testEnv) Run(m *testing.M) (exitCode int) {
+	return e.run(m.Run)
+}
+
+// FinishError returns the errors returned by the Finish operations
+// during the last call to Run, joined together, or nil.
+func (e *testEnv) FinishError() error {
+	return e.finishErr
+}
+
+// run executes the Setup operations, the tests with runTests and
+// the Finish operations in that order.
+func (e *testEnv) run(runTests func() int) (exitCode int) {
 	e.panicOnMissingContext()
-	ctx := e.ctx
+	ctx := klog.NewContext(e.ctx, e.logger)
 
 	setups := e.getSetupActions()
 	// fail fast on setup, upon err exit
 	var err error
+	var sharedNamespaceCreated bool
 
 	defer func() {
 		// Recover and see if the panic handler is disabled. If it is disabled, panic and stop the workflow.
@@ -384,7 +553,7 @@ func (e *testEnv) Run(m *testing.M) (exitCode int) {
 			if e.cfg.DisableGracefulTeardown() {
 				panic(rErr)
 			}
-			klog.Errorf("Recovering from panic and running finish actions: %s, stack: %s", rErr, string(debug.Stack()))
+			e.logger.Error(fmt.Errorf("%v", rErr), "Recovering from panic and running finish actions", "stack", string(debug.Stack()))
 			// Set this exit code value to non 0 to indicate that the test suite has failed
 			// Not doing this will mark the test suite as passed even though there was a panic
 			exitCode = 1
@@ -393,26 +562,80 @@ func (e *testEnv) Run(m *testing.M) (exitCode int) {
 		finishes := e.getFinishActions()
 		// attempt to gracefully clean up.
 		// Upon error, log and continue.
+		var finishErrs []error
+		if sharedNamespaceCreated {
+			// deleted ahead of the Finish funcs, which may destroy the cluster
+			if err := e.deleteSharedNamespace(ctx); err != nil {
+				e.logger.V(2).Error(err, "Cleanup failed", "namespace", e.sharedNamespace)
+				finishErrs = append(finishErrs, err)
+			}
+		}
 		for _, fin := range finishes {
+			e.logger.V(4).Info("Running action", "action", fin.role)
 			// context passed down to each finish step
 			if ctx, err = fin.run(ctx, e.cfg); err != nil {
-				klog.V(2).ErrorS(err, "Cleanup failed", "action", fin.role)
+				e.logger.V(2).Error(err, "Cleanup failed", "action", fin.role)
+				finishErrs = append(finishErrs, err)
 			}
 		}
 		e.ctx = ctx
+		e.finishErr = errors.Join(finishErrs...)
+		// A failed cleanup, such as a cluster that could not be destroyed, fails
+		// the test suite even if all the tests passed.
+		if e.finishErr != nil && exitCode == 0 {
+			exitCode = 1
+		}
 	}()
 
 	for _, setup := range setups {
+		e.logger.V(4).Info("Running action", "action", setup.role)
 		// context passed down to each setup
 		if ctx, err = setup.run(ctx, e.cfg); err != nil {
-			klog.Errorf("%s failure: %s", setup.role, err)
+			e.logger.Error(err, "Action failed", "action", setup.role)
+			return 1
+		}
+	}
+	if e.sharedNamespace != "" {
+		if err := e.createSharedNamespace(ctx); err != nil {
+			e.logger.Error(err, "Action failed", "namespace", e.sharedNamespace)
 			return 1
 		}
+		sharedNamespaceCreated = true
 	}
 	e.ctx = ctx
 
 	// Execute the test suite
-	return m.Run()
+	return runTests()
+}
+
+// createSharedNamespace creates the namespace set by WithSharedNamespace, and
+// sets it again as the namespace of the config, in case a Setup func changed it.
+func (e *testEnv) createSharedNamespace(ctx context.Context) error {
+	client, err := e.cfg.NewClient()
+	if err != nil {
+		return fmt.Errorf("create shared namespace: %w", err)
+	}
+	e.logger.V(2).Info("Creating shared namespace", "namespace", e.sharedNamespace)
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: e.sharedNamespace}}
+	if err := client.Resources().Create(ctx, namespace); err != nil {
+		return fmt.Errorf("create shared namespace: %w", err)
+	}
+	e.cfg.WithNamespace(e.sharedNamespace)
+	return nil
+}
+
+// deleteSharedNamespace deletes the namespace set by WithSharedNamespace.
+func (e *testEnv) deleteSharedNamespace(ctx context.Context) error {
+	client, err := e.cfg.NewClient()
+	if err != nil {
+		return fmt.Errorf("delete shared namespace: %w", err)
+	}
+	e.logger.V(2).Info("Deleting shared namespace", "namespace", e.sharedNamespace)
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: e.sharedNamespace}}
+	if err := client.Resources().Delete(ctx, namespace); err != nil {
+		return fmt.Errorf("delete shared namespace: %w", err)
+	}
+	return nil
 }
 
 func (e *testEnv) getActionsByRole(r actionRole) []action {
@@ -460,30 +683,112 @@ func (e *testEnv) getFinishActions() []action {
 	return finishAction
 }
 
-func (e *testEnv) executeSteps(ctx context.Context, t *testing.T, steps []types.Step) context.Context {
+func (e *testEnv) executeSteps(ctx context.Context, t *testing.T, steps []types.Step, timeout time.Duration) context.Context {
 	t.Helper()
 	if e.cfg.DryRunMode() {
 		return ctx
 	}
 	for _, setup := range steps {
-		ctx = setup.Func()(ctx, t, e.cfg)
+		e.logger.V(4).Info("Running step", "step", setup.Name(), "level", setup.Level())
+		ctx = e.executeStep(ctx, t, setup, timeout)
 	}
 	return ctx
 }
 
-func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string, f types.Feature) context.Context {
+// executeStep runs step, bounded by timeout if not zero. A panic of the step fails
+// the test of the step, as t.Fatal does, so that the teardowns and the following
+// features still run along with the Finish operations, instead of the panic
+// aborting the test binary. Panics are propagated when the graceful teardown is
+// disabled.
+func (e *testEnv) executeStep(ctx context.Context, t *testing.T, step types.Step, timeout time.Duration) context.Context {
 	t.Helper()
+	defer func() {
+		if rErr := recover(); rErr != nil {
+			if e.cfg.DisableGracefulTeardown() {
+				panic(rErr)
+			}
+			t.Fatalf("step %q panicked: %v\n%s", step.Name(), rErr, debug.Stack())
+		}
+	}()
+	if timeout > 0 {
+		return e.executeStepWithTimeout(ctx, t, step, timeout)
+	}
+	return step.Func()(ctx, t, e.cfg)
+}
+
+// executeStepWithTimeout runs step with a context expiring after timeout. The
+// values of the context returned by the step are surfaced to the next steps,
+// without the deadline of the step.
+func (e *testEnv) executeStepWithTimeout(ctx context.Context, t *testing.T, step types.Step, timeout time.Duration) context.Context {
+	t.Helper()
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out := step.Func()(stepCtx, t, e.cfg)
+	if out == nil || out == stepCtx {
+		return ctx
+	}
+	return &stepValuesContext{Context: ctx, values: out}
+}
+
+// stepValuesContext carries the values of the context returned by a step run
+// with a timeout, while its deadline and cancellation are the ones of the
+// context the step was given.
+type stepValuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c *stepValuesContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// stepTimeout returns the maximum duration of each step of f, if any.
+func stepTimeout(f types.Feature) time.Duration {
+	if tf, ok := f.(types.TimedFeature); ok {
+		return tf.StepTimeout()
+	}
+	return 0
+}
+
+func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string, f types.Feature) (context.Context, types.FeatureResult) {
+	t.Helper()
+	var featureT *testing.T
+	var skipReason string
+	var failedAssessments []string
+	start := time.Now()
 	// feature-level subtest
-	t.Run(featName, func(newT *testing.T) {
+	passed := t.Run(featName, func(newT *testing.T) {
 		newT.Helper()
+		featureT = newT
+		e.logger.V(2).Info("Running feature", "feature", featName)
 
 		if fDescription, ok := f.(types.DescribableFeature); ok && fDescription.Description() != "" {
 			t.Logf("Processing Feature: %s", fDescription.Description())
 		}
 
+		if skip, reason := e.requireSkipConditions(ctx, f); skip {
+			skipReason = reason
+			newT.Skip(reason)
+		}
+
+		// seed the context of the feature before its first step, including the teardowns that always run
+		if cf, ok := f.(types.ContextualFeature); ok {
+			for _, seed := range cf.ContextFuncs() {
+				ctx = seed(ctx)
+			}
+		}
+
+		// teardowns that must always run are deferred, so that they also run when the
+		// feature is stopped early by a failed setup or assessment
+		timeout := stepTimeout(f)
+		defer func() {
+			alwaysTeardowns := features.GetStepsByLevel(f.Steps(), types.LevelTeardownAlways)
+			ctx = e.executeSteps(ctx, newT, alwaysTeardowns, timeout)
+		}()
+
 		// setups run at feature-level
 		setups := features.GetStepsByLevel(f.Steps(), types.LevelSetup)
-		ctx = e.executeSteps(ctx, newT, setups)
+		ctx = e.executeSteps(ctx, newT, setups, timeout)
 
 		// assessments run as feature/assessment sub level
 		assessments := features.GetStepsByLevel(f.Steps(), types.LevelAssess)
@@ -500,7 +805,7 @@ func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string
 			// shouldFailNow catches whether t.FailNow() is called in the assessment.
 			// If it is, we won't proceed with the next assessment.
 			var shouldFailNow bool
-			newT.Run(assessName, func(internalT *testing.T) {
+			assessPassed := newT.Run(assessName, func(internalT *testing.T) {
 				internalT.Helper()
 				skipped, message := e.requireAssessmentProcessing(assess, i+1)
 				if skipped {
@@ -509,10 +814,13 @@ func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string
 				// Set shouldFailNow to true before actually running the assessment, because if the assessment
 				// calls t.FailNow(), the function will be abruptly stopped in the middle of `e.executeSteps()`.
 				shouldFailNow = true
-				ctx = e.executeSteps(ctx, internalT, []types.Step{assess})
+				ctx = e.executeSteps(ctx, internalT, []types.Step{assess}, timeout)
 				// If we reach this point, it means the assessment did not call t.FailNow().
 				shouldFailNow = false
 			})
+			if !assessPassed {
+				failedAssessments = append(failedAssessments, assessName)
+			}
 			// Check if the Test assessment under question performed either 2 things:
 			// - a t.FailNow() invocation
 			// - a `t.Fail()` or `t.Failed()` invocation
@@ -533,17 +841,63 @@ func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string
 
 		// teardowns run at feature-level
 		teardowns := features.GetStepsByLevel(f.Steps(), types.LevelTeardown)
-		ctx = e.executeSteps(ctx, newT, teardowns)
+		ctx = e.executeSteps(ctx, newT, teardowns, timeout)
 	})
 
-	return ctx
+	result := types.FeatureResult{Name: featName, Status: types.FeaturePassed, Duration: time.Since(start)}
+	switch {
+	case !passed:
+		result.Status = types.FeatureFailed
+		if len(failedAssessments) > 0 {
+			result.Err = fmt.Errorf("failed assessments: %s", strings.Join(failedAssessments, ", "))
+		} else {
+			result.Err = errors.New("a setup or teardown step failed")
+		}
+	case featureT != nil && featureT.Skipped():
+		result.Status = types.FeatureSkipped
+		if skipReason != "" {
+			result.Err = errors.New(skipReason)
+		}
+	}
+	return ctx, result
+}
+
+// requireSkipConditions evaluates the skip conditions registered on the feature, if any,
+// and returns the reason given by the first one requesting the feature to be skipped.
+func (e *testEnv) requireSkipConditions(ctx context.Context, f types.Feature) (skip bool, reason string) {
+	sf, ok := f.(types.SkippableFeature)
+	if !ok || e.cfg.DryRunMode() {
+		return false, ""
+	}
+	for _, cond := range sf.SkipConditions() {
+		if skip, reason := cond(ctx, e.cfg); skip {
+			return true, reason
+		}
+	}
+	return false, ""
 }
 
 // requireFeatureProcessing is a wrapper around the requireProcessing function to process the feature level validation
 func (e *testEnv) requireFeatureProcessing(f types.Feature) (skip bool, message string) {
 	requiredRegexp := e.cfg.FeatureRegex()
 	skipRegexp := e.cfg.SkipFeatureRegex()
-	return e.requireProcessing("feature", f.Name(), requiredRegexp, skipRegexp, f.Labels())
+	return e.requireProcessing("feature", f.Name(), requiredRegexp, skipRegexp, e.featureLabels(f))
+}
+
+// featureLabels returns the labels of the feature merged on top of the default
+// labels registered for the environment.
+func (e *testEnv) featureLabels(f types.Feature) types.Labels {
+	if len(e.defaultLabels) == 0 {
+		return f.Labels()
+	}
+	labels := make(types.Labels, len(e.defaultLabels))
+	for k, vals := range e.defaultLabels {
+		labels[k] = vals
+	}
+	for k, vals := range f.Labels() {
+		labels[k] = vals
+	}
+	return labels
 }
 
 // requireAssessmentProcessing is a wrapper around the requireProcessing function to process the Assessment level validation
@@ -672,5 +1026,6 @@ func deepCopyFeature(f types.Feature) types.Feature {
 	for _, step := range f.Steps() {
 		fcopy = fcopy.WithStep(step.Name(), step.Level(), nil)
 	}
+	fcopy = fcopy.DependsOn(featureDependencies(f)...).WithStepTimeout(stepTimeout(f))
 	return fcopy.Feature()
 }
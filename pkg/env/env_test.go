@@ -18,10 +18,26 @@ package env
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr/funcr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	klog "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/klient"
 	"sigs.k8s.io/e2e-framework/pkg/types"
 
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
@@ -635,6 +651,29 @@ func TestEnv_Test(t *testing.T) {
 				return
 			},
 		},
+		{
+			name: "with default labels",
+			ctx:  context.TODO(),
+			expected: []string{
+				"test-feat-1",
+			},
+			setup: func(ctx context.Context, t *testing.T) (val []string) {
+				env := NewWithConfig(envconf.New().WithLabels(map[string][]string{"suite": {"smoke"}}))
+				env.WithDefaultLabels(types.Labels{"suite": {"smoke"}})
+
+				f1 := features.New("test-feat").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					val = append(val, "test-feat-1")
+					return ctx
+				})
+				f2 := features.New("test-feat").
+					WithLabel("suite", "regression").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					val = append(val, "test-feat-2")
+					return ctx
+				})
+				_ = env.Test(t, f1.Feature(), f2.Feature())
+				return
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -652,6 +691,537 @@ func TestEnv_Test(t *testing.T) {
 	}
 }
 
+func TestEnv_WithLogger(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	logger := funcr.New(func(prefix, args string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: 4})
+
+	env := newTestEnv()
+	env.WithLogger(logger)
+	f := features.New("logged-feature").
+		Setup(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			klog.FromContext(ctx).Info("message from setup")
+			return ctx
+		}).
+		Assess("logged-assessment", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+
+	expected := []string{
+		`"msg"="Running feature" "feature"="logged-feature"`,
+		`"msg"="Running step" "step"="logged-feature-setup" "level"="Setup"`,
+		`"msg"="message from setup"`,
+		`"msg"="Running step" "step"="logged-assessment" "level"="Assess"`,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, exp := range expected {
+		found := false
+		for _, line := range lines {
+			if strings.Contains(line, exp) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected log line %s not found in:\n%s", exp, strings.Join(lines, "\n"))
+		}
+	}
+}
+
+func TestEnv_FeatureDependencies(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	newFeature := func(name string, deps ...string) types.Feature {
+		return features.New(name).DependsOn(deps...).
+			Assess("record", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, name)
+				return ctx
+			}).Feature()
+	}
+	a := newFeature("A")
+	b := newFeature("B", "A")
+	c := newFeature("C", "B")
+
+	t.Run("ordered sequentially", func(t *testing.T) {
+		order = nil
+		_ = newTestEnv().Test(t, c, b, a)
+		if strings.Join(order, ",") != "A,B,C" {
+			t.Errorf("expected features to run in dependency order A,B,C, got %v", order)
+		}
+	})
+
+	t.Run("ordered in parallel", func(t *testing.T) {
+		order = nil
+		_ = NewParallel().TestInParallel(t, c, b, a)
+		if strings.Join(order, ",") != "A,B,C" {
+			t.Errorf("expected features to run in dependency order A,B,C, got %v", order)
+		}
+	})
+
+	t.Run("failed dependency skips dependents", func(t *testing.T) {
+		ordered, err := orderFeatures([]types.Feature{c, b, a})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results := newFeatureResults(ordered)
+		results.finish(0, false)
+		if dep := results.failedDependency(1); dep != "A" {
+			t.Errorf("expected B to be skipped because of A, got %q", dep)
+		}
+		results.finish(1, false)
+		if dep := results.failedDependency(2); dep != "B" {
+			t.Errorf("expected C to be skipped because of B, got %q", dep)
+		}
+	})
+
+	t.Run("skipped dependency skips dependents", func(t *testing.T) {
+		order = nil
+		skipped := features.New("A").
+			SkipIf(func(context.Context, *envconf.Config) (bool, string) {
+				return true, "prerequisite not met"
+			}).
+			Assess("record", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				order = append(order, "A")
+				return ctx
+			}).Feature()
+		result := newTestEnv().TestWithResult(t, c, b, skipped)
+		if len(order) != 0 {
+			t.Errorf("expected no feature to be assessed, got %v", order)
+		}
+		for _, feature := range result.Features {
+			if feature.Status != types.FeatureSkipped {
+				t.Errorf("expected feature %s to be skipped, got %s", feature.Name, feature.Status)
+			}
+		}
+		if len(result.Features) != 3 {
+			t.Errorf("expected 3 feature results, got %d", len(result.Features))
+		}
+	})
+
+	t.Run("cycle detected", func(t *testing.T) {
+		_, err := orderFeatures([]types.Feature{newFeature("A", "C"), b, c})
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Errorf("expected a dependency cycle error, got %v", err)
+		}
+	})
+
+	t.Run("unknown dependency", func(t *testing.T) {
+		_, err := orderFeatures([]types.Feature{b})
+		if err == nil || !strings.Contains(err.Error(), "unknown feature") {
+			t.Errorf("expected an unknown dependency error, got %v", err)
+		}
+	})
+}
+
+func TestEnv_FailedDependencySkipsDependents(t *testing.T) {
+	// The prerequisite fails, so it is run in a separate process to
+	// keep this test from failing.
+	if os.Getenv("E2E_FRAMEWORK_FAILED_DEPENDENCY_HELPER") == "1" {
+		a := features.New("A").
+			Assess("fails", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				t.Error("assessment failed")
+				return ctx
+			}).Feature()
+		newDependent := func(name, dep string) types.Feature {
+			return features.New(name).DependsOn(dep).
+				Assess("runs", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					fmt.Printf("%s assessed\n", name)
+					return ctx
+				}).Feature()
+		}
+		result := newTestEnv().TestWithResult(t, newDependent("C", "B"), newDependent("B", "A"), a)
+		for _, feature := range result.Features {
+			fmt.Printf("result %s=%s\n", feature.Name, feature.Status)
+		}
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEnv_FailedDependencySkipsDependents$", "-test.v")
+	cmd.Env = append(os.Environ(), "E2E_FRAMEWORK_FAILED_DEPENDENCY_HELPER=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected the prerequisite to fail, got output:\n%s", out)
+	}
+	for _, expected := range []string{"result A=failed", "result B=skipped", "result C=skipped"} {
+		if !strings.Contains(string(out), expected) {
+			t.Errorf("expected output to contain %q, got output:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(string(out), "assessed") {
+		t.Errorf("expected the dependents of the failed feature not to be assessed, got output:\n%s", out)
+	}
+}
+
+func TestEnv_RunFinishError(t *testing.T) {
+	finishErr := errors.New("cluster could not be destroyed")
+	env := newTestEnv()
+	env.Finish(
+		func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			return ctx, finishErr
+		},
+		func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			return ctx, nil
+		},
+	)
+	f := features.New("passing-feature").
+		Assess("passes", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			return ctx
+		})
+
+	exitCode := env.run(func() int {
+		_ = env.Test(t, f.Feature())
+		return 0
+	})
+	if exitCode == 0 {
+		t.Error("expected a non-zero exit code when a finish func fails")
+	}
+	if !errors.Is(env.FinishError(), finishErr) {
+		t.Errorf("expected finish error %v, got %v", finishErr, env.FinishError())
+	}
+
+	env = newTestEnv()
+	if exitCode := env.run(func() int { return 0 }); exitCode != 0 || env.FinishError() != nil {
+		t.Errorf("expected a zero exit code and no finish error, got %d and %v", exitCode, env.FinishError())
+	}
+}
+
+func TestEnv_SkipIf(t *testing.T) {
+	// fake API server only serving the discovery of the core group
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api":
+			_ = json.NewEncoder(w).Encode(metav1.APIVersions{TypeMeta: metav1.TypeMeta{Kind: "APIVersions"}, Versions: []string{"v1"}})
+		case "/apis":
+			_ = json.NewEncoder(w).Encode(metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client, err := klient.New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requireAPIGroup := func(group string) types.SkipFunc {
+		return func(_ context.Context, cfg *envconf.Config) (bool, string) {
+			dc, err := discovery.NewDiscoveryClientForConfig(cfg.Client().RESTConfig())
+			if err != nil {
+				return true, err.Error()
+			}
+			groups, err := dc.ServerGroups()
+			if err != nil {
+				return true, err.Error()
+			}
+			for _, g := range groups.Groups {
+				if g.Name == group {
+					return false, ""
+				}
+			}
+			return true, fmt.Sprintf("API group %s is not available", group)
+		}
+	}
+
+	var executed []string
+	newFeature := func(name, group string) types.Feature {
+		return features.New(name).
+			SkipIf(requireAPIGroup(group)).
+			Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				executed = append(executed, name)
+				return ctx
+			}).Feature()
+	}
+
+	env := newTestEnv()
+	env.cfg.WithClient(client)
+	_ = env.Test(t, newFeature("missing-group", "example.e2e-framework.io"), newFeature("core-group", ""))
+	if len(executed) != 1 || executed[0] != "core-group" {
+		t.Errorf("expected only the feature with an available API group to run, got %v", executed)
+	}
+}
+
+func TestEnv_WithSharedNamespace(t *testing.T) {
+	// fake API server only serving the creation and deletion of namespaces
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api":
+			_ = json.NewEncoder(w).Encode(metav1.APIVersions{TypeMeta: metav1.TypeMeta{Kind: "APIVersions"}, Versions: []string{"v1"}})
+		case r.URL.Path == "/apis":
+			_ = json.NewEncoder(w).Encode(metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}})
+		case r.URL.Path == "/api/v1":
+			_ = json.NewEncoder(w).Encode(metav1.APIResourceList{
+				TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{{Name: "namespaces", Kind: "Namespace", Verbs: metav1.Verbs{"create", "delete"}}},
+			})
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces"):
+			mu.Lock()
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]interface{}{"name": "shared"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client, err := klient.New(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var namespaces []string
+	newFeature := func(name string) types.Feature {
+		return features.New(name).
+			Assess("assess", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+				namespaces = append(namespaces, cfg.Namespace())
+				return ctx
+			}).Feature()
+	}
+
+	env := newTestEnv()
+	env.cfg.WithClient(client)
+	env.WithSharedNamespace("shared")
+	exitCode := env.run(func() int {
+		mu.Lock()
+		created := append([]string{}, requests...)
+		mu.Unlock()
+		if len(created) != 1 || created[0] != "POST /api/v1/namespaces" {
+			t.Errorf("expected the shared namespace to be created before the tests, got %v", created)
+		}
+		_ = env.Test(t, newFeature("first"))
+		_ = env.Test(t, newFeature("second"))
+		return 0
+	})
+	if exitCode != 0 {
+		t.Fatalf("unexpected exit code %d: %v", exitCode, env.FinishError())
+	}
+	if len(namespaces) != 2 || namespaces[0] != "shared" || namespaces[1] != "shared" {
+		t.Errorf("expected both features to observe the shared namespace, got %v", namespaces)
+	}
+	if len(requests) != 2 || requests[1] != "DELETE /api/v1/namespaces/shared" {
+		t.Errorf("expected the shared namespace to be deleted after the tests, got %v", requests)
+	}
+}
+
+func TestEnv_TestWithResult(t *testing.T) {
+	type ctxKey struct{}
+	passing := features.New("passing").
+		Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			return context.WithValue(ctx, ctxKey{}, "passing")
+		}).Feature()
+	skipped := features.New("skipped").
+		SkipIf(func(context.Context, *envconf.Config) (bool, string) {
+			return true, "not supported"
+		}).
+		Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			return ctx
+		}).Feature()
+
+	result := newTestEnv().TestWithResult(t, passing, skipped)
+	if val, ok := result.Context.Value(ctxKey{}).(string); !ok || val != "passing" {
+		t.Errorf("expected the context surfaced by the features, got %v", result.Context.Value(ctxKey{}))
+	}
+	if len(result.Features) != 2 {
+		t.Fatalf("expected 2 feature results, got %d", len(result.Features))
+	}
+	if got := result.Features[0]; got.Name != "passing" || got.Status != types.FeaturePassed || got.Err != nil {
+		t.Errorf("unexpected result for the passing feature: %+v", got)
+	}
+	if got := result.Features[1]; got.Name != "skipped" || got.Status != types.FeatureSkipped || got.Err == nil || got.Err.Error() != "not supported" {
+		t.Errorf("unexpected result for the skipped feature: %+v", got)
+	}
+}
+
+func TestEnv_WithStepTimeout(t *testing.T) {
+	type ctxKey struct{}
+	var blockedErr, nextErr error
+	var value interface{}
+	f := features.New("step-timeout").
+		WithStepTimeout(50*time.Millisecond).
+		Setup(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			// a step hanging until its context expires
+			<-ctx.Done()
+			blockedErr = ctx.Err()
+			return context.WithValue(ctx, ctxKey{}, "setup")
+		}).
+		Assess("next step", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			nextErr = ctx.Err()
+			value = ctx.Value(ctxKey{})
+			return ctx
+		}).Feature()
+
+	_ = newTestEnv().Test(t, f)
+	if !errors.Is(blockedErr, context.DeadlineExceeded) {
+		t.Errorf("expected the blocking step to time out, got: %v", blockedErr)
+	}
+	if nextErr != nil {
+		t.Errorf("expected the next step to run with a live context, got: %v", nextErr)
+	}
+	if value != "setup" {
+		t.Errorf("expected the context values of the timed out step to be surfaced, got: %v", value)
+	}
+}
+
+func TestEnv_FeatureWithContext(t *testing.T) {
+	type envKey struct{}
+	type featureKey struct{}
+	var seededFrom, value interface{}
+	f := features.New("with-context").
+		WithContext(func(ctx context.Context) context.Context {
+			seededFrom = ctx.Value(envKey{})
+			return context.WithValue(ctx, featureKey{}, "seeded")
+		}).
+		Assess("read seeded value", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			value = ctx.Value(featureKey{})
+			return ctx
+		}).Feature()
+
+	_ = newTestEnv().WithContext(context.WithValue(context.TODO(), envKey{}, "env")).Test(t, f)
+	if seededFrom != "env" {
+		t.Errorf("expected the feature context to be seeded from the environment context, got: %v", seededFrom)
+	}
+	if value != "seeded" {
+		t.Errorf("expected the seeded value in the first assessment, got: %v", value)
+	}
+}
+
+func TestEnv_WithTeardownAlways(t *testing.T) {
+	// The feature under test fails, so it is run in a separate process to
+	// keep this test from failing.
+	if os.Getenv("E2E_FRAMEWORK_TEARDOWN_ALWAYS_HELPER") == "1" {
+		f := features.New("failing-setup").
+			Setup(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				t.Fatal("setup failed")
+				return ctx
+			}).
+			Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				fmt.Println("teardown ran")
+				return ctx
+			}).
+			WithTeardownAlways("cleanup", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				fmt.Println("cleanup flag set")
+				return ctx
+			})
+		_ = newTestEnv().Test(t, f.Feature())
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEnv_WithTeardownAlways$")
+	cmd.Env = append(os.Environ(), "E2E_FRAMEWORK_TEARDOWN_ALWAYS_HELPER=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected the feature with a failing setup to fail, got output:\n%s", out)
+	}
+	if !strings.Contains(string(out), "cleanup flag set") {
+		t.Errorf("expected the always teardown to run after the failing setup, got output:\n%s", out)
+	}
+	if strings.Contains(string(out), "teardown ran") {
+		t.Errorf("expected the regular teardown to be skipped after the failing setup, got output:\n%s", out)
+	}
+}
+
+func TestEnv_PanickingAssessment(t *testing.T) {
+	// The feature under test panics, so it is run in a separate process to
+	// keep this test from failing.
+	if os.Getenv("E2E_FRAMEWORK_PANIC_HELPER") == "1" {
+		panicking := features.New("panicking").
+			Assess("panics", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				var cfg *envconf.Config
+				fmt.Println(cfg.Namespace())
+				return ctx
+			}).
+			Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				fmt.Println("teardown ran")
+				return ctx
+			}).Feature()
+		next := features.New("next").
+			Assess("runs", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				fmt.Println("next feature assessed")
+				return ctx
+			}).Feature()
+		result := newTestEnv().TestWithResult(t, panicking, next)
+		// reaching this point means the test binary was not aborted, so the Finish operations would run
+		fmt.Printf("panicking feature status: %s\n", result.Features[0].Status)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEnv_PanickingAssessment$")
+	cmd.Env = append(os.Environ(), "E2E_FRAMEWORK_PANIC_HELPER=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected the panicking feature to fail, got output:\n%s", out)
+	}
+	for _, expected := range []string{
+		`step "panics" panicked: runtime error: invalid memory address or nil pointer dereference`,
+		"teardown ran",
+		"next feature assessed",
+		"panicking feature status: " + string(types.FeatureFailed),
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Errorf("expected %q in the output, got:\n%s", expected, out)
+		}
+	}
+}
+
+func TestEnv_WithFailFast(t *testing.T) {
+	// The first feature fails, so it is run in a separate process to
+	// keep this test from failing.
+	if os.Getenv("E2E_FRAMEWORK_FAIL_FAST_HELPER") == "1" {
+		env := newTestEnv().WithFailFast()
+		fail := features.New("feature-1").
+			Assess("fails", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				t.Error("assessment failed")
+				return ctx
+			}).Feature()
+		var next []types.Feature
+		for _, name := range []string{"feature-2", "feature-3"} {
+			name := name
+			next = append(next, features.New(name).
+				Assess("runs", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					fmt.Printf("%s assessed\n", name)
+					return ctx
+				}).Feature())
+		}
+		result := env.TestWithResult(t, fail, next[0])
+		result.Features = append(result.Features, env.TestWithResult(t, next[1]).Features...)
+		for _, feature := range result.Features {
+			fmt.Printf("result %s=%s\n", feature.Name, feature.Status)
+		}
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEnv_WithFailFast$", "-test.v")
+	cmd.Env = append(os.Environ(), "E2E_FRAMEWORK_FAIL_FAST_HELPER=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected the first feature to fail, got output:\n%s", out)
+	}
+	for _, expected := range []string{"result feature-1=failed", "result feature-2=skipped", "result feature-3=skipped"} {
+		if !strings.Contains(string(out), expected) {
+			t.Errorf("expected output to contain %q, got output:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(string(out), "assessed") {
+		t.Errorf("expected the features following the failure not to be assessed, got output:\n%s", out)
+	}
+}
+
 // This test shows the full context propagation from
 // environment setup functions (started in main_test.go) down to
 // feature step functions.
@@ -295,6 +295,8 @@ func randNS() string {
 
 // RandomName generates a random name of n length with the provided
 // prefix. If prefix is omitted, the then entire name is random char.
+// The random part is read from crypto/rand, so it is safe to call
+// RandomName from concurrently running features.
 func RandomName(prefix string, n int) string {
 	if n == 0 {
 		n = 32
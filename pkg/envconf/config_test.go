@@ -20,6 +20,7 @@ import (
 	"flag"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -114,3 +115,26 @@ func TestRandomGeneratorIsIndeedGeneratingRandom(t *testing.T) {
 		}
 	}
 }
+
+func TestRandomNameConcurrent(t *testing.T) {
+	const goroutines = 100
+	names := make(chan string, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names <- RandomName("parallel", 24)
+		}()
+	}
+	wg.Wait()
+	close(names)
+
+	seen := make(map[string]bool, goroutines)
+	for name := range names {
+		if seen[name] {
+			t.Errorf("random name %q was generated more than once", name)
+		}
+		seen[name] = true
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+const (
+	// DefaultCalicoManifestURL is the location of the Calico manifests applied by InstallCalico
+	// unless WithCNIManifestURL is used.
+	DefaultCalicoManifestURL = "https://raw.githubusercontent.com/projectcalico/calico/v3.28.2/manifests/calico.yaml"
+
+	// DefaultCiliumVersion is the version of the Cilium helm chart rendered by InstallCilium
+	// unless WithCNIManifestURL is used.
+	DefaultCiliumVersion = "1.16.3"
+
+	ciliumChartRepository = "https://helm.cilium.io"
+)
+
+// cniWorkloads are the workloads of the kube-system namespace a CNI runs, which InstallCalico and
+// InstallCilium wait for.
+type cniWorkloads struct {
+	daemonSet  string
+	deployment string
+}
+
+type cniOptions struct {
+	manifestURL string
+	timeout     time.Duration
+}
+
+type CNIOpts func(*cniOptions)
+
+// WithCNIManifestURL sets the location of the CNI manifests to apply, for instance to pin another
+// release. InstallCilium applies these manifests instead of rendering the Cilium helm chart.
+func WithCNIManifestURL(url string) CNIOpts {
+	return func(o *cniOptions) {
+		o.manifestURL = url
+	}
+}
+
+// WithCNITimeout sets how long to wait for the pods of the CNI to be ready.
+func WithCNITimeout(timeout time.Duration) CNIOpts {
+	return func(o *cniOptions) {
+		o.timeout = timeout
+	}
+}
+
+// InstallCalico provides an Environment.Func that installs the Calico CNI, which enforces
+// NetworkPolicies, and waits for its pods to be ready.
+//
+// The CNI shipped with kind must be disabled for the nodes of the cluster to use Calico, and the
+// pod subnet set to the default IP pool of Calico, with a kind config such as:
+//
+//	kind: Cluster
+//	apiVersion: kind.x-k8s.io/v1alpha4
+//	networking:
+//	  disableDefaultCNI: true
+//	  podSubnet: 192.168.0.0/16
+//
+// passed to CreateKindClusterWithConfig. The nodes of such a cluster are not Ready until a CNI is
+// installed.
+func InstallCalico(opts ...CNIOpts) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		options := &cniOptions{manifestURL: DefaultCalicoManifestURL, timeout: 5 * time.Minute}
+		for _, opt := range opts {
+			opt(options)
+		}
+
+		manifest, err := fetchManifest(ctx, options.manifestURL)
+		if err != nil {
+			return ctx, fmt.Errorf("install calico func: %w", err)
+		}
+		klog.FromContext(ctx).V(2).Info("Installing Calico", "manifest", options.manifestURL)
+		workloads := cniWorkloads{daemonSet: "calico-node", deployment: "calico-kube-controllers"}
+		if err := installCNI(ctx, cfg, manifest, workloads, options.timeout); err != nil {
+			return ctx, fmt.Errorf("install calico func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// InstallCilium provides an Environment.Func that installs the Cilium CNI, which enforces
+// NetworkPolicies, and waits for its pods to be ready. The manifests are rendered from the Cilium
+// helm chart, which requires the helm binary, unless WithCNIManifestURL is used.
+//
+// The CNI shipped with kind must be disabled for the nodes of the cluster to use Cilium, with a
+// kind config such as:
+//
+//	kind: Cluster
+//	apiVersion: kind.x-k8s.io/v1alpha4
+//	networking:
+//	  disableDefaultCNI: true
+//
+// passed to CreateKindClusterWithConfig. The nodes of such a cluster are not Ready until a CNI is
+// installed.
+func InstallCilium(opts ...CNIOpts) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		options := &cniOptions{timeout: 5 * time.Minute}
+		for _, opt := range opts {
+			opt(options)
+		}
+
+		var manifest []byte
+		var err error
+		if options.manifestURL != "" {
+			klog.FromContext(ctx).V(2).Info("Installing Cilium", "manifest", options.manifestURL)
+			manifest, err = fetchManifest(ctx, options.manifestURL)
+		} else {
+			klog.FromContext(ctx).V(2).Info("Installing Cilium", "version", DefaultCiliumVersion)
+			manifest, err = renderCiliumChart()
+		}
+		if err != nil {
+			return ctx, fmt.Errorf("install cilium func: %w", err)
+		}
+		workloads := cniWorkloads{daemonSet: "cilium", deployment: "cilium-operator"}
+		if err := installCNI(ctx, cfg, manifest, workloads, options.timeout); err != nil {
+			return ctx, fmt.Errorf("install cilium func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// installCNI creates the objects of manifest and waits for the workloads of the CNI to be ready.
+func installCNI(ctx context.Context, cfg *envconf.Config, manifest []byte, workloads cniWorkloads, timeout time.Duration) error {
+	client, err := cfg.NewClient()
+	if err != nil {
+		return err
+	}
+	res := client.Resources()
+	if err := decoder.DecodeEach(ctx, bytes.NewReader(manifest), decoder.CreateIgnoreAlreadyExists(res)); err != nil {
+		return err
+	}
+
+	daemonSet := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: workloads.daemonSet, Namespace: metav1.NamespaceSystem}}
+	err = wait.For(conditions.New(res).ResourceMatch(daemonSet, daemonSetRolledOut), wait.WithContext(ctx), wait.WithTimeout(timeout))
+	if err != nil {
+		return fmt.Errorf("daemonset %s not ready: %w", workloads.daemonSet, err)
+	}
+	err = wait.For(conditions.New(res).DeploymentAvailable(workloads.deployment, metav1.NamespaceSystem), wait.WithContext(ctx), wait.WithTimeout(timeout))
+	if err != nil {
+		return fmt.Errorf("deployment %s not available: %w", workloads.deployment, err)
+	}
+	return nil
+}
+
+// daemonSetRolledOut reports whether the pods of the DaemonSet are scheduled on its nodes and
+// ready. Unlike conditions.DaemonSetReady, a DaemonSet whose status is not yet reported by its
+// controller is not considered ready.
+func daemonSetRolledOut(obj k8s.Object) bool {
+	status := obj.(*appsv1.DaemonSet).Status
+	return status.DesiredNumberScheduled > 0 && status.NumberReady == status.DesiredNumberScheduled && status.NumberUnavailable == 0
+}
+
+// fetchManifest returns the content of the manifests at url.
+func fetchManifest(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// renderCiliumChart renders the manifests of the Cilium helm chart with the values fit for kind
+// clusters, as documented by https://docs.cilium.io/en/stable/installation/kind/.
+func renderCiliumChart() ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	command := fmt.Sprintf("helm template cilium cilium --repo %s --version %s --namespace %s --set image.pullPolicy=IfNotPresent --set ipam.mode=kubernetes",
+		ciliumChartRepository, DefaultCiliumVersion, metav1.NamespaceSystem)
+	if err := utils.RunCommandWithSeperatedOutput(command, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("rendering cilium chart: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/support/kind"
+)
+
+func TestInstallCalico(t *testing.T) {
+	clusterName := envconf.RandomName("calico-cluster", 16)
+	cfg := envconf.New()
+	ctx, err := envfuncs.CreateClusterWithConfig(kind.NewProvider(), clusterName, "testdata/kind-no-cni.yaml")(context.TODO(), cfg)
+	if err != nil {
+		t.Fatal("Error creating cluster", err)
+	}
+	defer func() {
+		if _, err := envfuncs.DestroyCluster(clusterName)(ctx, cfg); err != nil {
+			t.Error("Error destroying cluster", err)
+		}
+	}()
+
+	ctx, err = envfuncs.InstallCalico()(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := cfg.Client().Resources()
+	var nodes corev1.NodeList
+	if err := res.List(ctx, &nodes); err != nil {
+		t.Fatal("Error listing nodes", err)
+	}
+	err = wait.For(conditions.New(res).ResourceListMatchN(&nodes, len(nodes.Items), func(obj k8s.Object) bool {
+		for _, condition := range obj.(*corev1.Node).Status.Conditions {
+			if condition.Type == corev1.NodeReady {
+				return condition.Status == corev1.ConditionTrue
+			}
+		}
+		return false
+	}), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Fatal("Error waiting for the nodes to be Ready", err)
+	}
+}
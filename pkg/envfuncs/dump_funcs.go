@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// clusterScopedDumpDir is the directory of the dump of DumpClusterState holding the cluster scoped objects.
+const clusterScopedDumpDir = "_cluster"
+
+// DumpClusterState provides an Environment.Func that writes the objects of every kind served by
+// the cluster to the directory dir as YAML, such as to keep a postmortem artifact of a failed CI
+// run. It is meant to be registered with Finish, ahead of the function destroying the cluster.
+//
+// The objects of each namespace are written to a directory named after the namespace, and the
+// cluster scoped objects to the _cluster directory, with one file per resource, such as
+// dir/default/configmaps.yaml or dir/_cluster/nodes.yaml. The resources of API groups are suffixed
+// with their group, as in deployments.apps.yaml. Secrets are left out, so that the dump can be
+// published as is. The metadata fields managed by the API server are stripped, as with
+// resources.EncodeYAML.
+//
+// The dump is best-effort: the resources that cannot be listed or written are logged and
+// skipped, and no error is returned.
+func DumpClusterState(dir string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		log := klog.FromContext(ctx)
+		client, err := cfg.NewClient()
+		if err != nil {
+			log.Error(err, "Cluster state not dumped")
+			return ctx, nil
+		}
+		dc, err := discovery.NewDiscoveryClientForConfig(client.RESTConfig())
+		if err != nil {
+			log.Error(err, "Cluster state not dumped")
+			return ctx, nil
+		}
+		resourceLists, err := dc.ServerPreferredResources()
+		if err != nil {
+			// the resources of the groups that could be discovered are still dumped
+			log.Error(err, "Discovery of the resources to dump failed")
+		}
+
+		log.V(2).Info("Dumping cluster state", "dir", dir)
+		res := client.Resources()
+		for _, resourceList := range resourceLists {
+			gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+			if err != nil {
+				log.Error(err, "Resources not dumped", "groupVersion", resourceList.GroupVersion)
+				continue
+			}
+			for _, resource := range resourceList.APIResources {
+				if strings.Contains(resource.Name, "/") || !hasListVerb(resource.Verbs) {
+					continue
+				}
+				if gv.Group == "" && resource.Name == "secrets" {
+					continue
+				}
+				if err := dumpResource(ctx, res, dir, gv.WithResource(resource.Name), gv.WithKind(resource.Kind)); err != nil {
+					log.Error(err, "Resource not dumped", "resource", resource.Name, "groupVersion", gv.String())
+				}
+			}
+		}
+		return ctx, nil
+	}
+}
+
+// dumpResource writes the objects of the resource gvr, of kind gvk, to one file per namespace under dir.
+func dumpResource(ctx context.Context, res *resources.Resources, dir string, gvr schema.GroupVersionResource, gvk schema.GroupVersionKind) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := res.List(ctx, list); err != nil {
+		return err
+	}
+
+	byNamespace := make(map[string][]k8s.Object)
+	for i := range list.Items {
+		obj := &list.Items[i]
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = clusterScopedDumpDir
+		}
+		byNamespace[namespace] = append(byNamespace[namespace], obj)
+	}
+
+	file := gvr.Resource + ".yaml"
+	if gvr.Group != "" {
+		file = gvr.Resource + "." + gvr.Group + ".yaml"
+	}
+	for namespace, objs := range byNamespace {
+		data, err := resources.EncodeYAML(objs...)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(dir, namespace), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, namespace, file), data, 0o644); err != nil {
+			return fmt.Errorf("writing %s of namespace %s: %w", file, namespace, err)
+		}
+	}
+	return nil
+}
+
+// hasListVerb reports whether the list verb is found in verbs.
+func hasListVerb(verbs []string) bool {
+	for _, verb := range verbs {
+		if verb == "list" {
+			return true
+		}
+	}
+	return false
+}
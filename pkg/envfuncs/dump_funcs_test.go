@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestDumpClusterState(t *testing.T) {
+	namespace := envconf.RandomName("dump", 16)
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "dump-test", Namespace: namespace},
+		Data:       map[string]string{"key": "value"},
+	}
+
+	feat := features.New("DumpClusterState").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			if err := cfg.Client().Resources().Create(ctx, configMap); err != nil {
+				t.Fatal("Error creating config map", err)
+			}
+			return ctx
+		}).
+		Assess("namespace dumped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			dir := t.TempDir()
+			ctx, err := envfuncs.DumpClusterState(dir)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, namespace, "configmaps.yaml"))
+			if err != nil {
+				t.Fatal("Error reading dump of the namespace", err)
+			}
+			if !strings.Contains(string(data), "name: "+configMap.Name) {
+				t.Errorf("config map %s not found in dump:\n%s", configMap.Name, data)
+			}
+			if _, err := os.Stat(filepath.Join(dir, namespace, "secrets.yaml")); !os.IsNotExist(err) {
+				t.Error("secrets dumped")
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
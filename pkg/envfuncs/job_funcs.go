@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// jobLogTailLines is the number of trailing log lines of each container reported when a job fails.
+const jobLogTailLines = 20
+
+// ErrJobFailed is wrapped by the error of WaitForJobComplete when the job reports the Failed condition.
+var ErrJobFailed = errors.New("job failed")
+
+// WaitForJobComplete provides an Environment.Func that blocks until the job name of namespace
+// reports the Complete condition, such as a migration or bootstrap job run during setup.
+//
+// The wait stops as soon as the job reports the Failed condition, with an error wrapping
+// ErrJobFailed that carries the reason of the failure and the last lines of the logs of the
+// pods of the job. An error that does not wrap ErrJobFailed is returned when the job does not
+// finish within timeout.
+func WaitForJobComplete(name, namespace string, timeout time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("wait for job complete func: %w", err)
+		}
+		res := client.Resources(namespace)
+		var job batchv1.Job
+		var failed *batchv1.JobCondition
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			if err := res.Get(ctx, name, namespace, &job); err != nil {
+				return false, err
+			}
+			for i, cond := range job.Status.Conditions {
+				if cond.Status != corev1.ConditionTrue {
+					continue
+				}
+				switch cond.Type {
+				case batchv1.JobComplete:
+					return true, nil
+				case batchv1.JobFailed:
+					failed = &job.Status.Conditions[i]
+					return false, ErrJobFailed
+				}
+			}
+			return false, nil
+		}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+		if failed != nil {
+			return ctx, fmt.Errorf("wait for job complete func: job %s/%s: %w: %s: %s%s", namespace, name, ErrJobFailed, failed.Reason, failed.Message, jobPodLogs(ctx, client, res, &job))
+		}
+		if err != nil {
+			return ctx, fmt.Errorf("wait for job complete func: job %s/%s not complete: %w", namespace, name, err)
+		}
+		return ctx, nil
+	}
+}
+
+// jobPodLogs returns the last lines of the logs of the containers of the pods of job, one block
+// per container, for diagnosis. Logs that cannot be retrieved are reported as such.
+func jobPodLogs(ctx context.Context, client klient.Client, res *resources.Resources, job *batchv1.Job) string {
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return fmt.Sprintf("\n(pod logs unavailable: %v)", err)
+	}
+	var pods corev1.PodList
+	if err := res.List(ctx, &pods, resources.WithLabelSelector(selector.String())); err != nil {
+		return fmt.Sprintf("\n(pod logs unavailable: %v)", err)
+	}
+	clientset, err := kubernetes.NewForConfig(client.RESTConfig())
+	if err != nil {
+		return fmt.Sprintf("\n(pod logs unavailable: %v)", err)
+	}
+
+	tailLines := int64(jobLogTailLines)
+	var sb strings.Builder
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			fmt.Fprintf(&sb, "\n--- logs of pod %s, container %s ---\n", pod.Name, container.Name)
+			logs, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container: container.Name,
+				TailLines: &tailLines,
+			}).DoRaw(ctx)
+			if err != nil {
+				fmt.Fprintf(&sb, "(unavailable: %v)", err)
+				continue
+			}
+			sb.WriteString(strings.TrimSpace(string(logs)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func newJob(name, namespace, script string) *batchv1.Job {
+	backoffLimit := int32(0)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "busybox",
+						Image:   "busybox",
+						Command: []string{"sh", "-c", script},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestWaitForJobComplete(t *testing.T) {
+	namespace := envconf.RandomName("wait-for-job", 16)
+	succeeding := newJob("succeeding", namespace, "echo migrated")
+	failing := newJob("failing", namespace, "echo migration failed; exit 1")
+
+	feat := features.New("WaitForJobComplete").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			for _, job := range []*batchv1.Job{succeeding, failing} {
+				if err := cfg.Client().Resources().Create(ctx, job); err != nil {
+					t.Fatal("Error creating job", err)
+				}
+			}
+			return ctx
+		}).
+		Assess("job completes", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.WaitForJobComplete(succeeding.Name, namespace, 2*time.Minute)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ctx
+		}).
+		Assess("job failure reported with logs", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.WaitForJobComplete(failing.Name, namespace, 2*time.Minute)(ctx, cfg)
+			if !errors.Is(err, envfuncs.ErrJobFailed) {
+				t.Fatalf("expected the failure of the job to be reported, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), "migration failed") {
+				t.Errorf("expected the error to carry the logs of the job, got: %v", err)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
@@ -18,11 +18,72 @@ package envfuncs
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 
 	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/support/kind"
 )
 
+type kubernetesVersionContextKey string
+
+// kindNodeImages maps the Kubernetes minor versions supported by CreateKindClusterWithVersion to
+// the kindest/node images built for the kind release installed by the framework.
+var kindNodeImages = map[string]string{
+	"1.22": "kindest/node:v1.22.15@sha256:7d9708c4b0873f0fe2e171e2b1b7f45ae89482617778c1c875f1053d4cef2e41",
+	"1.23": "kindest/node:v1.23.13@sha256:ef453bb7c79f0e3caba88d2067d4196f427794086a7d0df8df4f019d5e336b61",
+	"1.24": "kindest/node:v1.24.7@sha256:577c630ce8e509131eab1aea12c022190978dd2f745aac5eb1fe65c0807eb315",
+	"1.25": "kindest/node:v1.25.3@sha256:f52781bc0d7a19fb6c405c2af83abfeb311f130707a0e219175677e366cc45d1",
+}
+
+// CreateKindClusterWithVersion returns an env.Func that creates a kind cluster running the
+// Kubernetes minor version k8sVersion, such as "1.25" or "v1.25", which enables testing
+// against a matrix of versions. The patch version of the cluster is the one of the
+// kindest/node image mapped to the minor version, and can be retrieved from the context
+// using GetKubernetesVersionFromContext. An error listing the supported versions is
+// returned when k8sVersion is not mapped to an image.
+//
+// NOTE: the returned function will update its env config with the
+// kubeconfig file for the config client.
+func CreateKindClusterWithVersion(clusterName, k8sVersion string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		image, ok := kindNodeImages[kubernetesMinorVersion(k8sVersion)]
+		if !ok {
+			supported := make([]string, 0, len(kindNodeImages))
+			for version := range kindNodeImages {
+				supported = append(supported, version)
+			}
+			sort.Strings(supported)
+			return ctx, fmt.Errorf("create kind cluster with version func: unsupported Kubernetes version %q, supported versions are: %s", k8sVersion, strings.Join(supported, ", "))
+		}
+		ctx, err := CreateCluster(kind.NewProvider().WithOpts(kind.WithImage(image)), clusterName)(ctx, cfg)
+		if err != nil {
+			return ctx, err
+		}
+		version := strings.TrimPrefix(image, "kindest/node:")
+		version = version[:strings.Index(version, "@")]
+		return context.WithValue(ctx, kubernetesVersionContextKey(clusterName), version), nil
+	}
+}
+
+// GetKubernetesVersionFromContext returns the Kubernetes version, such as "v1.25.3", of the
+// cluster created by CreateKindClusterWithVersion with the given name.
+func GetKubernetesVersionFromContext(ctx context.Context, clusterName string) (string, bool) {
+	version, ok := ctx.Value(kubernetesVersionContextKey(clusterName)).(string)
+	return version, ok
+}
+
+// kubernetesMinorVersion returns the major and minor components of version, without the v prefix.
+func kubernetesMinorVersion(version string) string {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
+
 // Deprecated: This handler has been deprecated in favor of GetClusterFromContext
 func GetKindClusterFromContext(ctx context.Context, clusterName string) (*kind.Cluster, bool) {
 	provider, ok := GetClusterFromContext(ctx, clusterName)
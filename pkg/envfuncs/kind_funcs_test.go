@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/discovery"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+)
+
+func TestCreateKindClusterWithVersion(t *testing.T) {
+	clusterName := envconf.RandomName("version-cluster", 16)
+	cfg := envconf.New()
+	ctx, err := envfuncs.CreateKindClusterWithVersion(clusterName, "1.24")(context.TODO(), cfg)
+	if err != nil {
+		t.Fatal("Error creating cluster", err)
+	}
+	defer func() {
+		if _, err := envfuncs.DestroyCluster(clusterName)(ctx, cfg); err != nil {
+			t.Error("Error destroying cluster", err)
+		}
+	}()
+
+	version, ok := envfuncs.GetKubernetesVersionFromContext(ctx, clusterName)
+	if !ok {
+		t.Fatal("expected the Kubernetes version to be stored in the context")
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg.Client().RESTConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := dc.ServerVersion()
+	if err != nil {
+		t.Fatal("Error getting server version", err)
+	}
+	if info.GitVersion != version {
+		t.Errorf("expected server version %s, got %s", version, info.GitVersion)
+	}
+}
+
+func TestCreateKindClusterWithVersionUnsupported(t *testing.T) {
+	_, err := envfuncs.CreateKindClusterWithVersion("unsupported-version", "1.10")(context.TODO(), envconf.New())
+	if err == nil {
+		t.Fatal("expected an error for an unsupported version")
+	}
+	if !strings.Contains(err.Error(), "1.25") {
+		t.Errorf("expected the error to list the supported versions, got: %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/types"
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+// loadBalancerImages are the images of the in-cluster implementations of LoadBalancer services.
+var loadBalancerImages = []string{"metallb", "kube-vip"}
+
+// cloudProviderKindImage is the image of cloud-provider-kind, which implements LoadBalancer services
+// for kind clusters from a container run next to the nodes.
+const cloudProviderKindImage = "cloud-provider-kind"
+
+// kindProviderIDPrefix is the prefix of the provider ID kind sets on its nodes.
+const kindProviderIDPrefix = "kind://"
+
+type externalAddressContextKey string
+
+// WaitForServiceExternalIP provides an Environment.Func that waits for the LoadBalancer service
+// name of namespace to be assigned an external address, which features retrieve with
+// GetServiceExternalIPFromContext to reach the service. The address is the IP assigned to the
+// service, or its hostname for load balancers exposing one, such as the ones of AWS.
+//
+// Kind clusters only assign addresses when metallb or cloud-provider-kind is installed, features
+// depending on them can be skipped otherwise with SkipWithoutLoadBalancer.
+func WaitForServiceExternalIP(name, namespace string, timeout time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("wait for service external ip func: %w", err)
+		}
+		res := client.Resources(namespace)
+		var service corev1.Service
+		var address string
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			if err := res.Get(ctx, name, namespace, &service); err != nil {
+				return false, err
+			}
+			if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+				return false, fmt.Errorf("service is of type %s, not %s", service.Spec.Type, corev1.ServiceTypeLoadBalancer)
+			}
+			address = loadBalancerAddress(service.Status.LoadBalancer.Ingress)
+			return address != "", nil
+		}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+		if err != nil {
+			return ctx, fmt.Errorf("wait for service external ip func: service %s/%s not assigned an address: %w", namespace, name, err)
+		}
+		klog.FromContext(ctx).V(2).Info("Service assigned an external address", "service", namespace+"/"+name, "address", address)
+		return context.WithValue(ctx, externalAddressContextKey("service/"+namespace+"/"+name), address), nil
+	}
+}
+
+// GetServiceExternalIPFromContext returns the external address of the service name of namespace
+// found by WaitForServiceExternalIP.
+func GetServiceExternalIPFromContext(ctx context.Context, namespace, name string) (string, bool) {
+	address, ok := ctx.Value(externalAddressContextKey("service/" + namespace + "/" + name)).(string)
+	return address, ok
+}
+
+// WaitForIngressAddress provides an Environment.Func that waits for the ingress controller to
+// publish the address of the Ingress name of namespace, which features retrieve with
+// GetIngressAddressFromContext to reach it. The address is an IP, or a hostname for load
+// balancers exposing one.
+//
+// Kind clusters ship no ingress controller, features depending on one can be skipped with
+// SkipWithoutIngressController.
+func WaitForIngressAddress(name, namespace string, timeout time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("wait for ingress address func: %w", err)
+		}
+		res := client.Resources(namespace)
+		var ingress networkingv1.Ingress
+		var address string
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			if err := res.Get(ctx, name, namespace, &ingress); err != nil {
+				return false, err
+			}
+			for _, lb := range ingress.Status.LoadBalancer.Ingress {
+				if address = lb.IP; address == "" {
+					address = lb.Hostname
+				}
+				if address != "" {
+					return true, nil
+				}
+			}
+			return false, nil
+		}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+		if err != nil {
+			return ctx, fmt.Errorf("wait for ingress address func: ingress %s/%s not assigned an address: %w", namespace, name, err)
+		}
+		klog.FromContext(ctx).V(2).Info("Ingress assigned an address", "ingress", namespace+"/"+name, "address", address)
+		return context.WithValue(ctx, externalAddressContextKey("ingress/"+namespace+"/"+name), address), nil
+	}
+}
+
+// GetIngressAddressFromContext returns the address of the Ingress name of namespace found by
+// WaitForIngressAddress.
+func GetIngressAddressFromContext(ctx context.Context, namespace, name string) (string, bool) {
+	address, ok := ctx.Value(externalAddressContextKey("ingress/" + namespace + "/" + name)).(string)
+	return address, ok
+}
+
+// loadBalancerAddress returns the first IP or hostname of ingress, or an empty string.
+func loadBalancerAddress(ingress []corev1.LoadBalancerIngress) string {
+	for _, lb := range ingress {
+		if lb.IP != "" {
+			return lb.IP
+		}
+		if lb.Hostname != "" {
+			return lb.Hostname
+		}
+	}
+	return ""
+}
+
+// SkipWithoutLoadBalancer returns a condition for features.FeatureBuilder.SkipIf that skips
+// features depending on LoadBalancer services being assigned an address when the cluster has no
+// implementation of them. Clusters whose nodes are run by a cloud provider are assumed to have
+// one, while kind clusters need metallb or kube-vip to be installed, or cloud-provider-kind to
+// run in a docker container. cloud-provider-kind run as a binary on the host is not detected.
+func SkipWithoutLoadBalancer() types.SkipFunc {
+	return func(ctx context.Context, cfg *envconf.Config) (bool, string) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return true, fmt.Sprintf("load balancer support could not be determined: %v", err)
+		}
+		res := client.Resources()
+		var nodes corev1.NodeList
+		if err := res.List(ctx, &nodes); err != nil {
+			return true, fmt.Sprintf("load balancer support could not be determined: %v", err)
+		}
+		for _, node := range nodes.Items {
+			if node.Spec.ProviderID != "" && !strings.HasPrefix(node.Spec.ProviderID, kindProviderIDPrefix) {
+				return false, ""
+			}
+		}
+
+		var deployments appsv1.DeploymentList
+		if err := res.List(ctx, &deployments); err != nil {
+			return true, fmt.Sprintf("load balancer support could not be determined: %v", err)
+		}
+		var daemonSets appsv1.DaemonSetList
+		if err := res.List(ctx, &daemonSets); err != nil {
+			return true, fmt.Sprintf("load balancer support could not be determined: %v", err)
+		}
+		var templates []corev1.PodTemplateSpec
+		for _, deployment := range deployments.Items {
+			templates = append(templates, deployment.Spec.Template)
+		}
+		for _, ds := range daemonSets.Items {
+			templates = append(templates, ds.Spec.Template)
+		}
+		for _, template := range templates {
+			for _, container := range template.Spec.Containers {
+				for _, image := range loadBalancerImages {
+					if strings.Contains(container.Image, image) {
+						return false, ""
+					}
+				}
+			}
+		}
+
+		p := utils.RunCommand("docker ps --format {{.Image}}")
+		if p.Err() == nil && strings.Contains(p.Result(), cloudProviderKindImage) {
+			return false, ""
+		}
+		return true, "no load balancer implementation found, install metallb or run cloud-provider-kind for LoadBalancer services to be assigned an address"
+	}
+}
+
+// SkipWithoutIngressController returns a condition for features.FeatureBuilder.SkipIf that skips
+// features depending on Ingresses being assigned an address when the cluster declares no
+// IngressClass, as installed along with ingress controllers such as ingress-nginx.
+func SkipWithoutIngressController() types.SkipFunc {
+	return func(ctx context.Context, cfg *envconf.Config) (bool, string) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return true, fmt.Sprintf("ingress controller support could not be determined: %v", err)
+		}
+		var classes networkingv1.IngressClassList
+		if err := client.Resources().List(ctx, &classes); err != nil {
+			return true, fmt.Sprintf("ingress controller support could not be determined: %v", err)
+		}
+		if len(classes.Items) == 0 {
+			return true, "no IngressClass found, install an ingress controller for Ingresses to be assigned an address"
+		}
+		return false, ""
+	}
+}
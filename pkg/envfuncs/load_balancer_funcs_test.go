@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestWaitForServiceExternalIP(t *testing.T) {
+	namespace := envconf.RandomName("load-balancer", 16)
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "load-balancer-test", Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeLoadBalancer,
+			Selector: map[string]string{"app": "load-balancer-test"},
+			Ports:    []corev1.ServicePort{{Port: 80}},
+		},
+	}
+
+	feat := features.New("WaitForServiceExternalIP").
+		SkipIf(envfuncs.SkipWithoutLoadBalancer()).
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			if err := cfg.Client().Resources().Create(ctx, service); err != nil {
+				t.Fatal("Error creating service", err)
+			}
+			return ctx
+		}).
+		Assess("external address assigned", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.WaitForServiceExternalIP(service.Name, namespace, 2*time.Minute)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if address, ok := envfuncs.GetServiceExternalIPFromContext(ctx, namespace, service.Name); !ok || address == "" {
+				t.Error("external address not found in context")
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
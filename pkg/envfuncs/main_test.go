@@ -19,6 +19,7 @@ package envfuncs_test
 import (
 	"os"
 	"testing"
+	"time"
 
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
@@ -35,7 +36,10 @@ func TestMain(m *testing.M) {
 
 	// Use the same cluster for all ns_funcs tests
 	nsTestenv.
-		Setup(envfuncs.CreateCluster(kind.NewProvider(), nsClusterName)).
+		Setup(
+			envfuncs.CreateCluster(kind.NewProvider(), nsClusterName),
+			envfuncs.WaitForClusterReady(3*time.Minute),
+		).
 		Finish(envfuncs.DestroyCluster(nsClusterName))
 
 	os.Exit(nsTestenv.Run(m))
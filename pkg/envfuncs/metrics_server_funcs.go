@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+const (
+	// DefaultMetricsServerManifestURL is the location of the metrics-server manifests
+	// applied by InstallMetricsServer unless WithMetricsServerManifestURL is used.
+	DefaultMetricsServerManifestURL = "https://github.com/kubernetes-sigs/metrics-server/releases/download/v0.7.2/components.yaml"
+
+	metricsServerName         = "metrics-server"
+	metricsAPIGroupVersion    = "metrics.k8s.io/v1beta1"
+	metricsServerInsecureFlag = "--kubelet-insecure-tls"
+)
+
+type metricsServerOptions struct {
+	manifestURL string
+	timeout     time.Duration
+}
+
+type MetricsServerOpts func(*metricsServerOptions)
+
+// WithMetricsServerManifestURL sets the location of the metrics-server manifests to apply,
+// for instance to pin another release.
+func WithMetricsServerManifestURL(url string) MetricsServerOpts {
+	return func(o *metricsServerOptions) {
+		o.manifestURL = url
+	}
+}
+
+// WithMetricsServerTimeout sets how long to wait for the metrics-server deployment to be available.
+func WithMetricsServerTimeout(timeout time.Duration) MetricsServerOpts {
+	return func(o *metricsServerOptions) {
+		o.timeout = timeout
+	}
+}
+
+// InstallMetricsServer provides an Environment.Func that installs metrics-server, which
+// HorizontalPodAutoscaler tests depend on and kind clusters do not ship, and waits for its
+// deployment to be available. The metrics-server container is started with the
+// --kubelet-insecure-tls flag, as the kubelets of kind clusters serve self-signed certificates.
+//
+// Nothing is installed when the metrics API is already served by the cluster.
+func InstallMetricsServer(opts ...MetricsServerOpts) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		options := &metricsServerOptions{manifestURL: DefaultMetricsServerManifestURL, timeout: 3 * time.Minute}
+		for _, opt := range opts {
+			opt(options)
+		}
+
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("install metrics server func: %w", err)
+		}
+		dc, err := discovery.NewDiscoveryClientForConfig(client.RESTConfig())
+		if err != nil {
+			return ctx, fmt.Errorf("install metrics server func: %w", err)
+		}
+		if _, err := dc.ServerResourcesForGroupVersion(metricsAPIGroupVersion); err == nil {
+			klog.FromContext(ctx).V(2).Info("Metrics API already served, skipping metrics-server installation")
+			return ctx, nil
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, options.manifestURL, nil)
+		if err != nil {
+			return ctx, fmt.Errorf("install metrics server func: %w", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return ctx, fmt.Errorf("install metrics server func: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return ctx, fmt.Errorf("install metrics server func: failed to fetch %s: %s", options.manifestURL, resp.Status)
+		}
+
+		klog.FromContext(ctx).V(2).Info("Installing metrics-server", "manifest", options.manifestURL)
+		res := client.Resources()
+		if err := decoder.DecodeEach(ctx, resp.Body, decoder.CreateIgnoreAlreadyExists(res), decoder.MutateOption(allowInsecureKubeletTLS)); err != nil {
+			return ctx, fmt.Errorf("install metrics server func: %w", err)
+		}
+
+		err = wait.For(conditions.New(res).DeploymentAvailable(metricsServerName, metav1.NamespaceSystem), wait.WithContext(ctx), wait.WithTimeout(options.timeout))
+		if err != nil {
+			return ctx, fmt.Errorf("install metrics server func: deployment not available: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// allowInsecureKubeletTLS adds the --kubelet-insecure-tls flag to the metrics-server container.
+func allowInsecureKubeletTLS(obj k8s.Object) error {
+	deployment, ok := obj.(*appsv1.Deployment)
+	if !ok || deployment.Name != metricsServerName {
+		return nil
+	}
+	for i := range deployment.Spec.Template.Spec.Containers {
+		container := &deployment.Spec.Template.Spec.Containers[i]
+		if container.Name != metricsServerName {
+			continue
+		}
+		for _, arg := range container.Args {
+			if arg == metricsServerInsecureFlag {
+				return nil
+			}
+		}
+		container.Args = append(container.Args, metricsServerInsecureFlag)
+	}
+	return nil
+}
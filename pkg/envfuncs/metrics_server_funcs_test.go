@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestInstallMetricsServer(t *testing.T) {
+	feat := features.New("InstallMetricsServer").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.InstallMetricsServer()(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error installing metrics-server", err)
+			}
+			return ctx
+		}).
+		Assess("node metrics are served", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// equivalent of kubectl top nodes, metrics are available once the nodes have been scraped
+			err := wait.For(func(ctx context.Context) (bool, error) {
+				metrics := &unstructured.UnstructuredList{}
+				metrics.SetGroupVersionKind(schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "NodeMetricsList"})
+				if err := cfg.Client().Resources().List(ctx, metrics); err != nil {
+					return false, nil
+				}
+				return len(metrics.Items) > 0, nil
+			}, wait.WithContext(ctx), wait.WithTimeout(3*time.Minute))
+			if err != nil {
+				t.Fatal("Error waiting for node metrics", err)
+			}
+			return ctx
+		}).
+		Assess("installation skipped when metrics are served", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// an unreachable manifest is never fetched as the metrics API is detected
+			_, err := envfuncs.InstallMetricsServer(envfuncs.WithMetricsServerManifestURL("http://127.0.0.1:0/components.yaml"))(ctx, cfg)
+			if err != nil {
+				t.Error("expected the installation to be skipped", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
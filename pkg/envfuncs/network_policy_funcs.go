@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+// networkPolicyCNIImages are the images of the CNI plugins known to enforce NetworkPolicies.
+var networkPolicyCNIImages = []string{
+	"calico/node",
+	"cilium/cilium",
+	"antrea/antrea",
+	"weaveworks/weave-npc",
+	"cloudnativelabs/kube-router",
+	"kube-network-policies",
+}
+
+// kindnetdPolicyReleaseTag is the tag of the first kindnetd image, shipped with kind v0.24.0, that
+// enforces NetworkPolicies. kindnetd images are tagged with their build date.
+const kindnetdPolicyReleaseTag = "v20240813"
+
+// ApplyNetworkPolicyFromFile provides an Environment.Func that creates the NetworkPolicies
+// declared in the YAML or JSON file at path in namespace. An error is returned if the file
+// declares objects of another kind.
+//
+// The policies are only enforced when the CNI plugin of the cluster supports them; features
+// asserting their effect can be skipped otherwise with SkipWithoutNetworkPolicySupport.
+func ApplyNetworkPolicyFromFile(path, namespace string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("apply network policy func: %w", err)
+		}
+		res := client.Resources(namespace)
+		err = decoder.DecodeEachFile(ctx, os.DirFS(filepath.Dir(path)), filepath.Base(path), func(ctx context.Context, obj k8s.Object) error {
+			if _, ok := obj.(*networkingv1.NetworkPolicy); !ok {
+				return fmt.Errorf("%s: unexpected %s %q, only NetworkPolicies are applied", path, obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName())
+			}
+			klog.FromContext(ctx).V(2).Info("Creating network policy", "name", obj.GetName(), "namespace", namespace)
+			return res.Create(ctx, obj)
+		}, decoder.MutateNamespace(namespace))
+		if err != nil {
+			return ctx, fmt.Errorf("apply network policy func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// SkipWithoutNetworkPolicySupport returns a condition for features.FeatureBuilder.SkipIf that
+// skips features asserting the effect of NetworkPolicies when the cluster runs no CNI plugin
+// known to enforce them, such as the kindnetd of kind releases older than v0.24.0 or flannel.
+//
+// The CNI plugin is identified from the images of the DaemonSets of the cluster, so plugins
+// that are not known to enforce NetworkPolicies are reported as lacking support.
+func SkipWithoutNetworkPolicySupport() types.SkipFunc {
+	return func(ctx context.Context, cfg *envconf.Config) (bool, string) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return true, fmt.Sprintf("network policy support could not be determined: %v", err)
+		}
+		var daemonSets appsv1.DaemonSetList
+		if err := client.Resources().List(ctx, &daemonSets); err != nil {
+			return true, fmt.Sprintf("network policy support could not be determined: %v", err)
+		}
+		for _, ds := range daemonSets.Items {
+			for _, container := range ds.Spec.Template.Spec.Containers {
+				if enforcesNetworkPolicies(container.Image) {
+					return false, ""
+				}
+			}
+		}
+		return true, "the CNI plugin of the cluster does not enforce NetworkPolicies"
+	}
+}
+
+// enforcesNetworkPolicies reports whether image is the image of a CNI plugin enforcing NetworkPolicies.
+func enforcesNetworkPolicies(image string) bool {
+	for _, name := range networkPolicyCNIImages {
+		if strings.Contains(image, name) {
+			return true
+		}
+	}
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > 0 && strings.HasSuffix(image[:i], "/kindnetd") {
+		// tags are of the form v20240813-c6f155d6, which sort by date
+		return image[i+1:] >= kindnetdPolicyReleaseTag
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestApplyNetworkPolicyFromFile(t *testing.T) {
+	namespace := envconf.RandomName("network-policy", 16)
+	labels := map[string]string{"app": "network-policy-server"}
+	server := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "network-policy-server", Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+			},
+		},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "network-policy-server", Namespace: namespace},
+		Spec:       corev1.ServiceSpec{Selector: labels, Ports: []corev1.ServicePort{{Port: 80}}},
+	}
+	client := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "network-policy-client", Namespace: namespace},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:    "busybox",
+			Image:   "busybox",
+			Command: []string{"sleep", "3600"},
+		}}},
+	}
+
+	feat := features.New("ApplyNetworkPolicyFromFile").
+		SkipIf(envfuncs.SkipWithoutNetworkPolicySupport()).
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			res := cfg.Client().Resources()
+			for _, obj := range []k8s.Object{server, service, client} {
+				if err := res.Create(ctx, obj); err != nil {
+					t.Fatal("Error creating object", err)
+				}
+			}
+			err = wait.For(conditions.New(res).DeploymentAvailable(server.Name, namespace), wait.WithTimeout(2*time.Minute))
+			if err != nil {
+				t.Fatal("Error waiting for server deployment", err)
+			}
+			if err := wait.For(conditions.New(res).PodReady(client), wait.WithTimeout(2*time.Minute)); err != nil {
+				t.Fatal("Error waiting for client pod", err)
+			}
+			return ctx
+		}).
+		Assess("traffic allowed without policy", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if err := cfg.Client().Resources().VerifyConnectivity(ctx, client, service, true); err != nil {
+				t.Fatal(err)
+			}
+			return ctx
+		}).
+		Assess("traffic denied by policy", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.ApplyNetworkPolicyFromFile("testdata/network-policies/deny-ingress.yaml", namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := cfg.Client().Resources()
+			var lastErr error
+			// policies take effect asynchronously
+			err = wait.For(func(ctx context.Context) (bool, error) {
+				lastErr = res.VerifyConnectivity(ctx, client, service, false)
+				return lastErr == nil, nil
+			}, wait.WithTimeout(time.Minute), wait.WithInterval(2*time.Second))
+			if err != nil {
+				t.Fatal("traffic not denied by policy:", lastErr)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// drainTimeout bounds how long DrainNode retries the evictions blocked by PodDisruptionBudgets, and
+// then waits for the evicted pods to terminate.
+const drainTimeout = 2 * time.Minute
+
+// drainRetryInterval is the interval between the attempts of DrainNode to evict blocked pods.
+const drainRetryInterval = 5 * time.Second
+
+// LabelNodes provides an Environment.Func that adds the labels add to the nodes matching
+// selector, such as to test the nodeSelector or the affinity of workloads. A nil selector
+// matches every node, which on the single node of a default kind cluster labels that node.
+// An error is returned when no node matches selector.
+//
+// UnlabelNodes removes the labels, for instance in Finish.
+func LabelNodes(selector labels.Selector, add map[string]string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		patchLabels := make(map[string]interface{}, len(add))
+		for key, value := range add {
+			patchLabels[key] = value
+		}
+		if err := patchNodeLabels(ctx, cfg, selector, patchLabels); err != nil {
+			return ctx, fmt.Errorf("label nodes func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// UnlabelNodes provides an Environment.Func that removes the keys of remove from the labels of
+// the nodes matching selector, undoing LabelNodes. A nil selector matches every node.
+func UnlabelNodes(selector labels.Selector, remove map[string]string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		patchLabels := make(map[string]interface{}, len(remove))
+		for key := range remove {
+			// a null value removes the label with a merge patch
+			patchLabels[key] = nil
+		}
+		if err := patchNodeLabels(ctx, cfg, selector, patchLabels); err != nil {
+			return ctx, fmt.Errorf("unlabel nodes func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// patchNodeLabels applies the labels of patchLabels to the nodes matching selector with a merge patch.
+func patchNodeLabels(ctx context.Context, cfg *envconf.Config, selector labels.Selector, patchLabels map[string]interface{}) error {
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	client, err := cfg.NewClient()
+	if err != nil {
+		return err
+	}
+	res := client.Resources()
+	var nodes corev1.NodeList
+	if err := res.List(ctx, &nodes, resources.WithLabelSelector(selector.String())); err != nil {
+		return err
+	}
+	if len(nodes.Items) == 0 {
+		return fmt.Errorf("no node matches selector %q", selector.String())
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"labels": patchLabels}})
+	if err != nil {
+		return err
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		klog.FromContext(ctx).V(2).Info("Patching node labels", "node", node.Name, "labels", patchLabels)
+		if err := res.Patch(ctx, node, k8s.Patch{PatchType: types.MergePatchType, Data: data}); err != nil {
+			return fmt.Errorf("node %s: %w", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// TaintNode provides an Environment.Func that adds taint to the node name, such as to test the
+// tolerations of workloads on a multi-node kind cluster. Tainting a node already holding taint is a
+// no-op, while an error is returned if the node holds a taint with the key and effect of taint but
+// another value.
+//
+// UntaintNode removes the taint, and is meant to be paired with TaintNode as its Finish counterpart.
+func TaintNode(name string, taint corev1.Taint) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		err := updateNodeTaints(ctx, cfg, name, func(taints []corev1.Taint) ([]corev1.Taint, error) {
+			for _, existing := range taints {
+				if !existing.MatchTaint(&taint) {
+					continue
+				}
+				if existing.Value != taint.Value {
+					return nil, fmt.Errorf("node %s already has the conflicting taint %s", name, existing.ToString())
+				}
+				return taints, nil
+			}
+			return append(taints, taint), nil
+		})
+		if err != nil {
+			return ctx, fmt.Errorf("taint node func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// UntaintNode provides an Environment.Func that removes the taint with the key and effect of taint
+// from the node name, undoing TaintNode. Untainting a node not holding the taint is a no-op.
+func UntaintNode(name string, taint corev1.Taint) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		err := updateNodeTaints(ctx, cfg, name, func(taints []corev1.Taint) ([]corev1.Taint, error) {
+			remaining := make([]corev1.Taint, 0, len(taints))
+			for _, existing := range taints {
+				if !existing.MatchTaint(&taint) {
+					remaining = append(remaining, existing)
+				}
+			}
+			return remaining, nil
+		})
+		if err != nil {
+			return ctx, fmt.Errorf("untaint node func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// updateNodeTaints replaces the taints of the node name with the ones returned by update, retrying
+// on conflicts. The node is left untouched when its taints are unchanged.
+func updateNodeTaints(ctx context.Context, cfg *envconf.Config, name string, update func([]corev1.Taint) ([]corev1.Taint, error)) error {
+	client, err := cfg.NewClient()
+	if err != nil {
+		return err
+	}
+	res := client.Resources()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var node corev1.Node
+		if err := res.Get(ctx, name, "", &node); err != nil {
+			return err
+		}
+		taints, err := update(append([]corev1.Taint(nil), node.Spec.Taints...))
+		if err != nil {
+			return err
+		}
+		if equality.Semantic.DeepEqual(taints, node.Spec.Taints) {
+			return nil
+		}
+		klog.FromContext(ctx).V(2).Info("Updating node taints", "node", name, "taints", taints)
+		node.Spec.Taints = taints
+		return res.Update(ctx, &node)
+	})
+}
+
+// CordonNode provides an Environment.Func that marks the node name unschedulable, so that no new
+// pod is scheduled on it. UncordonNode undoes it.
+func CordonNode(name string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if err := setNodeUnschedulable(ctx, cfg, name, true); err != nil {
+			return ctx, fmt.Errorf("cordon node func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// UncordonNode provides an Environment.Func that marks the node name schedulable again, undoing
+// CordonNode and DrainNode. It is meant to be paired with DrainNode as its Finish counterpart.
+func UncordonNode(name string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if err := setNodeUnschedulable(ctx, cfg, name, false); err != nil {
+			return ctx, fmt.Errorf("uncordon node func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// DrainNode provides an Environment.Func that cordons the node name and evicts its pods, as
+// `kubectl drain` does, such as to test the resilience of an application on a multi-node kind
+// cluster. The pods of DaemonSets, mirror pods and terminated pods are left on the node. Each
+// evicted pod is given gracePeriod to terminate, or the grace period of its spec when zero.
+//
+// Evictions honor PodDisruptionBudgets: the evictions they block are retried for up to two
+// minutes, after which the returned error lists the pods that could not be evicted. The function
+// returns once the evicted pods are gone. Pair it with UncordonNode in Finish.
+func DrainNode(name string, gracePeriod time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if err := setNodeUnschedulable(ctx, cfg, name, true); err != nil {
+			return ctx, fmt.Errorf("drain node func: %w", err)
+		}
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("drain node func: %w", err)
+		}
+		clientset, err := kubernetes.NewForConfig(client.RESTConfig())
+		if err != nil {
+			return ctx, fmt.Errorf("drain node func: %w", err)
+		}
+		res := client.Resources()
+		var pods corev1.PodList
+		if err := res.List(ctx, &pods, resources.WithFieldSelector("spec.nodeName="+name)); err != nil {
+			return ctx, fmt.Errorf("drain node func: %w", err)
+		}
+
+		var deleteOptions *metav1.DeleteOptions
+		if gracePeriod > 0 {
+			seconds := int64(gracePeriod.Seconds())
+			deleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: &seconds}
+		}
+		pending := make(map[string]*corev1.Pod)
+		for i := range pods.Items {
+			if pod := &pods.Items[i]; drainable(pod) {
+				pending[pod.Namespace+"/"+pod.Name] = pod
+			}
+		}
+		evicted := make([]*corev1.Pod, 0, len(pending))
+		var evictErr error
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			for key, pod := range pending {
+				eviction := &policyv1.Eviction{
+					ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+					DeleteOptions: deleteOptions,
+				}
+				err := clientset.CoreV1().Pods(pod.Namespace).EvictV1(ctx, eviction)
+				switch {
+				case err == nil, apierrors.IsNotFound(err):
+					klog.FromContext(ctx).V(2).Info("Evicted pod", "pod", key, "node", name)
+					evicted = append(evicted, pod)
+					delete(pending, key)
+				case apierrors.IsTooManyRequests(err):
+					// blocked by a PodDisruptionBudget, retried
+				default:
+					evictErr = fmt.Errorf("evicting pod %s: %w", key, err)
+					return false, evictErr
+				}
+			}
+			return len(pending) == 0, nil
+		}, wait.WithContext(ctx), wait.WithTimeout(drainTimeout), wait.WithInterval(drainRetryInterval), wait.WithImmediate())
+		if err != nil && evictErr == nil && len(pending) > 0 {
+			blocked := make([]string, 0, len(pending))
+			for key := range pending {
+				blocked = append(blocked, key)
+			}
+			sort.Strings(blocked)
+			return ctx, fmt.Errorf("drain node func: pods of node %s not evicted, as their PodDisruptionBudgets do not allow it: %s: %w", name, strings.Join(blocked, ", "), err)
+		}
+		if err != nil {
+			return ctx, fmt.Errorf("drain node func: %w", err)
+		}
+
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			for _, pod := range evicted {
+				var current corev1.Pod
+				err := res.Get(ctx, pod.Name, pod.Namespace, &current)
+				if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+					continue
+				}
+				return false, err
+			}
+			return true, nil
+		}, wait.WithContext(ctx), wait.WithTimeout(drainTimeout), wait.WithImmediate())
+		if err != nil {
+			return ctx, fmt.Errorf("drain node func: evicted pods of node %s not terminated: %w", name, err)
+		}
+		return ctx, nil
+	}
+}
+
+// drainable reports whether DrainNode evicts pod.
+func drainable(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	owner := metav1.GetControllerOf(pod)
+	return owner == nil || owner.Kind != "DaemonSet"
+}
+
+// setNodeUnschedulable sets the unschedulable field of the spec of the node name.
+func setNodeUnschedulable(ctx context.Context, cfg *envconf.Config, name string, unschedulable bool) error {
+	client, err := cfg.NewClient()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"unschedulable": unschedulable}})
+	if err != nil {
+		return err
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	klog.FromContext(ctx).V(2).Info("Setting node schedulability", "node", name, "unschedulable", unschedulable)
+	return client.Resources().Patch(ctx, node, k8s.Patch{PatchType: types.MergePatchType, Data: data})
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+	"sigs.k8s.io/e2e-framework/support/kind"
+)
+
+func TestLabelNodes(t *testing.T) {
+	namespace := envconf.RandomName("label-nodes", 16)
+	nodeLabels := map[string]string{"e2e-framework.sigs.k8s.io/scheduling-test": "label-nodes"}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-selector-test", Namespace: namespace},
+		Spec: corev1.PodSpec{
+			NodeSelector: nodeLabels,
+			Containers:   []corev1.Container{{Name: "nginx", Image: "nginx"}},
+		},
+	}
+
+	feat := features.New("LabelNodes").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// the nil selector labels the single node of the test cluster
+			ctx, err := envfuncs.LabelNodes(nil, nodeLabels)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, err = envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			return ctx
+		}).
+		Assess("pod scheduled on the labeled node", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			res := cfg.Client().Resources()
+			if err := res.Create(ctx, pod); err != nil {
+				t.Fatal("Error creating pod", err)
+			}
+			err := wait.For(conditions.New(res).PodConditionMatch(pod, corev1.PodScheduled, corev1.ConditionTrue), wait.WithTimeout(time.Minute))
+			if err != nil {
+				t.Fatal("Error waiting for the pod to be scheduled", err)
+			}
+			return ctx
+		}).
+		Assess("labels removed", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.UnlabelNodes(nil, nodeLabels)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var nodes corev1.NodeList
+			if err := cfg.Client().Resources().List(ctx, &nodes); err != nil {
+				t.Fatal("Error listing nodes", err)
+			}
+			for _, node := range nodes.Items {
+				if _, ok := node.Labels["e2e-framework.sigs.k8s.io/scheduling-test"]; ok {
+					t.Errorf("expected the label to be removed from node %s", node.Name)
+				}
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
+
+func TestTaintNode(t *testing.T) {
+	namespace := envconf.RandomName("taint-node", 16)
+	taint := corev1.Taint{Key: "e2e-framework.sigs.k8s.io/scheduling-test", Value: "taint-node", Effect: corev1.TaintEffectNoSchedule}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "toleration-test", Namespace: namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+	}
+	var nodeName string
+
+	feat := features.New("TaintNode").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			var nodes corev1.NodeList
+			if err := cfg.Client().Resources().List(ctx, &nodes); err != nil || len(nodes.Items) != 1 {
+				t.Fatalf("expected the single node of the test cluster, got %d nodes: %v", len(nodes.Items), err)
+			}
+			nodeName = nodes.Items[0].Name
+			ctx, err := envfuncs.TaintNode(nodeName, taint)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, err = envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			return ctx
+		}).
+		Assess("taint idempotent", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.TaintNode(nodeName, taint)(ctx, cfg)
+			if err != nil {
+				t.Errorf("expected tainting the node again to be a no-op, got %v", err)
+			}
+			conflicting := taint
+			conflicting.Value = "other"
+			if _, err := envfuncs.TaintNode(nodeName, conflicting)(ctx, cfg); err == nil {
+				t.Error("expected an error tainting the node with a conflicting value")
+			}
+			return ctx
+		}).
+		Assess("pod without toleration pending", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			res := cfg.Client().Resources()
+			if err := res.Create(ctx, pod); err != nil {
+				t.Fatal("Error creating pod", err)
+			}
+			err := wait.For(conditions.New(res).ResourceMatch(pod, func(obj k8s.Object) bool {
+				for _, condition := range obj.(*corev1.Pod).Status.Conditions {
+					if condition.Type == corev1.PodScheduled {
+						return condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable
+					}
+				}
+				return false
+			}), wait.WithTimeout(time.Minute))
+			if err != nil {
+				t.Fatal("Error waiting for the pod to be unschedulable", err)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.UntaintNode(nodeName, taint)(ctx, cfg)
+			if err != nil {
+				t.Error(err)
+			}
+			ctx, err = envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
+
+func TestDrainNode(t *testing.T) {
+	clusterName := envconf.RandomName("drain-cluster", 16)
+	cfg := envconf.New()
+	ctx, err := envfuncs.CreateClusterWithConfig(kind.NewProvider(), clusterName, "testdata/kind-multi-node.yaml")(context.TODO(), cfg)
+	if err != nil {
+		t.Fatal("Error creating cluster", err)
+	}
+	defer func() {
+		if _, err := envfuncs.DestroyCluster(clusterName)(ctx, cfg); err != nil {
+			t.Error("Error destroying cluster", err)
+		}
+	}()
+
+	replicas := int32(2)
+	labels := map[string]string{"app": "drain-test"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "drain-test", Namespace: metav1.NamespaceDefault},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+			},
+		},
+	}
+	res := cfg.Client().Resources(metav1.NamespaceDefault)
+	if err := res.Create(ctx, deployment); err != nil {
+		t.Fatal("Error creating deployment", err)
+	}
+	if err := wait.For(conditions.New(res).DeploymentAvailable(deployment.Name, deployment.Namespace), wait.WithTimeout(3*time.Minute)); err != nil {
+		t.Fatal("Error waiting for deployment", err)
+	}
+
+	var pods corev1.PodList
+	if err := res.List(ctx, &pods, resources.WithLabelSelector("app=drain-test")); err != nil {
+		t.Fatal("Error listing pods", err)
+	}
+	node := pods.Items[0].Spec.NodeName
+	ctx, err = envfuncs.DrainNode(node, 5*time.Second)(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := envfuncs.UncordonNode(node)(ctx, cfg); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	err = wait.For(conditions.New(res).ResourceListMatchN(&pods, int(replicas), func(obj k8s.Object) bool {
+		pod := obj.(*corev1.Pod)
+		return pod.Spec.NodeName != node && pod.Status.Phase == corev1.PodRunning
+	}, resources.WithLabelSelector("app=drain-test")), wait.WithTimeout(3*time.Minute))
+	if err != nil {
+		t.Fatal("Error waiting for the pods to be rescheduled off the drained node", err)
+	}
+	var drained corev1.Node
+	if err := res.Get(ctx, node, "", &drained); err != nil {
+		t.Fatal("Error getting node", err)
+	}
+	if !drained.Spec.Unschedulable {
+		t.Errorf("expected node %s to be cordoned", node)
+	}
+}
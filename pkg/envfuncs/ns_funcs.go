@@ -18,17 +18,27 @@ package envfuncs
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	klog "k8s.io/klog/v2"
 	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 )
 
 type NamespaceContextKey string
 
+// randomNamespaceSuffixLength is the number of random characters appended to
+// the prefix of namespaces created with CreateRandomNamespace.
+const randomNamespaceSuffixLength = 8
+
 type CreateNamespaceOpts func(klient.Client, *corev1.Namespace)
 
 // WithLabels provides an option to set custom labels on the namespace.
@@ -54,6 +64,9 @@ func WithAnnotations(annotations map[string]string) CreateNamespaceOpts {
 // for subsequent call.
 func CreateNamespace(name string, opts ...CreateNamespaceOpts) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return ctx, fmt.Errorf("create namespace func: invalid namespace name %q: %s", name, strings.Join(errs, "; "))
+		}
 		namespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
 		client, err := cfg.NewClient()
 		if err != nil {
@@ -62,6 +75,7 @@ func CreateNamespace(name string, opts ...CreateNamespaceOpts) env.Func {
 		for _, opt := range opts {
 			opt(client, &namespace)
 		}
+		klog.FromContext(ctx).V(2).Info("Creating namespace", "namespace", name)
 		if err := client.Resources().Create(ctx, &namespace); err != nil {
 			return ctx, fmt.Errorf("create namespace func: %w", err)
 		}
@@ -70,6 +84,72 @@ func CreateNamespace(name string, opts ...CreateNamespaceOpts) env.Func {
 	}
 }
 
+// CreateRandomNamespace provides an Environment.Func that creates a new
+// namespace named after prefix followed by a random suffix. The generated
+// name is made available via the env config as with CreateNamespace.
+//
+// An error is returned before contacting the API server if the prefix leaves
+// no room for the random suffix within the DNS-1123 label length limit.
+func CreateRandomNamespace(prefix string, opts ...CreateNamespaceOpts) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		n := len(prefix) + randomNamespaceSuffixLength
+		if prefix != "" {
+			n++ // separator between prefix and suffix
+		}
+		if n > validation.DNS1123LabelMaxLength {
+			return ctx, fmt.Errorf(
+				"create namespace func: prefix %q is too long: it must be at most %d characters to fit a random suffix",
+				prefix, validation.DNS1123LabelMaxLength-randomNamespaceSuffixLength-1,
+			)
+		}
+		return CreateNamespace(envconf.RandomName(prefix, n), opts...)(ctx, cfg)
+	}
+}
+
+// Pod Security Standards levels that can be enforced on a namespace with SetNamespacePodSecurity.
+const (
+	PodSecurityPrivileged = "privileged"
+	PodSecurityBaseline   = "baseline"
+	PodSecurityRestricted = "restricted"
+)
+
+// podSecurityModes are the Pod Security Admission modes whose label is set by SetNamespacePodSecurity.
+var podSecurityModes = []string{"enforce", "audit", "warn"}
+
+// SetNamespacePodSecurity provides an Environment.Func that labels the named
+// namespace with the pod-security.kubernetes.io/enforce, audit and warn labels
+// set to level, which is one of privileged, baseline or restricted. Setting the
+// privileged level allows running test workloads requiring elevated permissions
+// in clusters enforcing Pod Security Admission.
+func SetNamespacePodSecurity(namespace string, level string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		switch level {
+		case PodSecurityPrivileged, PodSecurityBaseline, PodSecurityRestricted:
+		default:
+			return ctx, fmt.Errorf("set namespace pod security func: invalid level %q", level)
+		}
+		labels := make(map[string]string, len(podSecurityModes))
+		for _, mode := range podSecurityModes {
+			labels["pod-security.kubernetes.io/"+mode] = level
+		}
+		patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}})
+		if err != nil {
+			return ctx, fmt.Errorf("set namespace pod security func: %w", err)
+		}
+
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("set namespace pod security func: %w", err)
+		}
+		klog.FromContext(ctx).V(2).Info("Setting namespace pod security level", "namespace", namespace, "level", level)
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+		if err := client.Resources().Patch(ctx, ns, k8s.Patch{PatchType: types.MergePatchType, Data: patch}); err != nil {
+			return ctx, fmt.Errorf("set namespace pod security func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
 // DeleteNamespace provides an Environment.Func that deletes the named
 // namespace. It first searches for the ns in its context, if not found then
 // attempt to retrieve it from the API server. Then deletes it.
@@ -105,6 +185,7 @@ func DeleteNamespace(name string) env.Func {
 		}
 
 		// remove namespace api object
+		klog.FromContext(ctx).V(2).Info("Deleting namespace", "namespace", name)
 		if err := client.Resources().Delete(ctx, namespace); err != nil {
 			return ctx, fmt.Errorf("delete namespace func: %w", err)
 		}
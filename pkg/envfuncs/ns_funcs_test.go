@@ -19,6 +19,7 @@ package envfuncs_test
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
@@ -117,6 +118,150 @@ func TestCreateNamespace(t *testing.T) {
 	nsTestenv.Test(t, feats...)
 }
 
+func TestCreateRandomNamespace(t *testing.T) {
+	prefix := "random-ns"
+	feat := features.New("CreateRandomNamespace").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateRandomNamespace(prefix)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			return ctx
+		}).
+		Assess("namespace created with prefix", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if !strings.HasPrefix(cfg.Namespace(), prefix+"-") {
+				t.Errorf("expected namespace %q to start with %q", cfg.Namespace(), prefix+"-")
+			}
+			var ns corev1.Namespace
+			if err := cfg.Client().Resources().Get(ctx, cfg.Namespace(), cfg.Namespace(), &ns); err != nil {
+				t.Fatal("error getting namespace", err)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(cfg.Namespace())(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
+
+func TestCreateNamespaceInvalidName(t *testing.T) {
+	longPrefix := strings.Repeat("a", 60)
+	tests := []struct {
+		name     string
+		fn       func(ctx context.Context, cfg *envconf.Config) (context.Context, error)
+		expected string
+	}{
+		{
+			name:     "prefix too long",
+			fn:       envfuncs.CreateRandomNamespace(longPrefix),
+			expected: "is too long",
+		},
+		{
+			name:     "name too long",
+			fn:       envfuncs.CreateNamespace(envconf.RandomName(longPrefix, 70)),
+			expected: "invalid namespace name",
+		},
+		{
+			name:     "name with invalid characters",
+			fn:       envfuncs.CreateNamespace("Invalid_Name"),
+			expected: "invalid namespace name",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the config has no client configured: an error from the API is not expected
+			_, err := test.fn(context.TODO(), envconf.New())
+			if err == nil {
+				t.Fatal("expected an error for an invalid namespace name")
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected error to contain %q, got: %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestSetNamespacePodSecurity(t *testing.T) {
+	namespace := envconf.RandomName("pod-security", 16)
+	privileged := true
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: "privileged-pod", Namespace: namespace},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name:            "nginx",
+				Image:           "nginx",
+				SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+			}}},
+		}
+	}
+	feat := features.New("SetNamespacePodSecurity").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			return ctx
+		}).
+		Assess("restricted level rejects privileged pods", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.SetNamespacePodSecurity(namespace, envfuncs.PodSecurityRestricted)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error setting namespace pod security", err)
+			}
+			if err := cfg.Client().Resources().Create(ctx, newPod()); !errors.IsForbidden(err) {
+				t.Errorf("expected the privileged pod to be forbidden, got: %v", err)
+			}
+			return ctx
+		}).
+		Assess("privileged level allows privileged pods", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.SetNamespacePodSecurity(namespace, envfuncs.PodSecurityPrivileged)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error setting namespace pod security", err)
+			}
+			var ns corev1.Namespace
+			if err := cfg.Client().Resources().Get(ctx, namespace, namespace, &ns); err != nil {
+				t.Fatal("error getting namespace", err)
+			}
+			for _, mode := range []string{"enforce", "audit", "warn"} {
+				if level := ns.Labels["pod-security.kubernetes.io/"+mode]; level != envfuncs.PodSecurityPrivileged {
+					t.Errorf("expected %s level %q, got %q", mode, envfuncs.PodSecurityPrivileged, level)
+				}
+			}
+			pod := newPod()
+			if err := cfg.Client().Resources().Create(ctx, pod); err != nil {
+				t.Fatal("error creating privileged pod", err)
+			}
+			err = wait.For(conditions.New(cfg.Client().Resources()).PodConditionMatch(pod, corev1.PodScheduled, corev1.ConditionTrue))
+			if err != nil {
+				t.Error("privileged pod was not scheduled", err)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
+
+func TestSetNamespacePodSecurityInvalidLevel(t *testing.T) {
+	_, err := envfuncs.SetNamespacePodSecurity("default", "unrestricted")(context.TODO(), envconf.New())
+	if err == nil || !strings.Contains(err.Error(), "invalid level") {
+		t.Errorf("expected an invalid level error, got: %v", err)
+	}
+}
+
 func TestDeleteNamespace(t *testing.T) {
 	var ns corev1.Namespace
 	namespace := envconf.RandomName("delete-ns", 16)
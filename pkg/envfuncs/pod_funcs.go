@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// WaitForAllPodsReady provides an Environment.Func that blocks until every pod
+// in the namespace is Ready, or has Succeeded as pods run by jobs do. Failed pods
+// owned by a job that has since completed are ignored, as the job retried them.
+//
+// If the pods do not settle within timeout, the returned error lists the pods
+// that are not ready yet.
+func WaitForAllPodsReady(namespace string, timeout time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("wait for pods ready func: %w", err)
+		}
+		if err := waitForPodsReady(ctx, client.Resources(namespace), namespace, timeout); err != nil {
+			return ctx, fmt.Errorf("wait for pods ready func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// WaitForClusterReady provides an Environment.Func that blocks until a newly
+// created cluster can run workloads: the default service account exists and
+// every pod of the kube-system namespace, such as CoreDNS, is Ready. It is meant
+// to follow CreateCluster, which returns as soon as the API server is up.
+//
+// If the cluster is not ready within timeout, the returned error names the
+// system components that lagged.
+func WaitForClusterReady(timeout time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("wait for cluster ready func: %w", err)
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		res := client.Resources()
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			var sa corev1.ServiceAccount
+			if err := res.Get(ctx, "default", metav1.NamespaceDefault, &sa); err != nil {
+				if apierrors.IsNotFound(err) {
+					return false, nil
+				}
+				return false, err
+			}
+			return true, nil
+		}, wait.WithContext(waitCtx), wait.WithTimeout(timeout), wait.WithImmediate())
+		if err != nil {
+			return ctx, fmt.Errorf("wait for cluster ready func: default service account not created: %w", err)
+		}
+
+		// waitCtx bounds both waits to timeout
+		if err := waitForPodsReady(waitCtx, client.Resources(metav1.NamespaceSystem), metav1.NamespaceSystem, timeout); err != nil {
+			return ctx, fmt.Errorf("wait for cluster ready func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// waitForPodsReady waits for the pods of namespace to settle, as described by WaitForAllPodsReady.
+func waitForPodsReady(ctx context.Context, res *resources.Resources, namespace string, timeout time.Duration) error {
+	var notReady []string
+	err := wait.For(func(ctx context.Context) (bool, error) {
+		var jobs batchv1.JobList
+		if err := res.List(ctx, &jobs); err != nil {
+			return false, err
+		}
+		completedJobs := make(map[string]bool)
+		for _, job := range jobs.Items {
+			for _, cond := range job.Status.Conditions {
+				if cond.Type == batchv1.JobComplete && cond.Status == corev1.ConditionTrue {
+					completedJobs[job.Name] = true
+				}
+			}
+		}
+
+		var pods corev1.PodList
+		if err := res.List(ctx, &pods); err != nil {
+			return false, err
+		}
+		notReady = notReady[:0]
+		for i := range pods.Items {
+			if !podSettled(&pods.Items[i], completedJobs) {
+				notReady = append(notReady, pods.Items[i].Name)
+			}
+		}
+		return len(notReady) == 0, nil
+	}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+	if err != nil && len(notReady) > 0 {
+		return fmt.Errorf("pods not ready in namespace %q: %s: %w", namespace, strings.Join(notReady, ", "), err)
+	}
+	return err
+}
+
+// podSettled reports whether the pod is Ready, has Succeeded or is a failed
+// attempt of a job found in completedJobs.
+func podSettled(pod *corev1.Pod, completedJobs map[string]bool) bool {
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return true
+	case corev1.PodFailed:
+		owner := metav1.GetControllerOf(pod)
+		return owner != nil && owner.Kind == "Job" && completedJobs[owner.Name]
+	}
+	return podReady(pod)
+}
+
+// podReady reports whether the PodReady condition of pod is true.
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestWaitForAllPodsReady(t *testing.T) {
+	namespace := envconf.RandomName("pods-ready", 16)
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "pods-ready-test", Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "pods-ready-test"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "pods-ready-test"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+			},
+		},
+	}
+
+	feat := features.New("WaitForAllPodsReady").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			if err := cfg.Client().Resources().Create(ctx, deployment); err != nil {
+				t.Fatal("Error creating deployment", err)
+			}
+			return ctx
+		}).
+		Assess("all pods ready", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// wait for the pods to be scheduled before checking their readiness
+			var pods corev1.PodList
+			err := wait.For(conditions.New(cfg.Client().Resources(namespace)).ResourceListN(&pods, int(replicas)), wait.WithTimeout(time.Minute))
+			if err != nil {
+				t.Fatal("Error waiting for pods to be scheduled", err)
+			}
+			ctx, err = envfuncs.WaitForAllPodsReady(namespace, 2*time.Minute)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.Client().Resources(namespace).List(ctx, &pods); err != nil {
+				t.Fatal("Error listing pods", err)
+			}
+			for _, pod := range pods.Items {
+				if pod.Status.Phase != corev1.PodRunning {
+					t.Errorf("expected pod %s to be running, got phase %s", pod.Name, pod.Status.Phase)
+				}
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
+
+func TestWaitForAllPodsReadyTimeout(t *testing.T) {
+	namespace := envconf.RandomName("pods-stuck", 16)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "stuck-pod", Namespace: namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "stuck", Image: "does-not-exist.invalid/image:latest"}}},
+	}
+
+	feat := features.New("WaitForAllPodsReady timeout").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			if err := cfg.Client().Resources().Create(ctx, pod); err != nil {
+				t.Fatal("Error creating pod", err)
+			}
+			return ctx
+		}).
+		Assess("stuck pod reported", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			_, err := envfuncs.WaitForAllPodsReady(namespace, 10*time.Second)(ctx, cfg)
+			if err == nil {
+				t.Fatal("expected an error for a pod that never becomes ready")
+			}
+			if !strings.Contains(err.Error(), pod.Name) {
+				t.Errorf("expected the error to name the stuck pod, got: %v", err)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
+
+func TestWaitForClusterReady(t *testing.T) {
+	// WaitForClusterReady is part of the setup of nsTestenv, so CoreDNS
+	// is expected to be ready before the first feature is tested.
+	feat := features.New("WaitForClusterReady").
+		Assess("coredns ready", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			var pods corev1.PodList
+			err := cfg.Client().Resources(metav1.NamespaceSystem).List(ctx, &pods, resources.WithLabelSelector("k8s-app=kube-dns"))
+			if err != nil {
+				t.Fatal("Error listing coredns pods", err)
+			}
+			if len(pods.Items) == 0 {
+				t.Fatal("expected coredns pods in the kube-system namespace")
+			}
+			for _, pod := range pods.Items {
+				ready := false
+				for _, cond := range pod.Status.Conditions {
+					if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+						ready = true
+					}
+				}
+				if !ready {
+					t.Errorf("expected coredns pod %s to be ready", pod.Name)
+				}
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
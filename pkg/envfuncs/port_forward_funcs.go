@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// portForwardPodTimeout is how long PortForwardService waits for a ready pod to back the service.
+const portForwardPodTimeout = time.Minute
+
+type portForwardContextKey string
+
+// portForward is the state of a port-forward stored in the context by PortForwardService.
+type portForward struct {
+	address string
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// PortForwardService provides a pair of Environment.Func to use in Setup and Finish that
+// forward localPort to the port remotePort of the service serviceName for the duration of the
+// suite, so features can reach the service over localhost. When localPort is 0, a free local
+// port is picked. The local address, such as "127.0.0.1:8080", can be retrieved in features
+// using GetPortForwardAddressFromContext.
+//
+// As with kubectl port-forward, the traffic is forwarded to a single ready pod selected by the
+// service, which is picked when the setup function runs.
+func PortForwardService(namespace, serviceName string, localPort, remotePort int) (setup env.Func, finish env.Func) {
+	key := portForwardContextKey(namespace + "/" + serviceName)
+
+	setup = func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("port forward service func: %w", err)
+		}
+		pod, podPort, err := servicePodPort(ctx, client.Resources(namespace), namespace, serviceName, remotePort)
+		if err != nil {
+			return ctx, fmt.Errorf("port forward service func: %w", err)
+		}
+
+		restConfig := client.RESTConfig()
+		transport, upgrader, err := spdy.RoundTripperFor(restConfig)
+		if err != nil {
+			return ctx, fmt.Errorf("port forward service func: %w", err)
+		}
+		hostURL, err := url.Parse(restConfig.Host)
+		if err != nil {
+			return ctx, fmt.Errorf("port forward service func: %w", err)
+		}
+		hostURL.Path += fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/portforward", namespace, pod)
+		dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, hostURL)
+
+		pf := &portForward{stopCh: make(chan struct{}), doneCh: make(chan struct{})}
+		readyCh := make(chan struct{})
+		forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("%d:%d", localPort, podPort)}, pf.stopCh, readyCh, io.Discard, io.Discard)
+		if err != nil {
+			return ctx, fmt.Errorf("port forward service func: %w", err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			defer close(pf.doneCh)
+			errCh <- forwarder.ForwardPorts()
+		}()
+		select {
+		case <-readyCh:
+		case err := <-errCh:
+			return ctx, fmt.Errorf("port forward service func: %w", err)
+		case <-ctx.Done():
+			close(pf.stopCh)
+			return ctx, fmt.Errorf("port forward service func: %w", ctx.Err())
+		}
+
+		ports, err := forwarder.GetPorts()
+		if err != nil {
+			close(pf.stopCh)
+			return ctx, fmt.Errorf("port forward service func: %w", err)
+		}
+		pf.address = fmt.Sprintf("127.0.0.1:%d", ports[0].Local)
+		klog.FromContext(ctx).V(2).Info("Forwarding port to service", "service", serviceName, "namespace", namespace, "pod", pod, "address", pf.address)
+		return context.WithValue(ctx, key, pf), nil
+	}
+
+	finish = func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		pf, ok := ctx.Value(key).(*portForward)
+		if !ok {
+			return ctx, fmt.Errorf("port forward service func: no port-forward to service %s/%s found in context", namespace, serviceName)
+		}
+		close(pf.stopCh)
+		<-pf.doneCh
+		return ctx, nil
+	}
+
+	return setup, finish
+}
+
+// GetPortForwardAddressFromContext returns the local address forwarded to the service
+// serviceName by PortForwardService.
+func GetPortForwardAddressFromContext(ctx context.Context, namespace, serviceName string) (string, bool) {
+	pf, ok := ctx.Value(portForwardContextKey(namespace + "/" + serviceName)).(*portForward)
+	if !ok {
+		return "", false
+	}
+	return pf.address, true
+}
+
+// servicePodPort returns the name of a ready pod selected by the service serviceName, along
+// with the container port its service port servicePort targets.
+func servicePodPort(ctx context.Context, res *resources.Resources, namespace, serviceName string, servicePort int) (string, int, error) {
+	var svc corev1.Service
+	if err := res.Get(ctx, serviceName, namespace, &svc); err != nil {
+		return "", 0, err
+	}
+	var svcPort *corev1.ServicePort
+	for i := range svc.Spec.Ports {
+		if int(svc.Spec.Ports[i].Port) == servicePort {
+			svcPort = &svc.Spec.Ports[i]
+			break
+		}
+	}
+	if svcPort == nil {
+		return "", 0, fmt.Errorf("service %s does not expose port %d", serviceName, servicePort)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return "", 0, fmt.Errorf("service %s has no selector", serviceName)
+	}
+
+	var pod *corev1.Pod
+	err := wait.For(func(ctx context.Context) (bool, error) {
+		var pods corev1.PodList
+		if err := res.List(ctx, &pods, resources.WithLabelSelector(labels.SelectorFromSet(svc.Spec.Selector).String())); err != nil {
+			return false, err
+		}
+		for i := range pods.Items {
+			if podReady(&pods.Items[i]) {
+				pod = &pods.Items[i]
+				return true, nil
+			}
+		}
+		return false, nil
+	}, wait.WithContext(ctx), wait.WithTimeout(portForwardPodTimeout), wait.WithImmediate())
+	if err != nil {
+		return "", 0, fmt.Errorf("no ready pod found for service %s: %w", serviceName, err)
+	}
+
+	target := svcPort.TargetPort
+	if target.IntValue() != 0 {
+		return pod.Name, target.IntValue(), nil
+	}
+	if target.StrVal == "" {
+		return pod.Name, servicePort, nil
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == target.StrVal && port.Protocol == svcPort.Protocol {
+				return pod.Name, int(port.ContainerPort), nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("pod %s does not expose the port %q targeted by service %s", pod.Name, target.StrVal, serviceName)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestPortForwardService(t *testing.T) {
+	namespace := envconf.RandomName("port-forward", 16)
+	labels := map[string]string{"app": "port-forward-test"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "port-forward-test", Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{
+					Name:  "nginx",
+					Image: "nginx",
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 80}},
+				}}},
+			},
+		},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "port-forward-test", Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports:    []corev1.ServicePort{{Port: 8080, TargetPort: intstr.FromString("http")}},
+		},
+	}
+	forwardSetup, forwardFinish := envfuncs.PortForwardService(namespace, service.Name, 0, 8080)
+
+	feat := features.New("PortForwardService").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			res := cfg.Client().Resources()
+			if err := res.Create(ctx, deployment); err != nil {
+				t.Fatal("Error creating deployment", err)
+			}
+			if err := res.Create(ctx, service); err != nil {
+				t.Fatal("Error creating service", err)
+			}
+			err = wait.For(conditions.New(res).DeploymentAvailable(deployment.Name, namespace), wait.WithTimeout(2*time.Minute))
+			if err != nil {
+				t.Fatal("Error waiting for deployment", err)
+			}
+			ctx, err = forwardSetup(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ctx
+		}).
+		Assess("service reachable over localhost", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			address, ok := envfuncs.GetPortForwardAddressFromContext(ctx, namespace, service.Name)
+			if !ok {
+				t.Fatal("port-forward address not found in context")
+			}
+			resp, err := http.Get("http://" + address)
+			if err != nil {
+				t.Fatal("Error requesting forwarded service", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("expected status %d, got %s", http.StatusOK, resp.Status)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := forwardFinish(ctx, cfg)
+			if err != nil {
+				t.Error(err)
+			}
+			ctx, err = envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
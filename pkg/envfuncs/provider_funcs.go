@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 
+	klog "k8s.io/klog/v2"
+
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/support"
@@ -48,6 +50,7 @@ func GetClusterFromContext(ctx context.Context, clusterName string) (support.E2E
 // kubeconfig file for the config client.
 func CreateCluster(p support.E2EClusterProvider, clusterName string) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		klog.FromContext(ctx).V(2).Info("Creating cluster", "cluster", clusterName)
 		k := p.SetDefaults().WithName(clusterName)
 		kubecfg, err := k.Create(ctx)
 		if err != nil {
@@ -75,6 +78,7 @@ func CreateCluster(p support.E2EClusterProvider, clusterName string) env.Func {
 // kubeconfig file for the config client.
 func CreateClusterWithConfig(p support.E2EClusterProvider, clusterName, configFilePath string, opts ...support.ClusterOpts) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		klog.FromContext(ctx).V(2).Info("Creating cluster", "cluster", clusterName, "config", configFilePath)
 		k := p.SetDefaults().WithName(clusterName).WithOpts(opts...)
 		kubecfg, err := k.CreateWithConfig(ctx, configFilePath)
 		if err != nil {
@@ -110,6 +114,7 @@ func DestroyCluster(name string) env.Func {
 			return ctx, fmt.Errorf("destroy e2e provider cluster func: unexpected type for cluster value")
 		}
 
+		klog.FromContext(ctx).V(2).Info("Destroying cluster", "cluster", name)
 		if err := cluster.Destroy(ctx); err != nil {
 			return ctx, fmt.Errorf("destroy e2e provider cluster: %w", err)
 		}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/support/kind"
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+// localRegistryImage is the image of the registry started by CreateLocalRegistry.
+const localRegistryImage = "registry:2"
+
+type localRegistryContextKey struct{}
+
+// localRegistry is the registry started by CreateLocalRegistry.
+type localRegistry struct {
+	container string
+	port      int
+}
+
+func (r *localRegistry) address() string {
+	return fmt.Sprintf("localhost:%d", r.port)
+}
+
+// CreateLocalRegistry provides an Environment.Func that starts a container registry in docker,
+// listening on localhost:port, such as to test image pulls end to end. Images are pushed to it
+// with the address returned by GetLocalRegistryFromContext, such as "localhost:5001/app:dev".
+//
+// Kind clusters pull from the registry when created with CreateKindClusterWithLocalRegistry, as
+// described by https://kind.sigs.k8s.io/docs/user/local-registry/. DeleteLocalRegistry removes
+// the registry.
+func CreateLocalRegistry(port int) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		registry := &localRegistry{container: fmt.Sprintf("e2e-registry-%d", port), port: port}
+		klog.FromContext(ctx).V(2).Info("Starting local registry", "container", registry.container, "address", registry.address())
+		p := utils.RunCommand(fmt.Sprintf("docker run -d --restart=always -p 127.0.0.1:%d:5000 --network bridge --name %s %s", port, registry.container, localRegistryImage))
+		if p.Err() != nil {
+			return ctx, fmt.Errorf("create local registry func: %s: %s", p.Err(), p.Result())
+		}
+		return context.WithValue(ctx, localRegistryContextKey{}, registry), nil
+	}
+}
+
+// GetLocalRegistryFromContext returns the address, such as "localhost:5001", of the registry
+// started by CreateLocalRegistry.
+func GetLocalRegistryFromContext(ctx context.Context) (string, bool) {
+	registry, ok := ctx.Value(localRegistryContextKey{}).(*localRegistry)
+	if !ok {
+		return "", false
+	}
+	return registry.address(), true
+}
+
+// CreateKindClusterWithLocalRegistry provides an Environment.Func that creates a kind cluster
+// whose containerd pulls the images of the address of the registry started by CreateLocalRegistry
+// from that registry. The registry is attached to the network of the kind nodes, and advertised
+// with the local-registry-hosting ConfigMap of the kube-public namespace for tools that read it.
+//
+// NOTE: the returned function will update its env config with the
+// kubeconfig file for the config client.
+func CreateKindClusterWithLocalRegistry(clusterName string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		registry, ok := ctx.Value(localRegistryContextKey{}).(*localRegistry)
+		if !ok {
+			return ctx, fmt.Errorf("create kind cluster with local registry func: no local registry found in context, CreateLocalRegistry must run first")
+		}
+
+		configFile, err := os.CreateTemp("", "kind-local-registry-*.yaml")
+		if err != nil {
+			return ctx, fmt.Errorf("create kind cluster with local registry func: %w", err)
+		}
+		defer os.Remove(configFile.Name())
+		config := fmt.Sprintf(`kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+containerdConfigPatches:
+- |-
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors."%s"]
+    endpoint = ["http://%s:5000"]
+`, registry.address(), registry.container)
+		if _, err := configFile.WriteString(config); err != nil {
+			configFile.Close()
+			return ctx, fmt.Errorf("create kind cluster with local registry func: %w", err)
+		}
+		if err := configFile.Close(); err != nil {
+			return ctx, fmt.Errorf("create kind cluster with local registry func: %w", err)
+		}
+
+		ctx, err = CreateClusterWithConfig(kind.NewProvider(), clusterName, configFile.Name())(ctx, cfg)
+		if err != nil {
+			return ctx, err
+		}
+
+		p := utils.RunCommand(fmt.Sprintf("docker network connect kind %s", registry.container))
+		if p.Err() != nil && !strings.Contains(p.Result(), "already exists") {
+			return ctx, fmt.Errorf("create kind cluster with local registry func: connecting registry to the kind network: %s: %s", p.Err(), p.Result())
+		}
+
+		hosting := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "local-registry-hosting", Namespace: metav1.NamespacePublic},
+			Data: map[string]string{
+				"localRegistryHosting.v1": fmt.Sprintf("host: %q\nhelp: \"https://kind.sigs.k8s.io/docs/user/local-registry/\"\n", registry.address()),
+			},
+		}
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("create kind cluster with local registry func: %w", err)
+		}
+		if err := client.Resources().Create(ctx, hosting); err != nil && !apierrors.IsAlreadyExists(err) {
+			return ctx, fmt.Errorf("create kind cluster with local registry func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// DeleteLocalRegistry provides an Environment.Func that removes the registry started by
+// CreateLocalRegistry, along with the images pushed to it.
+func DeleteLocalRegistry() env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		registry, ok := ctx.Value(localRegistryContextKey{}).(*localRegistry)
+		if !ok {
+			return ctx, fmt.Errorf("delete local registry func: no local registry found in context")
+		}
+		p := utils.RunCommand(fmt.Sprintf("docker rm -f -v %s", registry.container))
+		if p.Err() != nil {
+			return ctx, fmt.Errorf("delete local registry func: %s: %s", p.Err(), p.Result())
+		}
+		return ctx, nil
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+func TestCreateKindClusterWithLocalRegistry(t *testing.T) {
+	clusterName := envconf.RandomName("registry-cluster", 16)
+	cfg := envconf.New()
+	ctx, err := envfuncs.CreateLocalRegistry(5001)(context.TODO(), cfg)
+	if err != nil {
+		t.Fatal("Error creating local registry", err)
+	}
+	defer func() {
+		if _, err := envfuncs.DeleteLocalRegistry()(ctx, cfg); err != nil {
+			t.Error("Error deleting local registry", err)
+		}
+	}()
+	ctx, err = envfuncs.CreateKindClusterWithLocalRegistry(clusterName)(ctx, cfg)
+	if err != nil {
+		t.Fatal("Error creating cluster", err)
+	}
+	defer func() {
+		if _, err := envfuncs.DestroyCluster(clusterName)(ctx, cfg); err != nil {
+			t.Error("Error destroying cluster", err)
+		}
+	}()
+
+	address, ok := envfuncs.GetLocalRegistryFromContext(ctx)
+	if !ok {
+		t.Fatal("expected the registry address to be stored in the context")
+	}
+	image := fmt.Sprintf("%s/busybox:e2e", address)
+	for _, command := range []string{
+		"docker pull busybox",
+		"docker tag busybox " + image,
+		"docker push " + image,
+	} {
+		if p := utils.RunCommand(command); p.Err() != nil {
+			t.Fatalf("Error running %q: %s: %s", command, p.Err(), p.Result())
+		}
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "local-registry-image", Namespace: metav1.NamespaceDefault},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:            "busybox",
+			Image:           image,
+			ImagePullPolicy: corev1.PullAlways,
+			Command:         []string{"sleep", "3600"},
+		}}},
+	}
+	res := cfg.Client().Resources()
+	if err := res.Create(ctx, pod); err != nil {
+		t.Fatal("Error creating pod", err)
+	}
+	if err := wait.For(conditions.New(res).PodRunning(pod), wait.WithTimeout(2*time.Minute)); err != nil {
+		t.Fatal("Error waiting for the pod running the image of the local registry", err)
+	}
+}
@@ -17,10 +17,18 @@ limitations under the License.
 package envfuncs
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
 	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 )
@@ -49,3 +57,54 @@ func TeardownCRDs(crdPath, pattern string) env.Func {
 		return ctx, decoder.DeleteWithManifestDir(ctx, r, crdPath, pattern, []resources.DeleteOption{})
 	}
 }
+
+// WaitForJSONPath provides an Environment.Func that polls obj until the value found at jsonPath
+// in its unstructured form equals expected, such as a "Ready" status.phase of a custom resource
+// managed by an operator. jsonPath uses the kubectl syntax, with or without the enclosing braces:
+// "{.status.phase}", ".status.phase" and "status.phase" are equivalent. A missing field is
+// evaluated as an empty value.
+//
+// If the value does not match within timeout, the returned error reports the last observed value.
+func WaitForJSONPath(obj k8s.Object, jsonPath, expected string, timeout time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		parser := jsonpath.New("wait-for-jsonpath").AllowMissingKeys(true)
+		if err := parser.Parse(jsonPathTemplate(jsonPath)); err != nil {
+			return ctx, fmt.Errorf("wait for jsonpath func: invalid jsonpath %q: %w", jsonPath, err)
+		}
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("wait for jsonpath func: %w", err)
+		}
+
+		res := client.Resources()
+		observed := ""
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			if err := res.Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+				return false, err
+			}
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+			if err != nil {
+				return false, err
+			}
+			var buf bytes.Buffer
+			if err := parser.Execute(&buf, content); err != nil {
+				return false, err
+			}
+			observed = buf.String()
+			return observed == expected, nil
+		}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+		if err != nil {
+			return ctx, fmt.Errorf("wait for jsonpath func: %s of %s/%s is %q, expected %q: %w", jsonPath, obj.GetNamespace(), obj.GetName(), observed, expected, err)
+		}
+		return ctx, nil
+	}
+}
+
+// jsonPathTemplate returns jsonPath as a template enclosed in braces, with a leading dot.
+func jsonPathTemplate(jsonPath string) string {
+	path := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(jsonPath), "{"), "}")
+	if !strings.HasPrefix(path, ".") {
+		path = "." + path
+	}
+	return "{" + path + "}"
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestWaitForJSONPath(t *testing.T) {
+	namespace := envconf.RandomName("jsonpath", 16)
+	crd := &unstructured.Unstructured{}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName("widgets.e2e-framework.example")
+
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "e2e-framework.example/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "test-widget", "namespace": namespace},
+		"spec":       map[string]interface{}{"size": int64(1)},
+		"status":     map[string]interface{}{"phase": "Pending"},
+	}}
+
+	feat := features.New("WaitForJSONPath").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.SetupCRDs("testdata/crds", "*")(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating CRD", err)
+			}
+			ctx, err = envfuncs.WaitForJSONPath(crd, `{.status.conditions[?(@.type=="Established")].status}`, "True", time.Minute)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, err = envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			if err := cfg.Client().Resources().Create(ctx, widget); err != nil {
+				t.Fatal("Error creating widget", err)
+			}
+			return ctx
+		}).
+		Assess("status.phase becomes Ready", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			go func() {
+				time.Sleep(2 * time.Second)
+				patch := k8s.Patch{PatchType: types.MergePatchType, Data: []byte(`{"status":{"phase":"Ready"}}`)}
+				if err := cfg.Client().Resources().Patch(ctx, widget.DeepCopy(), patch); err != nil {
+					t.Error("Error patching widget", err)
+				}
+			}()
+			_, err := envfuncs.WaitForJSONPath(widget.DeepCopy(), "status.phase", "Ready", time.Minute)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ctx
+		}).
+		Assess("last observed value reported on timeout", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			_, err := envfuncs.WaitForJSONPath(widget.DeepCopy(), "status.phase", "Failed", 5*time.Second)(ctx, cfg)
+			if err == nil {
+				t.Fatal("expected an error for a value that never matches")
+			}
+			if !strings.Contains(err.Error(), `"Ready"`) {
+				t.Errorf("expected the error to report the observed phase, got: %v", err)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			ctx, err = envfuncs.TeardownCRDs("testdata/crds", "*")(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting CRD", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// DefaultImagePullSecretName is the name of the secret created by CreateImagePullSecret
+// unless WithImagePullSecretName is used.
+const DefaultImagePullSecretName = "e2e-framework-image-pull-secret"
+
+// serviceAccountTimeout bounds the wait for the service account to reference the
+// image pull secret, as the default service account of a new namespace is created
+// asynchronously.
+const serviceAccountTimeout = time.Minute
+
+type imagePullSecretOptions struct {
+	name           string
+	serviceAccount string
+}
+
+type ImagePullSecretOpts func(*imagePullSecretOptions)
+
+// WithImagePullSecretName sets the name of the secret created by CreateImagePullSecret.
+func WithImagePullSecretName(name string) ImagePullSecretOpts {
+	return func(o *imagePullSecretOptions) {
+		o.name = name
+	}
+}
+
+// WithImagePullSecretServiceAccount adds the secret created by CreateImagePullSecret to the
+// image pull secrets of the named service account, such as "default", so that the pods using it
+// can pull images from the registry without referencing the secret themselves.
+func WithImagePullSecretServiceAccount(name string) ImagePullSecretOpts {
+	return func(o *imagePullSecretOptions) {
+		o.serviceAccount = name
+	}
+}
+
+// CreateImagePullSecret provides an Environment.Func that creates a secret of type
+// kubernetes.io/dockerconfigjson in namespace, holding the credentials used to pull
+// images from registry.
+func CreateImagePullSecret(namespace, registry, username, password string, opts ...ImagePullSecretOpts) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		options := &imagePullSecretOptions{name: DefaultImagePullSecretName}
+		for _, opt := range opts {
+			opt(options)
+		}
+
+		dockerConfig, err := dockerConfigJSON(registry, username, password)
+		if err != nil {
+			return ctx, fmt.Errorf("create image pull secret func: %w", err)
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: options.name, Namespace: namespace},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig},
+		}
+
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("create image pull secret func: %w", err)
+		}
+		klog.FromContext(ctx).V(2).Info("Creating image pull secret", "namespace", namespace, "secret", options.name, "registry", registry)
+		if err := client.Resources().Create(ctx, secret); err != nil {
+			return ctx, fmt.Errorf("create image pull secret func: %w", err)
+		}
+
+		if options.serviceAccount == "" {
+			return ctx, nil
+		}
+		res := client.Resources(namespace)
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			var sa corev1.ServiceAccount
+			if err := res.Get(ctx, options.serviceAccount, namespace, &sa); err != nil {
+				if apierrors.IsNotFound(err) {
+					return false, nil
+				}
+				return false, err
+			}
+			for _, ref := range sa.ImagePullSecrets {
+				if ref.Name == options.name {
+					return true, nil
+				}
+			}
+			sa.ImagePullSecrets = append(sa.ImagePullSecrets, corev1.LocalObjectReference{Name: options.name})
+			if err := res.Update(ctx, &sa); err != nil {
+				if apierrors.IsConflict(err) {
+					return false, nil
+				}
+				return false, err
+			}
+			return true, nil
+		}, wait.WithContext(ctx), wait.WithTimeout(serviceAccountTimeout), wait.WithImmediate())
+		if err != nil {
+			return ctx, fmt.Errorf("create image pull secret func: add secret to service account %q: %w", options.serviceAccount, err)
+		}
+		return ctx, nil
+	}
+}
+
+// dockerConfigJSON builds the content of a .dockerconfigjson file holding the credentials for registry.
+func dockerConfigJSON(registry, username, password string) ([]byte, error) {
+	type authEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	return json.Marshal(struct {
+		Auths map[string]authEntry `json:"auths"`
+	}{
+		Auths: map[string]authEntry{
+			registry: {
+				Username: username,
+				Password: password,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+			},
+		},
+	})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestCreateImagePullSecret(t *testing.T) {
+	namespace := envconf.RandomName("pull-secret", 16)
+	registry := "registry.example.com"
+
+	feat := features.New("CreateImagePullSecret").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			ctx, err = envfuncs.CreateImagePullSecret(namespace, registry, "user", "secret",
+				envfuncs.WithImagePullSecretServiceAccount("default"))(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating image pull secret", err)
+			}
+			return ctx
+		}).
+		Assess("secret is a docker config", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			var secret corev1.Secret
+			if err := cfg.Client().Resources().Get(ctx, envfuncs.DefaultImagePullSecretName, namespace, &secret); err != nil {
+				t.Fatal("Error getting image pull secret", err)
+			}
+			if secret.Type != corev1.SecretTypeDockerConfigJson {
+				t.Errorf("expected secret of type %s, got %s", corev1.SecretTypeDockerConfigJson, secret.Type)
+			}
+			var dockerConfig struct {
+				Auths map[string]struct {
+					Username string `json:"username"`
+					Password string `json:"password"`
+					Auth     string `json:"auth"`
+				} `json:"auths"`
+			}
+			if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &dockerConfig); err != nil {
+				t.Fatal("Error decoding docker config", err)
+			}
+			entry, ok := dockerConfig.Auths[registry]
+			if !ok {
+				t.Fatalf("expected credentials for %s, got %v", registry, dockerConfig.Auths)
+			}
+			if expected := base64.StdEncoding.EncodeToString([]byte("user:secret")); entry.Auth != expected {
+				t.Errorf("expected auth %q, got %q", expected, entry.Auth)
+			}
+			return ctx
+		}).
+		Assess("default service account references the secret", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			var sa corev1.ServiceAccount
+			if err := cfg.Client().Resources().Get(ctx, "default", namespace, &sa); err != nil {
+				t.Fatal("Error getting service account", err)
+			}
+			for _, ref := range sa.ImagePullSecrets {
+				if ref.Name == envfuncs.DefaultImagePullSecretName {
+					return ctx
+				}
+			}
+			t.Errorf("expected service account to reference %s, got %v", envfuncs.DefaultImagePullSecretName, sa.ImagePullSecrets)
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// DeployWebhook provides an Environment.Func that creates the objects of the manifests found in
+// manifestDir, such as the Deployment and the Service serving an admission webhook along with
+// its ValidatingWebhookConfiguration or MutatingWebhookConfiguration, and waits for the webhook
+// to be reachable by the API server, so that the features sending it traffic do not race its
+// startup. Objects already existing are left as is, and the namespaced objects of the manifests
+// not declaring a namespace are created in namespace.
+//
+// The webhook is considered reachable once the Service serviceName of namespace has a ready
+// endpoint, and the webhooks of the configurations calling the Service have a CA bundle, such as
+// the ones injected by cert-manager. The Service should select pods with a readiness probe for
+// its endpoints to be ready only once the webhook server is serving.
+func DeployWebhook(manifestDir, serviceName, namespace string, timeout time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("deploy webhook func: %w", err)
+		}
+		res := client.Resources()
+		err = decoder.DecodeEachFile(ctx, os.DirFS(manifestDir), "*", decoder.CreateIgnoreAlreadyExists(res), decoder.MutateDefaultNamespace(namespace))
+		if err != nil {
+			return ctx, fmt.Errorf("deploy webhook func: %w", err)
+		}
+
+		var missingCABundle []string
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			if missingCABundle, err = webhooksMissingCABundle(ctx, res, serviceName, namespace); err != nil {
+				return false, err
+			}
+			return len(missingCABundle) == 0, nil
+		}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+		if err != nil {
+			return ctx, fmt.Errorf("deploy webhook func: webhooks %v of service %s/%s not assigned a CA bundle: %w", missingCABundle, namespace, serviceName, err)
+		}
+
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			return serviceEndpointReady(ctx, res, serviceName, namespace)
+		}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+		if err != nil {
+			return ctx, fmt.Errorf("deploy webhook func: service %s/%s has no ready endpoint: %w", namespace, serviceName, err)
+		}
+		klog.FromContext(ctx).V(2).Info("Webhook deployed", "service", namespace+"/"+serviceName, "manifests", manifestDir)
+		return ctx, nil
+	}
+}
+
+// webhooksMissingCABundle returns the names of the admission webhooks calling the service name of
+// namespace whose client config has no CA bundle.
+func webhooksMissingCABundle(ctx context.Context, res *resources.Resources, name, namespace string) ([]string, error) {
+	clientConfigs := make(map[string]admissionregistrationv1.WebhookClientConfig)
+	var validating admissionregistrationv1.ValidatingWebhookConfigurationList
+	if err := res.List(ctx, &validating); err != nil {
+		return nil, err
+	}
+	for _, configuration := range validating.Items {
+		for _, webhook := range configuration.Webhooks {
+			clientConfigs[configuration.Name+"/"+webhook.Name] = webhook.ClientConfig
+		}
+	}
+	var mutating admissionregistrationv1.MutatingWebhookConfigurationList
+	if err := res.List(ctx, &mutating); err != nil {
+		return nil, err
+	}
+	for _, configuration := range mutating.Items {
+		for _, webhook := range configuration.Webhooks {
+			clientConfigs[configuration.Name+"/"+webhook.Name] = webhook.ClientConfig
+		}
+	}
+
+	var missing []string
+	for webhook, clientConfig := range clientConfigs {
+		service := clientConfig.Service
+		if service != nil && service.Name == name && service.Namespace == namespace && len(clientConfig.CABundle) == 0 {
+			missing = append(missing, webhook)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// serviceEndpointReady reports whether the service name of namespace has a ready endpoint.
+func serviceEndpointReady(ctx context.Context, res *resources.Resources, name, namespace string) (bool, error) {
+	var slices discoveryv1.EndpointSliceList
+	if err := res.WithNamespace(namespace).List(ctx, &slices, resources.WithLabelSelector(discoveryv1.LabelServiceName+"="+name)); err != nil {
+		return false, err
+	}
+	for _, slice := range slices.Items {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready != nil && *endpoint.Conditions.Ready {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+// webhookManifests are the manifests of the agnhost webhook used by the Kubernetes e2e tests,
+// which denies the ConfigMaps holding the webhook-e2e-test: webhook-disallow data.
+const webhookManifests = `apiVersion: v1
+kind: Secret
+metadata:
+  name: webhook-test-certs
+type: kubernetes.io/tls
+data:
+  tls.crt: %[2]s
+  tls.key: %[3]s
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: webhook-test
+spec:
+  selector:
+    matchLabels:
+      app: webhook-test
+  template:
+    metadata:
+      labels:
+        app: webhook-test
+    spec:
+      containers:
+      - name: webhook
+        image: registry.k8s.io/e2e-test-images/agnhost:2.52
+        args:
+        - webhook
+        - --tls-cert-file=/certs/tls.crt
+        - --tls-private-key-file=/certs/tls.key
+        - --port=8443
+        ports:
+        - containerPort: 8443
+        readinessProbe:
+          tcpSocket:
+            port: 8443
+        volumeMounts:
+        - name: certs
+          mountPath: /certs
+          readOnly: true
+      volumes:
+      - name: certs
+        secret:
+          secretName: webhook-test-certs
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: webhook-test
+spec:
+  selector:
+    app: webhook-test
+  ports:
+  - port: 443
+    targetPort: 8443
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: %[1]s
+webhooks:
+- name: deny-configmaps.e2e-framework.io
+  clientConfig:
+    service:
+      name: webhook-test
+      namespace: %[1]s
+      path: /configmaps
+    caBundle: %[4]s
+  rules:
+  - apiGroups: [""]
+    apiVersions: ["v1"]
+    operations: ["CREATE"]
+    resources: ["configmaps"]
+  namespaceSelector:
+    matchLabels:
+      kubernetes.io/metadata.name: %[1]s
+  failurePolicy: Fail
+  sideEffects: None
+  admissionReviewVersions: ["v1", "v1beta1"]
+`
+
+func TestDeployWebhook(t *testing.T) {
+	namespace := envconf.RandomName("webhook", 16)
+	manifestDir := t.TempDir()
+
+	feat := features.New("DeployWebhook").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			caPEM, certPEM, keyPEM := webhookCertificates(t, "webhook-test."+namespace+".svc")
+			encode := base64.StdEncoding.EncodeToString
+			manifests := fmt.Sprintf(webhookManifests, namespace, encode(certPEM), encode(keyPEM), encode(caPEM))
+			if err := os.WriteFile(filepath.Join(manifestDir, "webhook.yaml"), []byte(manifests), 0o600); err != nil {
+				t.Fatal("Error writing manifests", err)
+			}
+			ctx, err = envfuncs.DeployWebhook(manifestDir, "webhook-test", namespace, 3*time.Minute)(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ctx
+		}).
+		Assess("create denied by webhook", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			denied := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "denied", Namespace: namespace},
+				Data:       map[string]string{"webhook-e2e-test": "webhook-disallow"},
+			}
+			err := cfg.Client().Resources().Create(ctx, denied)
+			if err == nil || !strings.Contains(err.Error(), "denied the request") {
+				t.Errorf("expected the webhook to deny the config map, got %v", err)
+			}
+			allowed := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "allowed", Namespace: namespace},
+				Data:       map[string]string{"key": "value"},
+			}
+			if err := cfg.Client().Resources().Create(ctx, allowed); err != nil {
+				t.Errorf("expected the webhook to allow the config map, got %v", err)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+			if err := cfg.Client().Resources().Delete(ctx, configuration); err != nil {
+				t.Error("Error deleting webhook configuration", err)
+			}
+			ctx, err := envfuncs.DeleteNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Error("Error deleting namespace", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
+
+// webhookCertificates returns a CA certificate, and a serving certificate for dnsName signed by
+// the CA along with its key, PEM encoded.
+func webhookCertificates(t *testing.T, dnsName string) (caPEM, certPEM, keyPEM []byte) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "webhook-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, cert, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package features
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"sigs.k8s.io/e2e-framework/pkg/types"
 )
@@ -42,6 +44,43 @@ func (b *FeatureBuilder) WithLabel(key, value string) *FeatureBuilder {
 	return b
 }
 
+// DependsOn declares the names of the features that must be tested, and succeed,
+// before this feature. Features tested in the same Environment.Test call are
+// ordered accordingly, and this feature is skipped if a dependency fails.
+func (b *FeatureBuilder) DependsOn(names ...string) *FeatureBuilder {
+	b.feat.dependencies = append(b.feat.dependencies, names...)
+	return b
+}
+
+// SkipIf registers a condition evaluated before the feature is tested. When it
+// returns true, the feature is skipped with the returned reason instead of
+// failing, e.g. on clusters lacking a required capability.
+func (b *FeatureBuilder) SkipIf(fn types.SkipFunc) *FeatureBuilder {
+	b.feat.skipConditions = append(b.feat.skipConditions, fn)
+	return b
+}
+
+// WithStepTimeout bounds the duration of every step of the feature, setups,
+// assessments and teardowns alike: each step is given a context whose deadline
+// expires after d, so that a hanging call to the cluster returns an error
+// without affecting the next steps. The deadline of an existing context is kept
+// when it is the tighter bound.
+func (b *FeatureBuilder) WithStepTimeout(d time.Duration) *FeatureBuilder {
+	b.feat.stepTimeout = d
+	return b
+}
+
+// WithContext registers fn to seed the context of the feature, such as with a
+// test-specific logger or a registry shared by its steps, without an explicit
+// setup step. fn is passed the context of the environment, as updated by its
+// setup and BeforeEachFeature functions, and the context it returns flows into
+// the setups, assessments and teardowns of the feature. Functions registered
+// more than once are applied in order.
+func (b *FeatureBuilder) WithContext(fn func(context.Context) context.Context) *FeatureBuilder {
+	b.feat.contextFuncs = append(b.feat.contextFuncs, fn)
+	return b
+}
+
 // WithStep adds a new step that will be applied prior to feature test.
 func (b *FeatureBuilder) WithStep(name string, level Level, fn Func) *FeatureBuilder {
 	b.feat.steps = append(b.feat.steps, newStep(name, level, fn))
@@ -75,6 +114,15 @@ func (b *FeatureBuilder) WithTeardown(name string, fn Func) *FeatureBuilder {
 	return b.WithStep(name, LevelTeardown, fn)
 }
 
+// WithTeardownAlways adds a new teardown step that runs even if a setup or an
+// assessment of the feature failed, including when the test is stopped with
+// t.Fatal or in fail-fast mode, so that partially created resources are cleaned
+// up. Such steps run after the teardown steps registered with Teardown and
+// WithTeardown, when those run.
+func (b *FeatureBuilder) WithTeardownAlways(name string, fn Func) *FeatureBuilder {
+	return b.WithStep(name, LevelTeardownAlways, fn)
+}
+
 // Assess adds an assessment step to the feature test.
 func (b *FeatureBuilder) Assess(desc string, fn Func) *FeatureBuilder {
 	return b.WithStep(desc, LevelAssess, fn)
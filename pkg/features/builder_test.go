@@ -18,8 +18,28 @@ package features
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/types"
 )
@@ -262,3 +282,342 @@ func TestFeatureBuilder(t *testing.T) {
 		})
 	}
 }
+
+func TestEventually(t *testing.T) {
+	type ctxKey struct{}
+	attempts, cleanups := 0, 0
+	fn := Eventually(func(ctx context.Context, t testing.TB, _ *envconf.Config) context.Context {
+		attempts++
+		if cleanups != attempts-1 {
+			t.Errorf("expected the cleanups of the previous attempts to have run, got %d for %d attempts", cleanups, attempts-1)
+		}
+		t.Cleanup(func() { cleanups++ })
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the attempt to run with the context of the poll")
+		}
+		if attempts < 3 {
+			t.Fatalf("attempt %d failed", attempts)
+		}
+		return context.WithValue(ctx, ctxKey{}, attempts)
+	}, time.Second*5, time.Millisecond*10)
+
+	ctx := fn(context.TODO(), t, envconf.New())
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if cleanups != 3 {
+		t.Errorf("expected the cleanups of the 3 attempts to run, got %d", cleanups)
+	}
+	if val, ok := ctx.Value(ctxKey{}).(int); !ok || val != 3 {
+		t.Errorf("expected context from the successful attempt, got %v", ctx.Value(ctxKey{}))
+	}
+	if err := ctx.Err(); err != nil {
+		t.Errorf("expected the context of the step to outlive the poll, got %v", err)
+	}
+}
+
+func TestEventuallyTimeout(t *testing.T) {
+	// The step times out and fails, so it is run in a separate process to
+	// keep this test from failing.
+	if os.Getenv("E2E_FRAMEWORK_EVENTUALLY_HELPER") == "1" {
+		attempts := 0
+		Eventually(func(ctx context.Context, t testing.TB, _ *envconf.Config) context.Context {
+			attempts++
+			t.Cleanup(func() { fmt.Printf("attempt %d cleaned up\n", attempts) })
+			t.Errorf("attempt %d failed", attempts)
+			return ctx
+		}, 200*time.Millisecond, 50*time.Millisecond)(context.TODO(), t, envconf.New())
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEventuallyTimeout$", "-test.v")
+	cmd.Env = append(os.Environ(), "E2E_FRAMEWORK_EVENTUALLY_HELPER=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected the step to fail, got output:\n%s", out)
+	}
+	output := string(out)
+	if !strings.Contains(output, "last attempt failed") || strings.Count(output, "failed:") != 1 {
+		t.Errorf("expected the step to fail once with the last failure, got output:\n%s", out)
+	}
+	if strings.Contains(output, "attempt 1 failed") {
+		t.Errorf("expected only the failure of the last attempt to be reported, got output:\n%s", out)
+	}
+	if cleanedUp := strings.Count(output, "cleaned up"); cleanedUp == 0 || !strings.Contains(output, fmt.Sprintf("attempt %d failed", cleanedUp)) {
+		t.Errorf("expected the cleanups of every attempt to run and the last attempt to be reported, got output:\n%s", out)
+	}
+}
+
+func TestTableBuildDefaultName(t *testing.T) {
+	table := Table{{Assessment: func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }}}
+
+	var names []string
+	for i := 0; i < 2; i++ {
+		names = append(names, table.Build().Feature().Name())
+	}
+	if names[0] == "" || !strings.HasPrefix(names[0], "builder_test.go:") {
+		t.Errorf("expected a feature name generated from the caller, got %q", names[0])
+	}
+	if names[0] != names[1] {
+		t.Errorf("expected the generated name to be stable, got %q and %q", names[0], names[1])
+	}
+	if other := table.Build().Feature().Name(); other == names[0] {
+		t.Errorf("expected tables built from different lines to be distinguishable, got %q twice", other)
+	}
+	if name := table.Build("explicit").Feature().Name(); name != "explicit" {
+		t.Errorf("expected the explicit name to be kept, got %q", name)
+	}
+}
+
+func TestTableExpectErr(t *testing.T) {
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "denied", fmt.Errorf("rejected by webhook"))
+	operationCalled := false
+	feat := Table{
+		{
+			Name: "allowed",
+			Assessment: func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				return ctx
+			},
+		},
+		{
+			Name: "rejected",
+			Operation: func(ctx context.Context, t *testing.T, _ *envconf.Config) (context.Context, error) {
+				operationCalled = true
+				return ctx, forbidden
+			},
+			ExpectErr: apierrors.IsForbidden,
+		},
+	}.Build("table").Feature()
+
+	assessments := GetStepsByLevel(feat.Steps(), types.LevelAssess)
+	if len(assessments) != 2 {
+		t.Fatalf("expected 2 assessments, got %d", len(assessments))
+	}
+	if name := assessments[1].Name(); name != "rejected" {
+		t.Errorf("unexpected assessment name %q", name)
+	}
+	assessments[1].Func()(context.TODO(), t, envconf.New())
+	if !operationCalled {
+		t.Error("expected the operation of the negative assessment to be called")
+	}
+}
+
+func TestTableIncompleteNegativeRow(t *testing.T) {
+	tests := []struct {
+		name string
+		row  TableRow
+	}{
+		{
+			name: "operation without ExpectErr",
+			row: TableRow{Operation: func(ctx context.Context, t *testing.T, _ *envconf.Config) (context.Context, error) {
+				return ctx, nil
+			}},
+		},
+		{
+			name: "ExpectErr without operation",
+			row:  TableRow{ExpectErr: apierrors.IsForbidden},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected Build to panic on an incomplete negative row")
+				}
+			}()
+			Table{test.row}.Build("table")
+		})
+	}
+}
+
+func TestFeatureBuilder_WithSnapshot(t *testing.T) {
+	feat := New("test-feat").
+		Setup(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			return ctx
+		}).
+		WithSnapshot().
+		Feature()
+
+	setups := GetStepsByLevel(feat.Steps(), types.LevelSetup)
+	if len(setups) != 2 || setups[0].Name() != "test-feat-snapshot" {
+		t.Errorf("expected the snapshot to be taken before the other setups, got %v", setups)
+	}
+	teardowns := GetStepsByLevel(feat.Steps(), types.LevelTeardownAlways)
+	if len(teardowns) != 1 || teardowns[0].Name() != "test-feat-snapshot-diff" {
+		t.Errorf("expected the snapshot diff to run as an always teardown, got %v", teardowns)
+	}
+}
+
+func TestFeatureBuilder_WithSnapshotDiff(t *testing.T) {
+	const namespace = "with-snapshot"
+	collection := path.Join("/api/v1/namespaces", namespace, "configmaps")
+	var mu sync.Mutex
+	var stored []corev1.ConfigMap
+	// fake API server serving the configmaps of the namespace
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api":
+			_ = json.NewEncoder(w).Encode(metav1.APIVersions{TypeMeta: metav1.TypeMeta{Kind: "APIVersions"}, Versions: []string{"v1"}})
+		case r.URL.Path == "/apis":
+			_ = json.NewEncoder(w).Encode(metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}})
+		case r.URL.Path == "/api/v1":
+			_ = json.NewEncoder(w).Encode(metav1.APIResourceList{
+				TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"create", "list"}}},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == collection:
+			var cm corev1.ConfigMap
+			if err := json.NewDecoder(r.Body).Decode(&cm); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			cm.Namespace = namespace
+			cm.ResourceVersion = fmt.Sprint(len(stored) + 1)
+			stored = append(stored, cm)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(cm)
+		case r.Method == http.MethodGet && r.URL.Path == collection:
+			list := corev1.ConfigMapList{TypeMeta: metav1.TypeMeta{Kind: "ConfigMapList", APIVersion: "v1"}, Items: stored}
+			_ = json.NewEncoder(w).Encode(list)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	// the fake API server does not speak protobuf
+	client, err := klient.New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: runtime.ContentTypeJSON}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := envconf.New().WithClient(client).WithNamespace(namespace)
+
+	feat := New("test-feat").WithSnapshot().Feature()
+	snapshot := GetStepsByLevel(feat.Steps(), types.LevelSetup)[0].Func()
+	firstRun := snapshot(context.TODO(), t, cfg)
+	before, ok := firstRun.Value(snapshotContextKey("test-feat")).(namespaceSnapshot)
+	if !ok {
+		t.Fatal("expected the snapshot to be stored in the context of the steps")
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-cm", Namespace: namespace}}
+	if err := client.Resources(namespace).Create(context.TODO(), cm); err != nil {
+		t.Fatal("failed to create configmap", err)
+	}
+	changes, err := namespaceChanges(firstRun, cfg, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"created: ConfigMap/test-cm"}; !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected diff %v, got %v", expected, changes)
+	}
+
+	// another run of the feature is compared with its own snapshot
+	secondRun := snapshot(context.TODO(), t, cfg)
+	if _, ok := secondRun.Value(snapshotContextKey("test-feat")).(namespaceSnapshot)["ConfigMap/test-cm"]; !ok {
+		t.Error("expected the snapshot of the second run to hold the configmap")
+	}
+	if _, ok := firstRun.Value(snapshotContextKey("test-feat")).(namespaceSnapshot)["ConfigMap/test-cm"]; ok {
+		t.Error("expected the snapshot of the first run to be left unchanged by the second run")
+	}
+}
+
+func TestNamespaceSnapshotDiff(t *testing.T) {
+	before := namespaceSnapshot{
+		"ConfigMap/kept":      "1",
+		"ConfigMap/updated":   "2",
+		"Secret/deleted":      "3",
+		"Deployment.apps/app": "4",
+	}
+	after := namespaceSnapshot{
+		"ConfigMap/kept":      "1",
+		"ConfigMap/updated":   "5",
+		"ConfigMap/created":   "6",
+		"Deployment.apps/app": "4",
+	}
+	expected := []string{"changed: ConfigMap/updated", "created: ConfigMap/created", "deleted: Secret/deleted"}
+	if got := before.diff(after); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected diff %v, got %v", expected, got)
+	}
+	if got := after.diff(after); len(got) != 0 {
+		t.Errorf("expected no diff between identical snapshots, got %v", got)
+	}
+}
+
+func TestWithResources(t *testing.T) {
+	const namespace = "with-resources"
+	collection := path.Join("/api/v1/namespaces", namespace, "configmaps")
+	var mu sync.Mutex
+	stored := map[string]corev1.ConfigMap{}
+	// fake API server serving the configmaps of the namespace
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api":
+			_ = json.NewEncoder(w).Encode(metav1.APIVersions{TypeMeta: metav1.TypeMeta{Kind: "APIVersions"}, Versions: []string{"v1"}})
+		case r.URL.Path == "/apis":
+			_ = json.NewEncoder(w).Encode(metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}})
+		case r.URL.Path == "/api/v1":
+			_ = json.NewEncoder(w).Encode(metav1.APIResourceList{
+				TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"create", "get"}}},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == collection:
+			var cm corev1.ConfigMap
+			if err := json.NewDecoder(r.Body).Decode(&cm); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			cm.Namespace = namespace
+			stored[cm.Name] = cm
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(cm)
+		case r.Method == http.MethodGet && path.Dir(r.URL.Path) == collection:
+			cm, ok := stored[path.Base(r.URL.Path)]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(cm)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	// the fake API server does not speak protobuf
+	client, err := klient.New(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: runtime.ContentTypeJSON}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := envconf.New().WithClient(client).WithNamespace(namespace)
+
+	fn := WithResources(func(ctx context.Context, t *testing.T, r *resources.Resources) context.Context {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cm", Namespace: namespace},
+			Data:       map[string]string{"foo": "bar"},
+		}
+		if err := r.Create(ctx, cm); err != nil {
+			t.Fatal("failed to create configmap", err)
+		}
+		var actual corev1.ConfigMap
+		if err := r.Get(ctx, cm.Name, namespace, &actual); err != nil {
+			t.Fatal("failed to get configmap", err)
+		}
+		if actual.Data["foo"] != "bar" {
+			t.Errorf("unexpected configmap data: %v", actual.Data)
+		}
+		return ctx
+	})
+	fn(context.TODO(), t, cfg)
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := stored["test-cm"]; !ok {
+		t.Error("expected the configmap to be created through the Resources client")
+	}
+}
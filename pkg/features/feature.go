@@ -17,7 +17,9 @@ limitations under the License.
 package features
 
 import (
+	"context"
 	"regexp"
+	"time"
 
 	"sigs.k8s.io/e2e-framework/pkg/types"
 )
@@ -37,13 +39,20 @@ const (
 	LevelAssess = types.LevelAssess
 	// LevelTeardown when doing the teardown phase
 	LevelTeardown = types.LevelTeardown
+	// LevelTeardownAlways when doing the teardown phase that runs even
+	// if a setup or an assessment of the feature failed
+	LevelTeardownAlways = types.LevelTeardownAlways
 )
 
 type defaultFeature struct {
-	name        string
-	description string
-	labels      types.Labels
-	steps       []types.Step
+	name           string
+	description    string
+	labels         types.Labels
+	steps          []types.Step
+	dependencies   []string
+	skipConditions []types.SkipFunc
+	stepTimeout    time.Duration
+	contextFuncs   []func(context.Context) context.Context
 }
 
 func newDefaultFeature(name, description string) *defaultFeature {
@@ -66,6 +75,22 @@ func (f *defaultFeature) Description() string {
 	return f.description
 }
 
+func (f *defaultFeature) DependsOn() []string {
+	return f.dependencies
+}
+
+func (f *defaultFeature) SkipConditions() []types.SkipFunc {
+	return f.skipConditions
+}
+
+func (f *defaultFeature) StepTimeout() time.Duration {
+	return f.stepTimeout
+}
+
+func (f *defaultFeature) ContextFuncs() []func(context.Context) context.Context {
+	return f.contextFuncs
+}
+
 type testStep struct {
 	name        string
 	description string
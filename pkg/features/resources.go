@@ -0,0 +1,43 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// ResourcesFunc is a step function receiving the Resources client scoped to the
+// namespace of the environment config.
+type ResourcesFunc func(context.Context, *testing.T, *resources.Resources) context.Context
+
+// WithResources wraps fn in a Func that resolves the Resources client for the
+// namespace of the config, as cfg.Client().Resources(cfg.Namespace()) does, and
+// passes it to fn. The step is failed if the client cannot be created.
+func WithResources(fn ResourcesFunc) Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		client, err := cfg.NewClient()
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+		return fn(ctx, t, client.Resources(cfg.Namespace()))
+	}
+}
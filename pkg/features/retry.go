@@ -0,0 +1,223 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	klog "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// EventuallyFunc is a step function retried by Eventually. Its failures are
+// reported through the testing.TB it is handed, which records them for the
+// attempt instead of failing the test, as a *testing.T cannot.
+type EventuallyFunc func(context.Context, testing.TB, *envconf.Config) context.Context
+
+// Eventually wraps fn in a Func that invokes fn every interval until an
+// attempt completes without failing, or until timeout elapses. Each attempt is
+// handed the context of the poll, which is done once timeout elapses, and a
+// testing.TB of its own recording the messages logged and the failures
+// reported by the attempt; Fatal and FailNow end the attempt, and the cleanup
+// functions it registers run once it completes. When the timeout is reached,
+// the step is failed with the messages of the last attempt. The messages of
+// the successful attempt are logged to the step.
+//
+// The values of the context returned by the successful attempt are surfaced
+// to the subsequent steps.
+func Eventually(fn EventuallyFunc, timeout, interval time.Duration) Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		var last *attemptTB
+		var out context.Context
+		err := apimachinerywait.PollUntilContextTimeout(ctx, interval, timeout, true, func(pollCtx context.Context) (bool, error) {
+			var result context.Context
+			result, last = attempt(pollCtx, t, fn, cfg)
+			if last.skipped {
+				return true, nil
+			}
+			if last.Failed() {
+				klog.FromContext(ctx).V(4).Info("Step failed, retrying", "error", last.output(), "interval", interval)
+				return false, nil
+			}
+			out = result
+			return true, nil
+		})
+		if last != nil && last.skipped {
+			t.Skip(last.output())
+		}
+		if err != nil {
+			if last == nil {
+				t.Fatalf("step did not succeed within %s: %s", timeout, err)
+			}
+			t.Fatalf("step did not succeed within %s, last attempt failed:\n%s", timeout, last.output())
+		}
+		if len(last.messages) > 0 {
+			t.Log(last.output())
+		}
+		if out == nil {
+			return ctx
+		}
+		return attemptContext{Context: ctx, values: out}
+	}
+}
+
+// attempt invokes fn with an attemptTB recording its outcome, and runs the
+// cleanup functions registered by fn once it completes. fn runs in a goroutine
+// of its own, as FailNow stops the goroutine it is called from.
+func attempt(ctx context.Context, t *testing.T, fn EventuallyFunc, cfg *envconf.Config) (context.Context, *attemptTB) {
+	tb := &attemptTB{TB: t}
+	var result context.Context
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer tb.runCleanups()
+		result = fn(ctx, tb, cfg)
+	}()
+	<-done
+	return result, tb
+}
+
+// attemptTB is the testing.TB of an attempt of Eventually. It records the
+// messages logged, the failures and the cleanup functions of the attempt, and
+// delegates the other methods, such as TempDir, to the *testing.T of the step.
+type attemptTB struct {
+	testing.TB
+
+	mu       sync.Mutex
+	messages []string
+	failed   bool
+	skipped  bool
+	cleanups []func()
+}
+
+func (tb *attemptTB) Helper() {}
+
+func (tb *attemptTB) Log(args ...any) { tb.log(fmt.Sprintln(args...)) }
+
+func (tb *attemptTB) Logf(format string, args ...any) { tb.log(fmt.Sprintf(format, args...)) }
+
+func (tb *attemptTB) Error(args ...any) {
+	tb.Log(args...)
+	tb.Fail()
+}
+
+func (tb *attemptTB) Errorf(format string, args ...any) {
+	tb.Logf(format, args...)
+	tb.Fail()
+}
+
+func (tb *attemptTB) Fatal(args ...any) {
+	tb.Log(args...)
+	tb.FailNow()
+}
+
+func (tb *attemptTB) Fatalf(format string, args ...any) {
+	tb.Logf(format, args...)
+	tb.FailNow()
+}
+
+func (tb *attemptTB) Fail() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.failed = true
+}
+
+func (tb *attemptTB) FailNow() {
+	tb.Fail()
+	runtime.Goexit()
+}
+
+func (tb *attemptTB) Failed() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.failed
+}
+
+func (tb *attemptTB) Skip(args ...any) {
+	tb.Log(args...)
+	tb.SkipNow()
+}
+
+func (tb *attemptTB) Skipf(format string, args ...any) {
+	tb.Logf(format, args...)
+	tb.SkipNow()
+}
+
+func (tb *attemptTB) SkipNow() {
+	tb.mu.Lock()
+	tb.skipped = true
+	tb.mu.Unlock()
+	runtime.Goexit()
+}
+
+func (tb *attemptTB) Skipped() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.skipped
+}
+
+func (tb *attemptTB) Cleanup(fn func()) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.cleanups = append(tb.cleanups, fn)
+}
+
+func (tb *attemptTB) log(msg string) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.messages = append(tb.messages, strings.TrimSuffix(msg, "\n"))
+}
+
+// runCleanups runs the cleanup functions registered by the attempt, the last
+// registered first.
+func (tb *attemptTB) runCleanups() {
+	tb.mu.Lock()
+	cleanups := tb.cleanups
+	tb.cleanups = nil
+	tb.mu.Unlock()
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
+
+// output returns the messages recorded for the attempt.
+func (tb *attemptTB) output() string {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return strings.Join(tb.messages, "\n")
+}
+
+// attemptContext surfaces the values of the context returned by a successful
+// attempt, which derives from the context of the poll, with the deadline and
+// cancellation of the context of the step.
+type attemptContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c attemptContext) Value(key any) any {
+	return c.values.Value(key)
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// namespaceSnapshot maps the objects found in a namespace, identified by their
+// kind and name, to their resourceVersion.
+type namespaceSnapshot map[string]string
+
+// snapshotContextKey is the key of the namespace snapshot taken for a feature
+// in the context of its steps.
+type snapshotContextKey string
+
+// WithSnapshot records the objects of the namespace of the environment
+// configuration before the setups of the feature run and, if the feature
+// fails, lists them again once the feature is torn down and logs the objects
+// that were created, changed or deleted in the meantime. This helps diagnosing
+// leaked objects and unexpected mutations. Events are not recorded.
+//
+// The snapshot is carried by the context of the steps, so that every run of
+// the feature is compared with its own snapshot.
+func (b *FeatureBuilder) WithSnapshot() *FeatureBuilder {
+	key := snapshotContextKey(b.feat.name)
+	snapshot := newStep(fmt.Sprintf("%s-snapshot", b.feat.name), LevelSetup, func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		before, err := takeNamespaceSnapshot(ctx, cfg)
+		if err != nil {
+			t.Logf("failed to snapshot namespace %q: %s", cfg.Namespace(), err)
+			return ctx
+		}
+		return context.WithValue(ctx, key, before)
+	})
+	// the snapshot is taken before any other setup step
+	b.feat.steps = append([]Step{snapshot}, b.feat.steps...)
+	return b.WithTeardownAlways(fmt.Sprintf("%s-snapshot-diff", b.feat.name), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		before, ok := ctx.Value(key).(namespaceSnapshot)
+		if !ok || !t.Failed() {
+			return ctx
+		}
+		changes, err := namespaceChanges(ctx, cfg, before)
+		if err != nil {
+			t.Logf("failed to snapshot namespace %q: %s", cfg.Namespace(), err)
+			return ctx
+		}
+		if len(changes) > 0 {
+			t.Logf("changes to namespace %q during the feature:\n%s", cfg.Namespace(), strings.Join(changes, "\n"))
+		} else {
+			t.Logf("no change to namespace %q during the feature", cfg.Namespace())
+		}
+		return ctx
+	})
+}
+
+// namespaceChanges returns the objects of the namespace of cfg created,
+// changed or deleted since the snapshot before was taken.
+func namespaceChanges(ctx context.Context, cfg *envconf.Config, before namespaceSnapshot) ([]string, error) {
+	after, err := takeNamespaceSnapshot(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return before.diff(after), nil
+}
+
+// takeNamespaceSnapshot lists the objects of every namespaced kind served by
+// the cluster in the namespace of cfg.
+func takeNamespaceSnapshot(ctx context.Context, cfg *envconf.Config) (namespaceSnapshot, error) {
+	if cfg.Namespace() == "" {
+		return nil, fmt.Errorf("no namespace configured")
+	}
+	client, err := cfg.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(client.RESTConfig())
+	if err != nil {
+		return nil, err
+	}
+	resourceLists, err := dc.ServerPreferredNamespacedResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+	res := client.Resources(cfg.Namespace())
+	snapshot := make(namespaceSnapshot)
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resourceList.APIResources {
+			if strings.Contains(resource.Name, "/") || resource.Kind == "Event" || !hasListVerb(resource.Verbs) {
+				continue
+			}
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(gv.WithKind(resource.Kind + "List"))
+			if err := res.List(ctx, list); err != nil {
+				return nil, fmt.Errorf("failed to list %s: %w", resource.Name, err)
+			}
+			for _, obj := range list.Items {
+				key := fmt.Sprintf("%s/%s", gv.WithKind(resource.Kind).GroupKind(), obj.GetName())
+				snapshot[key] = obj.GetResourceVersion()
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+func hasListVerb(verbs []string) bool {
+	for _, verb := range verbs {
+		if verb == "list" {
+			return true
+		}
+	}
+	return false
+}
+
+// diff returns the objects created, changed or deleted between the snapshot
+// and after, sorted.
+func (s namespaceSnapshot) diff(after namespaceSnapshot) []string {
+	var changes []string
+	for key, version := range after {
+		previous, found := s[key]
+		switch {
+		case !found:
+			changes = append(changes, "created: "+key)
+		case previous != version:
+			changes = append(changes, "changed: "+key)
+		}
+	}
+	for key := range s {
+		if _, found := after[key]; !found {
+			changes = append(changes, "deleted: "+key)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
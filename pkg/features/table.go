@@ -17,13 +17,50 @@ limitations under the License.
 package features
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
 )
 
+// OperationFunc is the operation of a negative assessment of a Table, which
+// returns the error it is expected to fail with instead of failing the
+// *testing.T.
+type OperationFunc func(context.Context, *testing.T, *envconf.Config) (context.Context, error)
+
 type TableRow struct {
 	Name        string
 	Description string
 	Assessment  Func
+	// Operation, along with ExpectErr, makes the row a negative assessment:
+	// the row fails unless Operation returns an error matched by ExpectErr.
+	// Operation is only used when Assessment is not set. Setting only one of
+	// Operation and ExpectErr makes Build panic.
+	Operation OperationFunc
+	ExpectErr func(error) bool
+}
+
+// ExpectError wraps fn in a Func that fails the step if fn returns no error
+// or an error that is not matched by match, such as apierrors.IsForbidden for
+// a request rejected by an admission webhook.
+func ExpectError(fn OperationFunc, match func(error) bool) Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		out, err := fn(ctx, t, cfg)
+		if err == nil {
+			t.Fatal("expected an error, got none")
+		}
+		if !match(err) {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if out == nil {
+			return ctx
+		}
+		return out
+	}
 }
 
 // Table provides a structure for table-driven tests.
@@ -33,13 +70,20 @@ type Table []TableRow
 // Build converts the defined test steps in the table
 // into a FeatureBuilder which can be used to add additional attributes
 // to the feature before it's exercised. Build takes an optional feature name
-// if omitted will be generated.
+// if omitted will be generated from the file and line of the caller, such as
+// "table_test.go:42". Build panics if a row sets only one of Operation and
+// ExpectErr.
 func (table Table) Build(args ...string) *FeatureBuilder {
 	var name string
 	var description string
 	if len(args) > 0 {
 		name = args[0]
 	}
+	if name == "" {
+		if _, file, line, ok := runtime.Caller(1); ok {
+			name = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		}
+	}
 	if len(args) > 1 {
 		description = args[1]
 	}
@@ -48,8 +92,14 @@ func (table Table) Build(args ...string) *FeatureBuilder {
 		if test.Name == "" {
 			test.Name = fmt.Sprintf("Assessment-%d", i)
 		}
-		if test.Assessment != nil {
+		if (test.Operation == nil) != (test.ExpectErr == nil) {
+			panic(fmt.Sprintf("table row %q: Operation and ExpectErr must be set together", test.Name))
+		}
+		switch {
+		case test.Assessment != nil:
 			f.AssessWithDescription(test.Name, test.Description, test.Assessment)
+		case test.Operation != nil:
+			f.AssessWithDescription(test.Name, test.Description, ExpectError(test.Operation, test.ExpectErr))
 		}
 	}
 	return f
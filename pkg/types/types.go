@@ -19,6 +19,9 @@ package types
 import (
 	"context"
 	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
 
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/flags"
@@ -50,6 +53,26 @@ type Environment interface {
 	// WithContext returns a new Environment with a new context
 	WithContext(context.Context) Environment
 
+	// WithDefaultLabels registers a baseline set of labels that are merged
+	// with the labels of every feature tested in the environment. Feature
+	// labels take precedence over the defaults on key conflict.
+	WithDefaultLabels(Labels) Environment
+
+	// WithLogger sets the logger used to report the progress of the
+	// environment. The logger is also made available to the env and step
+	// functions through their context.
+	WithLogger(logr.Logger) Environment
+
+	// WithFailFast skips the features that remain to be tested once a
+	// feature tested in the environment fails. The Finish funcs are still
+	// executed.
+	WithFailFast() Environment
+
+	// WithSharedNamespace runs every feature tested in the environment in
+	// the namespace name, created once the Setup funcs executed and deleted
+	// before the Finish funcs execute.
+	WithSharedNamespace(name string) Environment
+
 	// Setup registers environment operations that are executed once
 	// prior to the environment being ready and prior to any test.
 	Setup(...EnvFunc) Environment
@@ -70,6 +93,10 @@ type Environment interface {
 	// This method surfaces context for further updates.
 	Test(*testing.T, ...Feature) context.Context
 
+	// TestWithResult works the same way Test does, and returns the
+	// outcome of each feature along with the surfaced context.
+	TestWithResult(*testing.T, ...Feature) Result
+
 	// TestInParallel executes a series of test features defined in a
 	// TestXXX function in parallel. This works the same way Test method
 	// does with the caveat that the features will all be run in parallel
@@ -83,8 +110,13 @@ type Environment interface {
 	// test suite.
 	Finish(...EnvFunc) Environment
 
-	// Run Launches the test suite from within a TestMain
+	// Run Launches the test suite from within a TestMain. The returned
+	// exit code is non-zero if the tests, a Setup or a Finish func failed.
 	Run(*testing.M) int
+
+	// FinishError returns the errors returned by the Finish funcs
+	// during the last Run, joined together, or nil.
+	FinishError() error
 }
 
 type Labels = flags.LabelsMap
@@ -107,8 +139,26 @@ const (
 	LevelAssess
 	// LevelTeardown when doing the teardown phase
 	LevelTeardown
+	// LevelTeardownAlways when doing the teardown phase that runs even
+	// if a setup or an assessment of the feature failed
+	LevelTeardownAlways
 )
 
+func (l Level) String() string {
+	switch l {
+	case LevelSetup:
+		return "Setup"
+	case LevelAssess:
+		return "Assess"
+	case LevelTeardown:
+		return "Teardown"
+	case LevelTeardownAlways:
+		return "TeardownAlways"
+	default:
+		return "Unknown"
+	}
+}
+
 type StepFunc func(context.Context, *testing.T, *envconf.Config) context.Context
 
 type Step interface {
@@ -127,6 +177,42 @@ type DescribableStep interface {
 	Description() string
 }
 
+// SkipFunc reports whether a feature should be skipped, and why, before it
+// is tested. It can query the cluster, e.g. to check for a required API group.
+type SkipFunc func(context.Context, *envconf.Config) (skip bool, reason string)
+
+type SkippableFeature interface {
+	Feature
+
+	// SkipConditions returns the conditions evaluated before the feature is
+	// tested. The feature is skipped if any of them reports true.
+	SkipConditions() []SkipFunc
+}
+
+type DependentFeature interface {
+	Feature
+
+	// DependsOn returns the names of the features that must run, and succeed,
+	// before this feature is tested.
+	DependsOn() []string
+}
+
+type TimedFeature interface {
+	Feature
+
+	// StepTimeout returns the maximum duration of each step of the feature,
+	// or zero when the steps are not bounded.
+	StepTimeout() time.Duration
+}
+
+type ContextualFeature interface {
+	Feature
+
+	// ContextFuncs returns the functions seeding the context of the feature,
+	// applied in order before its first step runs.
+	ContextFuncs() []func(context.Context) context.Context
+}
+
 type DescribableFeature interface {
 	Feature
 
@@ -135,3 +221,32 @@ type DescribableFeature interface {
 	// feature.
 	Description() string
 }
+
+// FeatureStatus is the outcome of a tested feature.
+type FeatureStatus string
+
+const (
+	FeaturePassed  FeatureStatus = "passed"
+	FeatureFailed  FeatureStatus = "failed"
+	FeatureSkipped FeatureStatus = "skipped"
+)
+
+// FeatureResult is the outcome of a single tested feature.
+type FeatureResult struct {
+	// Name is the name of the feature subtest
+	Name string
+	// Status reports whether the feature passed, failed or was skipped
+	Status FeatureStatus
+	// Duration is the time spent testing the feature
+	Duration time.Duration
+	// Err describes why the feature failed or was skipped, when known
+	Err error
+}
+
+// Result is the outcome of the features tested by Environment.TestWithResult.
+type Result struct {
+	// Context is the context surfaced by the features, as returned by Environment.Test
+	Context context.Context
+	// Features holds the result of each tested feature, in the order they were tested
+	Features []FeatureResult
+}